@@ -0,0 +1,54 @@
+// reconcile-points 积分对账命令：按积分变动记录核对用户积分余额
+//
+// 用法：
+//
+//	go run ./cmd/reconcile-points            # 只报告差异
+//	go run ./cmd/reconcile-points -repair    # 以变动记录为准修正余额
+//	go run ./cmd/reconcile-points -user 42   # 只核对指定用户
+package main
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/services"
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "以积分变动记录为准修正账户余额")
+	user := flag.Uint("user", 0, "只核对指定用户ID")
+	flag.Parse()
+
+	config.LoadConfig()
+	database.InitDB()
+
+	var userID *uint
+	if *user > 0 {
+		uid := *user
+		userID = &uid
+	}
+
+	result, err := services.NewPointsService().Reconcile(database.GetDB(), *repair, userID)
+	if err != nil {
+		log.Printf("积分对账失败: %v", err)
+	}
+	if result == nil {
+		os.Exit(1)
+	}
+
+	for _, drift := range result.Drifts {
+		status := "未修正"
+		if drift.Repaired {
+			status = "已修正"
+		}
+		log.Printf("用户 %d: 账户余额 %d，变动记录 %d，差异 %+d（%s）",
+			drift.UserID, drift.TotalPoints, drift.LedgerPoints, drift.Drift, status)
+	}
+	log.Printf("共核对 %d 个账户，%d 个存在差异，修正 %d 个", result.Checked, result.Drifted, result.Repaired)
+
+	if err != nil || (!*repair && result.Drifted > 0) {
+		os.Exit(1)
+	}
+}
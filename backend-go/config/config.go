@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultJWTSecret 未配置 JWT_SECRET 时使用的开发密钥，已公开在代码库中，release 模式下禁止使用
+const DefaultJWTSecret = "coffee-ordering-secret-key-change-in-production"
+
 type Config struct {
 	Port        string
 	GinMode     string
@@ -17,6 +21,52 @@ type Config struct {
 	DBPassword  string
 	DBName      string
 	CORSOrigins string
+
+	// JWT 签名密钥
+	JWTSecret string
+
+	// 幂等键有效期（小时）
+	IdempotencyKeyTTLHours int
+
+	// 支付渠道（mock 为本地模拟支付，release 模式下禁止使用）及回调签名密钥
+	PaymentProvider      string
+	PaymentWebhookSecret string
+
+	// 未支付订单超时时间（分钟），超时后自动取消并释放库存、优惠券与积分，0 表示不自动取消
+	UnpaidOrderTimeoutMinutes int
+
+	// 出品时限（分钟），KDS 中超时订单会被标记
+	KDSOrderSLAMinutes int
+
+	// 未指定门店时使用的默认门店
+	DefaultStoreID uint
+
+	// 预约取餐：时段长度（分钟）、每时段订单上限（0 不限）、提前进入出品队列的分钟数、最多可预约天数
+	ScheduleSlotMinutes  int
+	ScheduleSlotCapacity int
+	ScheduleLeadMinutes  int
+	ScheduleMaxDaysAhead int
+
+	// 生日积分基数，实际发放数量 = 基数 × 会员等级生日倍数
+	BirthdayBonusBasePoints int
+
+	// 积分有效期（月，0 表示永不过期）及到期提醒提前天数
+	PointsExpiryMonths     int
+	PointsExpiryNoticeDays int
+
+	// 手动积分调整超过该数量（绝对值）时需第二位管理员审批
+	PointsAdjustmentApprovalThreshold int
+
+	// 推荐奖励：推荐人与被推荐人获得的积分，以及每位推荐人最多获得奖励的人数（0 不限）
+	ReferralReferrerPoints int
+	ReferralRefereePoints  int
+	ReferralMaxPerReferrer int
+
+	// 后台定时任务执行间隔（秒）
+	SchedulerIntervalSeconds int
+
+	// 开发认证模式：可用预置角色账号直接获取令牌，仅限本地开发，release 模式下禁止开启
+	DevAuthEnabled bool
 }
 
 var AppConfig *Config
@@ -37,7 +87,54 @@ func LoadConfig() {
 		DBPassword:  getEnv("DB_PASSWORD", ""),
 		DBName:      getEnv("DB_NAME", "coffee_ordering"),
 		CORSOrigins: getEnv("CORS_ORIGINS", "http://localhost:3000,http://127.0.0.1:3000"),
+
+		JWTSecret: getEnv("JWT_SECRET", DefaultJWTSecret),
+
+		IdempotencyKeyTTLHours: getEnvInt("IDEMPOTENCY_KEY_TTL_HOURS", 24),
+
+		PaymentProvider:      getEnv("PAYMENT_PROVIDER", "mock"),
+		PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", "mock-webhook-secret"),
+
+		UnpaidOrderTimeoutMinutes: getEnvInt("UNPAID_ORDER_TIMEOUT_MINUTES", 15),
+
+		KDSOrderSLAMinutes: getEnvInt("KDS_ORDER_SLA_MINUTES", 10),
+
+		DefaultStoreID: uint(getEnvInt("DEFAULT_STORE_ID", 1)),
+
+		ScheduleSlotMinutes:  getEnvInt("SCHEDULE_SLOT_MINUTES", 15),
+		ScheduleSlotCapacity: getEnvInt("SCHEDULE_SLOT_CAPACITY", 10),
+		ScheduleLeadMinutes:  getEnvInt("SCHEDULE_LEAD_MINUTES", 15),
+		ScheduleMaxDaysAhead: getEnvInt("SCHEDULE_MAX_DAYS_AHEAD", 2),
+
+		BirthdayBonusBasePoints: getEnvInt("BIRTHDAY_BONUS_BASE_POINTS", 100),
+
+		PointsExpiryMonths:     getEnvInt("POINTS_EXPIRY_MONTHS", 12),
+		PointsExpiryNoticeDays: getEnvInt("POINTS_EXPIRY_NOTICE_DAYS", 30),
+
+		PointsAdjustmentApprovalThreshold: getEnvInt("POINTS_ADJUSTMENT_APPROVAL_THRESHOLD", 500),
+
+		ReferralReferrerPoints: getEnvInt("REFERRAL_REFERRER_POINTS", 200),
+		ReferralRefereePoints:  getEnvInt("REFERRAL_REFEREE_POINTS", 100),
+		ReferralMaxPerReferrer: getEnvInt("REFERRAL_MAX_PER_REFERRER", 20),
+
+		SchedulerIntervalSeconds: getEnvInt("SCHEDULER_INTERVAL_SECONDS", 30),
+
+		DevAuthEnabled: getEnvBool("DEV_AUTH_ENABLED", false),
+	}
+}
+
+// Validate 校验配置组合是否安全
+func (c *Config) Validate() error {
+	if c.DevAuthEnabled && c.GinMode == "release" {
+		return fmt.Errorf("GIN_MODE=release 时不能开启 DEV_AUTH_ENABLED")
+	}
+	if c.PaymentProvider == "mock" && c.GinMode == "release" {
+		return fmt.Errorf("GIN_MODE=release 时不能使用模拟支付（PAYMENT_PROVIDER=mock）")
+	}
+	if (c.JWTSecret == "" || c.JWTSecret == DefaultJWTSecret) && c.GinMode == "release" {
+		return fmt.Errorf("GIN_MODE=release 时必须通过 JWT_SECRET 配置专用的令牌签名密钥")
 	}
+	return nil
 }
 
 // GetDSN 获取数据库连接字符串
@@ -57,3 +154,23 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+		log.Printf("环境变量 %s 不是有效整数，使用默认值 %d", key, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+		log.Printf("环境变量 %s 不是有效布尔值，使用默认值 %t", key, defaultValue)
+	}
+	return defaultValue
+}
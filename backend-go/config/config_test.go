@@ -0,0 +1,26 @@
+package config
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "开发模式允许默认配置", config: Config{GinMode: "debug", JWTSecret: DefaultJWTSecret, PaymentProvider: "mock", DevAuthEnabled: true}},
+		{name: "release 模式配置完整", config: Config{GinMode: "release", JWTSecret: "a-long-random-production-secret", PaymentProvider: "stripe"}},
+		{name: "release 模式使用默认密钥", config: Config{GinMode: "release", JWTSecret: DefaultJWTSecret, PaymentProvider: "stripe"}, wantErr: true},
+		{name: "release 模式未配置密钥", config: Config{GinMode: "release", PaymentProvider: "stripe"}, wantErr: true},
+		{name: "release 模式开启开发认证", config: Config{GinMode: "release", JWTSecret: "a-long-random-production-secret", PaymentProvider: "stripe", DevAuthEnabled: true}, wantErr: true},
+		{name: "release 模式使用模拟支付", config: Config{GinMode: "release", JWTSecret: "a-long-random-production-secret", PaymentProvider: "mock"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v，期望出错 = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
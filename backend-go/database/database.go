@@ -38,9 +38,32 @@ func InitDB() {
 // AutoMigrate 自动迁移数据库表
 func AutoMigrate() {
 	err := DB.AutoMigrate(
+		&models.Store{},
+		&models.StoreMenuItem{},
+		&models.StoreOpeningHour{},
+		&models.StoreClosure{},
 		&models.MenuItem{},
+		&models.MenuOptionGroup{},
+		&models.MenuOption{},
 		&models.Order{},
+		&models.Promotion{},
+		&models.OrderDiscount{},
 		&models.OrderItem{},
+		&models.OrderItemOption{},
+		&models.OrderStatusHistory{},
+		&models.Payment{},
+		&models.PaymentRefund{},
+		&models.OrderSequence{},
+		&models.IdempotencyKey{},
+		&models.Ingredient{},
+		&models.RecipeItem{},
+		&models.StockMovement{},
+		&models.MemberLevelConfig{},
+		&models.MemberLevelHistory{},
+		&models.BirthdayBonusGrant{},
+		&models.Referral{},
+		&models.PointLot{},
+		&models.PointsAdjustment{},
 	)
 	if err != nil {
 		log.Fatalf("数据库迁移失败: %v", err)
@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/middleware"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scopedInventoryMessage 原料库存为全部门店共用（售罄同步影响全部门店），仅限未绑定门店的管理员修改
+const scopedInventoryMessage = "原料库存为全部门店共用，门店管理员不能修改"
+
+// GetIngredients 获取原料列表（管理员）
+func GetIngredients(c *gin.Context) {
+	lowStockOnly := c.Query("low_stock") == "true"
+
+	db := database.GetDB()
+	var ingredients []models.Ingredient
+
+	query := db.Model(&models.Ingredient{})
+	if lowStockOnly {
+		query = query.Where("stock <= low_stock_threshold")
+	}
+	query.Order("name ASC").Find(&ingredients)
+
+	ingredientList := make([]gin.H, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		ingredientList = append(ingredientList, gin.H{
+			"id":                  ingredient.ID,
+			"name":                ingredient.Name,
+			"unit":                ingredient.Unit,
+			"stock":               ingredient.Stock,
+			"low_stock_threshold": ingredient.LowStockThreshold,
+			"is_low_stock":        ingredient.IsLowStock(),
+			"updated_at":          ingredient.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    ingredientList,
+	})
+}
+
+// CreateIngredient 创建原料（管理员）
+func CreateIngredient(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedInventoryMessage) {
+		return
+	}
+
+	var req struct {
+		Name              string  `json:"name" binding:"required"`
+		Unit              string  `json:"unit" binding:"required"`
+		Stock             float64 `json:"stock" binding:"gte=0"`
+		LowStockThreshold float64 `json:"low_stock_threshold" binding:"gte=0"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	ingredient := models.Ingredient{
+		Name:              req.Name,
+		Unit:              req.Unit,
+		LowStockThreshold: req.LowStockThreshold,
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Create(&ingredient).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"创建原料失败: " + err.Error()},
+		})
+		return
+	}
+
+	// 初始库存记为一次入库
+	if req.Stock > 0 {
+		operatorID, _ := middleware.GetUserID(c)
+		updated, err := services.NewInventoryService().StockIn(tx, ingredient.ID, req.Stock, &operatorID, "初始库存")
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"errors":  []string{"创建原料失败: " + err.Error()},
+			})
+			return
+		}
+		ingredient = *updated
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"创建原料失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":    true,
+		"message":    "原料创建成功",
+		"ingredient": ingredient,
+	})
+}
+
+// UpdateIngredient 更新原料基本信息（管理员），库存只能通过入库或盘点调整
+func UpdateIngredient(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedInventoryMessage) {
+		return
+	}
+
+	id := c.Param("id")
+
+	var req struct {
+		Name *string `json:"name"`
+		Unit *string `json:"unit"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var ingredient models.Ingredient
+
+	if err := db.First(&ingredient, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"原料不存在"},
+		})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Unit != nil {
+		updates["unit"] = *req.Unit
+	}
+
+	if err := db.Model(&ingredient).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新原料失败: " + err.Error()},
+		})
+		return
+	}
+
+	db.First(&ingredient, id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "原料更新成功",
+		"ingredient": ingredient,
+	})
+}
+
+// UpdateIngredientThreshold 设置原料低库存阈值（管理员）
+func UpdateIngredientThreshold(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedInventoryMessage) {
+		return
+	}
+
+	id := c.Param("id")
+
+	var req struct {
+		LowStockThreshold *float64 `json:"low_stock_threshold" binding:"required,gte=0"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var ingredient models.Ingredient
+
+	if err := db.First(&ingredient, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"原料不存在"},
+		})
+		return
+	}
+
+	if err := db.Model(&ingredient).Update("low_stock_threshold", *req.LowStockThreshold).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新阈值失败: " + err.Error()},
+		})
+		return
+	}
+	ingredient.LowStockThreshold = *req.LowStockThreshold
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "低库存阈值更新成功",
+		"ingredient": ingredient,
+	})
+}
+
+// StockInIngredient 原料入库（管理员）
+func StockInIngredient(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedInventoryMessage) {
+		return
+	}
+
+	var req struct {
+		Quantity float64 `json:"quantity" binding:"required,gt=0"`
+		Note     string  `json:"note"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	note := req.Note
+	if note == "" {
+		note = "入库"
+	}
+
+	adjustIngredientStock(c, func(tx *gorm.DB, id uint, operatorID *uint) (*models.Ingredient, error) {
+		return services.NewInventoryService().StockIn(tx, id, req.Quantity, operatorID, note)
+	})
+}
+
+// StocktakeIngredient 盘点调整原料库存（管理员）
+func StocktakeIngredient(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedInventoryMessage) {
+		return
+	}
+
+	var req struct {
+		ActualStock *float64 `json:"actual_stock" binding:"required,gte=0"`
+		Note        string   `json:"note"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	note := req.Note
+	if note == "" {
+		note = "盘点调整"
+	}
+
+	adjustIngredientStock(c, func(tx *gorm.DB, id uint, operatorID *uint) (*models.Ingredient, error) {
+		return services.NewInventoryService().Stocktake(tx, id, *req.ActualStock, operatorID, note)
+	})
+}
+
+// adjustIngredientStock 在事务内执行库存调整并返回结果
+func adjustIngredientStock(c *gin.Context, adjust func(tx *gorm.DB, id uint, operatorID *uint) (*models.Ingredient, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的原料ID"},
+		})
+		return
+	}
+
+	operatorID, _ := middleware.GetUserID(c)
+
+	db := database.GetDB()
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	ingredient, err := adjust(tx, uint(id), &operatorID)
+	if err != nil {
+		tx.Rollback()
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrIngredientNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"库存调整失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "库存调整成功",
+		"ingredient": gin.H{
+			"id":                  ingredient.ID,
+			"name":                ingredient.Name,
+			"unit":                ingredient.Unit,
+			"stock":               ingredient.Stock,
+			"low_stock_threshold": ingredient.LowStockThreshold,
+			"is_low_stock":        ingredient.IsLowStock(),
+		},
+	})
+}
+
+// GetIngredientMovements 获取原料库存变动记录（管理员）
+func GetIngredientMovements(c *gin.Context) {
+	id := c.Param("id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	db := database.GetDB()
+	var movements []models.StockMovement
+	var total int64
+
+	query := db.Model(&models.StockMovement{}).Where("ingredient_id = ?", id)
+	query.Count(&total)
+
+	offset := (page - 1) * perPage
+	query.Offset(offset).Limit(perPage).Order("created_at DESC, id DESC").Find(&movements)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"movements": movements,
+			"total":     total,
+			"page":      page,
+			"per_page":  perPage,
+			"pages":     (total + int64(perPage) - 1) / int64(perPage),
+		},
+	})
+}
+
+// GetMenuItemRecipe 获取菜品配方（管理员）
+func GetMenuItemRecipe(c *gin.Context) {
+	id := c.Param("id")
+
+	db := database.GetDB()
+	var recipe []models.RecipeItem
+
+	db.Preload("Ingredient").Where("menu_item_id = ?", id).Order("id ASC").Find(&recipe)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    recipe,
+	})
+}
+
+// UpdateMenuItemRecipe 设置菜品配方（管理员），请求为完整配方
+func UpdateMenuItemRecipe(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedMenuMessage) {
+		return
+	}
+
+	id := c.Param("id")
+
+	var req struct {
+		Items []struct {
+			IngredientID uint    `json:"ingredient_id" binding:"required"`
+			Quantity     float64 `json:"quantity" binding:"required,gt=0"`
+		} `json:"items" binding:"dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var menuItem models.MenuItem
+
+	if err := db.First(&menuItem, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"菜单项不存在"},
+		})
+		return
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Where("menu_item_id = ?", menuItem.ID).Delete(&models.RecipeItem{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新配方失败: " + err.Error()},
+		})
+		return
+	}
+
+	for _, item := range req.Items {
+		var ingredient models.Ingredient
+		if err := tx.First(&ingredient, item.IngredientID).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"原料不存在"},
+			})
+			return
+		}
+
+		recipeItem := models.RecipeItem{
+			MenuItemID:   menuItem.ID,
+			IngredientID: item.IngredientID,
+			Quantity:     item.Quantity,
+		}
+		if err := tx.Create(&recipeItem).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"配方原料重复或无效: " + err.Error()},
+			})
+			return
+		}
+	}
+
+	// 配方变化后重新计算售罄状态
+	if err := services.NewInventoryService().SyncMenuItems(tx, []uint{menuItem.ID}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新配方失败: " + err.Error()},
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新配方失败: " + err.Error()},
+		})
+		return
+	}
+
+	var recipe []models.RecipeItem
+	db.Preload("Ingredient").Where("menu_item_id = ?", menuItem.ID).Order("id ASC").Find(&recipe)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "配方更新成功",
+		"data":    recipe,
+	})
+}
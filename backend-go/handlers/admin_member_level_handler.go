@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MemberLevelRequest 会员等级配置请求
+type MemberLevelRequest struct {
+	LevelName             models.MemberLevel `json:"level_name" binding:"required"`
+	LevelDisplayName      string             `json:"level_display_name" binding:"required,max=50"`
+	MinPoints             int                `json:"min_points"`
+	PointsEarningRate     float64            `json:"points_earning_rate" binding:"required"`
+	PointsDiscountRate    float64            `json:"points_discount_rate"`
+	MaxDiscountPercentage float64            `json:"max_discount_percentage"`
+	BirthdayMultiplier    float64            `json:"birthday_multiplier"`
+	Benefits              []string           `json:"benefits"`
+	LevelIcon             string             `json:"level_icon" binding:"max=255"`
+	IsActive              *bool              `json:"is_active"`
+	SortOrder             int                `json:"sort_order"`
+}
+
+// toModel 转换为会员等级配置模型
+func (r *MemberLevelRequest) toModel() models.MemberLevelConfig {
+	benefits := r.Benefits
+	if benefits == nil {
+		benefits = []string{}
+	}
+	encoded, _ := json.Marshal(benefits)
+
+	return models.MemberLevelConfig{
+		LevelName:             r.LevelName,
+		LevelDisplayName:      r.LevelDisplayName,
+		MinPoints:             r.MinPoints,
+		PointsEarningRate:     r.PointsEarningRate,
+		PointsDiscountRate:    r.PointsDiscountRate,
+		MaxDiscountPercentage: r.MaxDiscountPercentage,
+		BirthdayMultiplier:    r.BirthdayMultiplier,
+		Benefits:              string(encoded),
+		LevelIcon:             r.LevelIcon,
+		IsActive:              r.IsActive == nil || *r.IsActive,
+		SortOrder:             r.SortOrder,
+	}
+}
+
+// memberLevelErrorStatus 会员等级配置错误对应的HTTP状态码
+func memberLevelErrorStatus(err error) int {
+	if errors.Is(err, services.ErrInvalidMemberLevel) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// recalculateMemberLevels 等级配置变更后按新门槛重新计算会员等级
+func recalculateMemberLevels(reason string) gin.H {
+	changed, err := services.NewPointsService().RecalculateMemberLevels(database.GetDB(), reason)
+	result := gin.H{"members_changed": changed}
+	if err != nil {
+		result["error"] = "会员等级重算未完成: " + err.Error()
+	}
+	return result
+}
+
+// GetMemberLevels 获取会员等级配置列表（管理员），含各等级会员数
+func GetMemberLevels(c *gin.Context) {
+	db := database.GetDB()
+
+	var levels []models.MemberLevelConfig
+	db.Order("min_points ASC").Find(&levels)
+
+	var counts []struct {
+		MemberLevel models.MemberLevel
+		Count       int64
+	}
+	db.Model(&models.UserPoints{}).Select("member_level, COUNT(*) AS count").
+		Group("member_level").Scan(&counts)
+	members := make(map[models.MemberLevel]int64, len(counts))
+	for _, row := range counts {
+		members[row.MemberLevel] = row.Count
+	}
+
+	result := make([]gin.H, 0, len(levels))
+	for _, level := range levels {
+		result = append(result, gin.H{
+			"level":        level,
+			"member_count": members[level.LevelName],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// CreateMemberLevel 创建会员等级配置（管理员），创建后重新计算会员等级
+func CreateMemberLevel(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能维护会员等级") {
+		return
+	}
+
+	var req MemberLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	db := database.GetDB()
+	level := req.toModel()
+	if err := services.NewMemberLevelService().Validate(db, &level); err != nil {
+		c.JSON(memberLevelErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	var count int64
+	db.Model(&models.MemberLevelConfig{}).Where("level_name = ?", level.LevelName).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"该会员等级配置已存在"},
+		})
+		return
+	}
+
+	if err := db.Create(&level).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"创建会员等级失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":       true,
+		"message":       "会员等级创建成功",
+		"data":          level,
+		"recalculation": recalculateMemberLevels("会员等级配置调整：新增" + level.LevelDisplayName),
+	})
+}
+
+// UpdateMemberLevel 更新会员等级配置（管理员），门槛或启用状态变化时重新计算会员等级
+func UpdateMemberLevel(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能维护会员等级") {
+		return
+	}
+
+	db := database.GetDB()
+	var existing models.MemberLevelConfig
+	if err := db.First(&existing, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"会员等级不存在"},
+		})
+		return
+	}
+
+	var req MemberLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+	if req.LevelName != existing.LevelName {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"等级标识不能修改"},
+		})
+		return
+	}
+
+	level := req.toModel()
+	level.ID = existing.ID
+	level.CreatedAt = existing.CreatedAt
+	if req.IsActive == nil {
+		level.IsActive = existing.IsActive
+	}
+	if req.Benefits == nil {
+		level.Benefits = existing.Benefits
+	}
+	if err := services.NewMemberLevelService().Validate(db, &level); err != nil {
+		c.JSON(memberLevelErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := db.Save(&level).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新会员等级失败: " + err.Error()},
+		})
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"message": "会员等级更新成功",
+		"data":    level,
+	}
+	if level.MinPoints != existing.MinPoints || level.IsActive != existing.IsActive {
+		response["recalculation"] = recalculateMemberLevels("会员等级配置调整：" + level.LevelDisplayName)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteMemberLevel 删除会员等级配置（管理员），该等级的会员按剩余等级重新计算
+func DeleteMemberLevel(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能维护会员等级") {
+		return
+	}
+
+	db := database.GetDB()
+	var level models.MemberLevelConfig
+	if err := db.First(&level, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"会员等级不存在"},
+		})
+		return
+	}
+
+	if err := services.NewMemberLevelService().CanDelete(db, &level); err != nil {
+		c.JSON(memberLevelErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := db.Delete(&level).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"删除会员等级失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"message":       "会员等级删除成功",
+		"recalculation": recalculateMemberLevels("会员等级配置调整：删除" + level.LevelDisplayName),
+	})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/testutil"
+	"net/http"
+	"testing"
+)
+
+func TestCreateMemberLevelKeepsZeroValues(t *testing.T) {
+	db := testutil.NewDB(t)
+	for _, level := range []models.MemberLevelConfig{
+		{LevelName: models.MemberLevelBronze, LevelDisplayName: "铜牌会员", MinPoints: 0, PointsEarningRate: 1, MaxDiscountPercentage: 20, BirthdayMultiplier: 1, IsActive: true},
+		{LevelName: models.MemberLevelGold, LevelDisplayName: "金牌会员", MinPoints: 1000, PointsEarningRate: 1.5, MaxDiscountPercentage: 20, BirthdayMultiplier: 3, IsActive: true},
+	} {
+		if err := db.Create(&level).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 停用的等级不参与门槛排序校验，门槛高于金牌也可以创建
+	body := `{"level_name":"silver","level_display_name":"银牌会员","min_points":5000,"points_earning_rate":1.2,` +
+		`"max_discount_percentage":0,"birthday_multiplier":0,"is_active":false}`
+	c, w := newJSONContext(http.MethodPost, "/api/admin/member-levels", body, nil)
+	CreateMemberLevel(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建会员等级返回 %d: %s", w.Code, w.Body.String())
+	}
+
+	var silver models.MemberLevelConfig
+	if err := db.Where("level_name = ?", models.MemberLevelSilver).First(&silver).Error; err != nil {
+		t.Fatal(err)
+	}
+	if silver.IsActive {
+		t.Error("is_active=false 的等级被保存为启用")
+	}
+	if silver.MaxDiscountPercentage != 0 {
+		t.Errorf("max_discount_percentage = %v，期望 0", silver.MaxDiscountPercentage)
+	}
+	if silver.BirthdayMultiplier != 0 {
+		t.Errorf("birthday_multiplier = %v，期望 0", silver.BirthdayMultiplier)
+	}
+}
@@ -9,8 +9,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// scopedMenuMessage 菜单、定制选项与配方对全部门店生效，绑定门店的管理员只能维护本店菜单设置
+const scopedMenuMessage = "全局菜单对全部门店生效，门店管理员请通过 /api/admin/stores/:id/menu 调整本店价格与上下架"
+
 // CreateMenuItem 创建菜单项（管理员）
 func CreateMenuItem(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedMenuMessage) {
+		return
+	}
+
 	var req struct {
 		Name        string  `json:"name" binding:"required"`
 		Description string  `json:"description"`
@@ -56,6 +63,10 @@ func CreateMenuItem(c *gin.Context) {
 
 // UpdateMenuItem 更新菜单项（管理员）
 func UpdateMenuItem(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedMenuMessage) {
+		return
+	}
+
 	id := c.Param("id")
 
 	var req struct {
@@ -112,6 +123,7 @@ func UpdateMenuItem(c *gin.Context) {
 	}
 	if req.IsAvailable != nil {
 		updates["is_available"] = *req.IsAvailable
+		updates["sold_out"] = false // 手动上下架后不再由库存自动恢复
 	}
 
 	if err := db.Model(&menuItem).Updates(updates).Error; err != nil {
@@ -134,6 +146,10 @@ func UpdateMenuItem(c *gin.Context) {
 
 // DeleteMenuItem 删除菜单项（管理员）
 func DeleteMenuItem(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedMenuMessage) {
+		return
+	}
+
 	id := c.Param("id")
 
 	db := database.GetDB()
@@ -172,8 +188,12 @@ func DeleteMenuItem(c *gin.Context) {
 	})
 }
 
-// ToggleMenuItemAvailability 切换菜单项可用状态（管理员）
+// ToggleMenuItemAvailability 切换菜单项可用状态（管理员），对全部门店生效
 func ToggleMenuItemAvailability(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedMenuMessage) {
+		return
+	}
+
 	id := c.Param("id")
 
 	db := database.GetDB()
@@ -187,8 +207,9 @@ func ToggleMenuItemAvailability(c *gin.Context) {
 		return
 	}
 
-	// 切换状态
+	// 切换状态（手动上下架后不再由库存自动恢复）
 	menuItem.IsAvailable = !menuItem.IsAvailable
+	menuItem.SoldOut = false
 	if err := db.Save(&menuItem).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OptionGroupRequest 定制选项组请求
+type OptionGroupRequest struct {
+	Name          string          `json:"name" binding:"required"`
+	SelectionType string          `json:"selection_type"` // single（默认）或 multi
+	IsRequired    bool            `json:"is_required"`
+	MaxSelect     int             `json:"max_select"`
+	SortOrder     int             `json:"sort_order"`
+	Options       []OptionRequest `json:"options" binding:"required,min=1,dive"`
+}
+
+// OptionRequest 定制选项请求，带ID表示更新已有选项
+type OptionRequest struct {
+	ID          uint    `json:"id"`
+	Name        string  `json:"name" binding:"required"`
+	PriceDelta  float64 `json:"price_delta"`
+	IsDefault   bool    `json:"is_default"`
+	IsAvailable *bool   `json:"is_available"`
+	SortOrder   int     `json:"sort_order"`
+}
+
+// maxOptionPriceDelta 单个选项加价或减价的上限（元）
+const maxOptionPriceDelta = 100.0
+
+// validate 校验选项组配置
+func (r *OptionGroupRequest) validate() string {
+	if r.SelectionType == "" {
+		r.SelectionType = string(models.OptionSelectionSingle)
+	}
+	if r.SelectionType != string(models.OptionSelectionSingle) && r.SelectionType != string(models.OptionSelectionMulti) {
+		return "选择方式必须为 single 或 multi"
+	}
+	if r.MaxSelect < 0 {
+		return "多选上限不能为负数"
+	}
+
+	defaults := 0
+	for _, opt := range r.Options {
+		if opt.IsDefault {
+			defaults++
+		}
+		if opt.PriceDelta > maxOptionPriceDelta || opt.PriceDelta < -maxOptionPriceDelta {
+			return "选项加价需在 -100 到 100 元之间"
+		}
+		if services.RoundMoney(opt.PriceDelta) != opt.PriceDelta {
+			return "选项加价最多保留两位小数"
+		}
+	}
+	if r.SelectionType == string(models.OptionSelectionSingle) && defaults > 1 {
+		return "单选组只能有一个默认选项"
+	}
+	return ""
+}
+
+// validatePriceDeltas 校验选项减价不超过菜品价格，避免选择后单价为零或负数
+func (r *OptionGroupRequest) validatePriceDeltas(menuItem *models.MenuItem) string {
+	for _, opt := range r.Options {
+		if opt.PriceDelta < 0 && -opt.PriceDelta >= menuItem.Price {
+			return "选项「" + opt.Name + "」的减价不能超过菜品价格"
+		}
+	}
+	return ""
+}
+
+// preloadOptionGroups 按排序预加载选项组及选项
+func preloadOptionGroups(db *gorm.DB) *gorm.DB {
+	return db.Preload("OptionGroups", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC, id ASC")
+	}).Preload("OptionGroups.Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC, id ASC")
+	})
+}
+
+// GetMenuItemOptions 获取菜单项定制选项（管理员）
+func GetMenuItemOptions(c *gin.Context) {
+	id := c.Param("id")
+
+	db := database.GetDB()
+	var menuItem models.MenuItem
+
+	if err := preloadOptionGroups(db).First(&menuItem, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"菜单项不存在"},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    menuItem.OptionGroups,
+	})
+}
+
+// CreateMenuItemOptionGroup 为菜单项创建定制选项组（管理员）
+func CreateMenuItemOptionGroup(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedMenuMessage) {
+		return
+	}
+
+	id := c.Param("id")
+
+	var req OptionGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{msg},
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var menuItem models.MenuItem
+
+	if err := db.First(&menuItem, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"菜单项不存在"},
+		})
+		return
+	}
+	if msg := req.validatePriceDeltas(&menuItem); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{msg},
+		})
+		return
+	}
+
+	group := models.MenuOptionGroup{
+		MenuItemID:    menuItem.ID,
+		Name:          req.Name,
+		SelectionType: models.OptionSelectionType(req.SelectionType),
+		IsRequired:    req.IsRequired,
+		MaxSelect:     req.MaxSelect,
+		SortOrder:     req.SortOrder,
+	}
+	for _, opt := range req.Options {
+		group.Options = append(group.Options, buildMenuOption(opt, 0))
+	}
+
+	if err := db.Create(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"创建选项组失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "选项组创建成功",
+		"data":    group,
+	})
+}
+
+// UpdateMenuItemOptionGroup 更新定制选项组（管理员）
+// 请求中的选项列表为完整列表：带ID的更新，不带ID的新增，未出现的删除
+func UpdateMenuItemOptionGroup(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedMenuMessage) {
+		return
+	}
+
+	id := c.Param("id")
+	groupID := c.Param("group_id")
+
+	var req OptionGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{msg},
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var group models.MenuOptionGroup
+
+	if err := db.Preload("Options").Where("id = ? AND menu_item_id = ?", groupID, id).First(&group).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"选项组不存在"},
+		})
+		return
+	}
+
+	var menuItem models.MenuItem
+	if err := db.First(&menuItem, group.MenuItemID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"菜单项不存在"},
+		})
+		return
+	}
+	if msg := req.validatePriceDeltas(&menuItem); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{msg},
+		})
+		return
+	}
+
+	existing := make(map[uint]bool, len(group.Options))
+	for _, opt := range group.Options {
+		existing[opt.ID] = true
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	updates := map[string]interface{}{
+		"name":           req.Name,
+		"selection_type": req.SelectionType,
+		"is_required":    req.IsRequired,
+		"max_select":     req.MaxSelect,
+		"sort_order":     req.SortOrder,
+	}
+	if err := tx.Model(&group).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新选项组失败: " + err.Error()},
+		})
+		return
+	}
+
+	kept := make([]uint, 0, len(req.Options))
+	for _, optReq := range req.Options {
+		if optReq.ID != 0 && !existing[optReq.ID] {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"选项不属于该选项组"},
+			})
+			return
+		}
+
+		option := buildMenuOption(optReq, group.ID)
+		if err := tx.Save(&option).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"errors":  []string{"保存选项失败: " + err.Error()},
+			})
+			return
+		}
+		kept = append(kept, option.ID)
+	}
+
+	if err := tx.Where("group_id = ? AND id NOT IN ?", group.ID, kept).Delete(&models.MenuOption{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"删除选项失败: " + err.Error()},
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新选项组失败: " + err.Error()},
+		})
+		return
+	}
+
+	// 重新查询以获取更新后的数据
+	db.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC, id ASC")
+	}).First(&group, group.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "选项组更新成功",
+		"data":    group,
+	})
+}
+
+// DeleteMenuItemOptionGroup 删除定制选项组（管理员）
+func DeleteMenuItemOptionGroup(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, scopedMenuMessage) {
+		return
+	}
+
+	id := c.Param("id")
+	groupID := c.Param("group_id")
+
+	db := database.GetDB()
+	var group models.MenuOptionGroup
+
+	if err := db.Where("id = ? AND menu_item_id = ?", groupID, id).First(&group).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"选项组不存在"},
+		})
+		return
+	}
+
+	// 选项随选项组级联删除，历史订单保留选项快照
+	if err := db.Delete(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"删除选项组失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "选项组删除成功",
+	})
+}
+
+// buildMenuOption 由请求构建选项模型
+func buildMenuOption(req OptionRequest, groupID uint) models.MenuOption {
+	isAvailable := true
+	if req.IsAvailable != nil {
+		isAvailable = *req.IsAvailable
+	}
+	return models.MenuOption{
+		ID:          req.ID,
+		GroupID:     groupID,
+		Name:        req.Name,
+		PriceDelta:  req.PriceDelta,
+		IsDefault:   req.IsDefault,
+		IsAvailable: isAvailable,
+		SortOrder:   req.SortOrder,
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/testutil"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateMenuItemOptionGroup(t *testing.T) {
+	db := testutil.NewDB(t)
+	item := models.MenuItem{Name: "拿铁", Price: 18, Category: "coffee", IsAvailable: true}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "id", Value: fmt.Sprint(item.ID)}}
+
+	tests := []struct {
+		name       string
+		options    string
+		wantStatus int
+	}{
+		{"加价过高", `[{"name":"金箔","price_delta":1000}]`, http.StatusBadRequest},
+		{"超过两位小数", `[{"name":"燕麦奶","price_delta":3.001}]`, http.StatusBadRequest},
+		{"减价超过菜品价格", `[{"name":"小杯","price_delta":-18}]`, http.StatusBadRequest},
+		{"合理的加价与减价", `[{"name":"小杯","price_delta":-3},{"name":"燕麦奶","price_delta":4,"is_available":false}]`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"name":"奶类","options":` + tt.options + `}`
+			c, w := newJSONContext(http.MethodPost, "/api/admin/menu/options", body, params)
+			CreateMenuItemOptionGroup(c)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("返回 %d，期望 %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	var oat models.MenuOption
+	if err := db.Where("name = ?", "燕麦奶").First(&oat).Error; err != nil {
+		t.Fatal(err)
+	}
+	if oat.IsAvailable {
+		t.Fatal("is_available=false 的选项被保存为可选")
+	}
+}
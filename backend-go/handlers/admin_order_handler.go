@@ -3,6 +3,8 @@ package handlers
 import (
 	"coffee-ordering-backend/database"
 	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -18,11 +20,16 @@ func GetAllOrders(c *gin.Context) {
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 
+	storeID, ok := adminStoreFilter(c)
+	if !ok {
+		return
+	}
+
 	db := database.GetDB()
 	var orders []models.Order
 	var total int64
 
-	query := db.Model(&models.Order{})
+	query := db.Model(&models.Order{}).Scopes(orderStoreScope(storeID))
 
 	// 状态筛选
 	if status != "" && status != "all" {
@@ -48,36 +55,35 @@ func GetAllOrders(c *gin.Context) {
 
 	// 分页
 	offset := (page - 1) * perPage
-	query.Offset(offset).Limit(perPage).Order("created_at DESC").Find(&orders)
+	query.Offset(offset).Limit(perPage).Order("created_at DESC").
+		Preload("OrderItems.MenuItem").Preload("OrderItems.Options").Preload("Discounts").Find(&orders)
 
 	// 格式化订单数据
 	orderList := make([]gin.H, 0)
 	for _, order := range orders {
-		// 获取订单项数量和计算总价
-		var itemCount int64
-		var orderItems []models.OrderItem
-		db.Model(&models.OrderItem{}).Where("order_id = ?", order.ID).Find(&orderItems)
-		itemCount = int64(len(orderItems))
-
-		// 计算订单总价
-		totalPrice := 0.0
-		for _, item := range orderItems {
-			totalPrice += item.GetSubtotal()
-		}
+		// 订单项（含定制选项，供出品查看）及总价
+		items, totalPrice := formatOrderItems(order.OrderItems)
+		itemCount := int64(len(order.OrderItems))
 
 		// 计算最终支付金额
-		finalPrice := totalPrice - order.PointsDeductionAmount
+		finalPrice := totalPrice - order.DiscountAmount - order.PointsDeductionAmount
 
 		orderList = append(orderList, gin.H{
 			"id":                      order.ID,
 			"order_number":            order.OrderNumber,
+			"store_id":                order.StoreID,
 			"pickup_code":             order.PickupCode,
 			"original_total_price":    totalPrice,
+			"discount_amount":         order.DiscountAmount,
+			"discounts":               formatOrderDiscounts(order.Discounts),
 			"points_deduction_amount": order.PointsDeductionAmount,
 			"final_payment_amount":    finalPrice,
 			"status":                  order.Status,
+			"payment_status":          order.PaymentStatus,
+			"scheduled_pickup_at":     order.ScheduledPickupAt,
 			"notes":                   order.Notes,
 			"item_count":              itemCount,
+			"items":                   items,
 			"created_at":              order.CreatedAt,
 			"updated_at":              order.UpdatedAt,
 		})
@@ -97,10 +103,16 @@ func GetAllOrders(c *gin.Context) {
 
 // UpdateOrderStatus 更新订单状态（管理员）
 func UpdateOrderStatus(c *gin.Context) {
+	if !ensureAdminOrderAccess(c, c.Param("id")) {
+		return
+	}
+
 	id := c.Param("id")
 
 	var req struct {
-		Status string `json:"status" binding:"required"`
+		Status  string `json:"status" binding:"required"`
+		Reason  string `json:"reason"`
+		Restock *bool  `json:"restock"` // 取消时是否恢复原料库存，不传时仅制作前取消才恢复
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -111,23 +123,170 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	// 验证状态值
-	validStatuses := map[string]bool{
-		"pending":   true,
-		"preparing": true,
-		"ready":     true,
-		"completed": true,
-		"cancelled": true,
+	orderID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的订单ID"},
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	// 在事务内更新状态，状态钩子（如积分发放）与状态变更一起提交
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	statusTx := tx
+	if req.Restock != nil && models.OrderStatus(req.Status) == models.OrderStatusCancelled {
+		statusTx = services.WithRestock(tx, *req.Restock)
+	}
+
+	orderService := services.NewOrderService()
+	order, err := orderService.UpdateStatus(statusTx, uint(orderID), models.OrderStatus(req.Status), statusActorFromContext(c), req.Reason)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, services.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"errors":  []string{"订单不存在"},
+			})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidOrderStatus) || errors.Is(err, services.ErrIllegalStatusTransition) ||
+			errors.Is(err, services.ErrOrderUnpaid) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{err.Error()},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新订单状态失败: " + err.Error()},
+		})
+		return
 	}
 
-	if !validStatuses[req.Status] {
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新订单状态失败: " + err.Error()},
+		})
+		return
+	}
+
+	services.PublishOrderEvent(services.OrderEventStatusChanged, order)
+
+	orderData := gin.H{
+		"id":           order.ID,
+		"order_number": order.OrderNumber,
+		"pickup_code":  order.PickupCode,
+		"status":       order.Status,
+		"updated_at":   order.UpdatedAt,
+	}
+
+	// 取消订单登记的退款在事务提交后调用渠道，失败的由定时任务重试
+	if order.Status == models.OrderStatusCancelled {
+		services.NewPaymentService().ProcessOrderRefunds(db, order.ID)
+
+		restored, _ := services.NewInventoryService().StockRestored(db, order.ID)
+		orderData["stock_restored"] = restored
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "订单状态更新成功",
+		"order":   orderData,
+	})
+}
+
+// UpdateOrderItemStatus 更新单品出品状态（管理员），订单状态随之汇总
+func UpdateOrderItemStatus(c *gin.Context) {
+	if !ensureAdminOrderAccess(c, c.Param("id")) {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"errors":  []string{"无效的订单状态"},
+			"errors":  []string{"请求参数错误: " + err.Error()},
 		})
 		return
 	}
 
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的订单ID"},
+		})
+		return
+	}
+	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的订单项ID"},
+		})
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	itemService := services.NewOrderItemService()
+	item, order, err := itemService.UpdateStatus(tx, uint(orderID), uint(itemID), models.OrderItemStatus(req.Status), statusActorFromContext(c))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(kdsErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新订单项状态失败: " + err.Error()},
+		})
+		return
+	}
+
+	services.PublishOrderEvent(services.OrderEventItemUpdated, order)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "订单项状态更新成功",
+		"data": gin.H{
+			"item":         item,
+			"order_status": order.Status,
+		},
+	})
+}
+
+// GetOrderStatusHistory 获取订单状态变更历史（管理员）
+func GetOrderStatusHistory(c *gin.Context) {
+	if !ensureAdminOrderAccess(c, c.Param("id")) {
+		return
+	}
+
+	id := c.Param("id")
+
 	db := database.GetDB()
 	var order models.Order
 
@@ -139,25 +298,23 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	// 更新状态
-	order.Status = models.OrderStatus(req.Status)
-	if err := db.Save(&order).Error; err != nil {
+	orderService := services.NewOrderService()
+	history, err := orderService.GetStatusHistory(db, order.ID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"errors":  []string{"更新订单状态失败: " + err.Error()},
+			"errors":  []string{"获取状态历史失败: " + err.Error()},
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "订单状态更新成功",
-		"order": gin.H{
-			"id":           order.ID,
+		"data": gin.H{
+			"order_id":     order.ID,
 			"order_number": order.OrderNumber,
-			"pickup_code":  order.PickupCode,
 			"status":       order.Status,
-			"updated_at":   order.UpdatedAt,
+			"history":      history,
 		},
 	})
 }
@@ -167,8 +324,14 @@ func GetOrderStatistics(c *gin.Context) {
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 
+	storeID, ok := adminStoreFilter(c)
+	if !ok {
+		return
+	}
+	storeScope := orderStoreScope(storeID)
+
 	db := database.GetDB()
-	query := db.Model(&models.Order{})
+	query := db.Model(&models.Order{}).Scopes(storeScope)
 
 	// 日期范围筛选
 	if startDate != "" {
@@ -192,7 +355,7 @@ func GetOrderStatistics(c *gin.Context) {
 		Status string
 		Count  int64
 	}
-	db.Model(&models.Order{}).
+	db.Model(&models.Order{}).Scopes(storeScope).
 		Select("status, COUNT(*) as count").
 		Group("status").
 		Scan(&statusCounts)
@@ -204,22 +367,29 @@ func GetOrderStatistics(c *gin.Context) {
 
 	// 总收入（所有非取消订单）- 通过order_items动态计算
 	var totalRevenue float64
-	db.Table("orders").
+	db.Table("orders").Scopes(storeScope).
 		Joins("INNER JOIN order_items ON orders.id = order_items.order_id").
 		Where("orders.status != ?", "cancelled").
 		Select("COALESCE(SUM(order_items.quantity * order_items.unit_price), 0)").
 		Scan(&totalRevenue)
 
+	// 优惠总额（所有非取消订单）
+	var totalDiscount float64
+	db.Model(&models.Order{}).Scopes(storeScope).
+		Where("status != ?", "cancelled").
+		Select("COALESCE(SUM(discount_amount), 0)").
+		Scan(&totalDiscount)
+
 	// 今日订单数
 	today := time.Now().Truncate(24 * time.Hour)
 	var todayOrders int64
-	db.Model(&models.Order{}).
+	db.Model(&models.Order{}).Scopes(storeScope).
 		Where("created_at >= ?", today).
 		Count(&todayOrders)
 
 	// 今日收入（所有非取消订单）- 通过order_items动态计算
 	var todayRevenue float64
-	db.Table("orders").
+	db.Table("orders").Scopes(storeScope).
 		Joins("INNER JOIN order_items ON orders.id = order_items.order_id").
 		Where("orders.status != ? AND orders.created_at >= ?", "cancelled", today).
 		Select("COALESCE(SUM(order_items.quantity * order_items.unit_price), 0)").
@@ -252,14 +422,51 @@ func GetOrderStatistics(c *gin.Context) {
 		Revenue  float64 `json:"revenue"`
 	}
 	var topProducts []TopProduct
-	db.Table("order_items").
+	db.Table("order_items").Scopes(storeScope).
 		Select("order_items.menu_item_id, menu_items.name as menu_name, SUM(order_items.quantity) as quantity, SUM(order_items.quantity * order_items.unit_price) as revenue").
+		Joins("INNER JOIN orders ON order_items.order_id = orders.id").
 		Joins("LEFT JOIN menu_items ON order_items.menu_item_id = menu_items.id").
 		Group("order_items.menu_item_id, menu_items.name").
 		Order("quantity DESC").
 		Limit(5).
 		Scan(&topProducts)
 
+	// 热门定制选项Top10（不含取消订单）
+	type TopOption struct {
+		GroupName  string  `json:"group_name"`
+		OptionName string  `json:"option_name"`
+		Quantity   int64   `json:"quantity"`
+		Revenue    float64 `json:"revenue"`
+	}
+	var topOptions []TopOption
+	db.Table("order_item_options").Scopes(storeScope).
+		Select("order_item_options.group_name, order_item_options.option_name, SUM(order_items.quantity) as quantity, SUM(order_items.quantity * order_item_options.price_delta) as revenue").
+		Joins("INNER JOIN order_items ON order_item_options.order_item_id = order_items.id").
+		Joins("INNER JOIN orders ON order_items.order_id = orders.id").
+		Where("orders.status != ?", "cancelled").
+		Group("order_item_options.group_name, order_item_options.option_name").
+		Order("quantity DESC").
+		Limit(10).
+		Scan(&topOptions)
+
+	// 优惠使用情况（不含取消订单）
+	type PromotionUsage struct {
+		PromotionID uint    `json:"promotion_id"`
+		Name        string  `json:"name"`
+		Code        string  `json:"code"`
+		Uses        int64   `json:"uses"`
+		Amount      float64 `json:"amount"`
+	}
+	var promotionUsage []PromotionUsage
+	db.Table("order_discounts").Scopes(storeScope).
+		Select("order_discounts.promotion_id, MAX(order_discounts.name) as name, MAX(order_discounts.code) as code, COUNT(*) as uses, SUM(order_discounts.amount) as amount").
+		Joins("INNER JOIN orders ON order_discounts.order_id = orders.id").
+		Where("orders.status != ?", "cancelled").
+		Group("order_discounts.promotion_id").
+		Order("amount DESC").
+		Limit(10).
+		Scan(&promotionUsage)
+
 	// 最近7天订单趋势 - 使用小写json字段名
 	type DailyOrder struct {
 		Date    string  `json:"date"`
@@ -268,7 +475,7 @@ func GetOrderStatistics(c *gin.Context) {
 	}
 	var dailyOrders []DailyOrder
 	sevenDaysAgo := time.Now().AddDate(0, 0, -6).Truncate(24 * time.Hour)
-	db.Table("orders").
+	db.Table("orders").Scopes(storeScope).
 		Select("DATE(orders.created_at) as date, COUNT(*) as count, COALESCE(SUM(order_items.quantity * order_items.unit_price), 0) as revenue").
 		Joins("INNER JOIN order_items ON orders.id = order_items.order_id").
 		Where("orders.created_at >= ?", sevenDaysAgo).
@@ -283,6 +490,7 @@ func GetOrderStatistics(c *gin.Context) {
 			"total_revenue":   totalRevenue,
 			"today_orders":    todayOrders,
 			"today_revenue":   todayRevenue,
+			"total_discount":  totalDiscount,
 			"status_counts":   statusMap,
 			"pending_count":   statusMap["pending"],
 			"preparing_count": statusMap["preparing"],
@@ -292,28 +500,44 @@ func GetOrderStatistics(c *gin.Context) {
 			"total_users":     totalUsers,
 			"member_levels":   memberLevelMap,
 			"top_products":    topProducts,
+			"top_options":     topOptions,
+			"promotion_usage": promotionUsage,
 			"daily_orders":    dailyOrders,
+			"store_id":        storeID,
 		},
 	})
 }
 
-// DeleteOrder 删除订单（管理员）
+// DeleteOrder 删除订单（管理员），仅限已取消且无待处理支付与退款的订单
 func DeleteOrder(c *gin.Context) {
-	id := c.Param("id")
-
-	db := database.GetDB()
-	var order models.Order
+	if !ensureAdminOrderAccess(c, c.Param("id")) {
+		return
+	}
 
-	if err := db.First(&order, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"errors":  []string{"订单不存在"},
+			"errors":  []string{"无效的订单ID"},
 		})
 		return
 	}
 
-	// 删除订单（会级联删除订单项）
-	if err := db.Delete(&order).Error; err != nil {
+	if err := services.NewOrderService().DeleteOrder(database.GetDB(), uint(orderID)); err != nil {
+		if errors.Is(err, services.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"errors":  []string{"订单不存在"},
+			})
+			return
+		}
+		if errors.Is(err, services.ErrOrderNotDeletable) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{err.Error()},
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"errors":  []string{"删除订单失败: " + err.Error()},
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/middleware"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RunBirthdayBonus 手动执行生日积分发放（管理员）
+// date 指定补发日期（YYYY-MM-DD，默认当天），dry_run=true 时只返回发放名单
+func RunBirthdayBonus(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能发放会员积分") {
+		return
+	}
+
+	day := time.Now()
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"日期格式应为 YYYY-MM-DD"},
+			})
+			return
+		}
+		if parsed.After(day) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"不能提前发放生日积分"},
+			})
+			return
+		}
+		day = parsed
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	result, err := services.NewBirthdayService().Run(database.GetDB(), day, dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"生日积分发放失败: " + err.Error()},
+			"data":    result,
+		})
+		return
+	}
+
+	message := "生日积分发放完成"
+	if dryRun {
+		message = "生日积分发放预演完成，未实际发放"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    result,
+	})
+}
+
+// ReconcilePoints 积分对账（管理员）：按积分变动记录核对账户余额
+// 默认只报告差异，repair=true 时以变动记录为准修正；user_id 指定只核对单个用户
+func ReconcilePoints(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能执行积分对账") {
+		return
+	}
+
+	var userID *uint
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"用户ID格式错误"},
+			})
+			return
+		}
+		uid := uint(id)
+		userID = &uid
+	}
+	repair := c.Query("repair") == "true"
+
+	result, err := services.NewPointsService().Reconcile(database.GetDB(), repair, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"积分对账失败: " + err.Error()},
+			"data":    result,
+		})
+		return
+	}
+
+	message := "积分对账完成"
+	if repair {
+		message = "积分对账完成，已修正余额差异"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    result,
+	})
+}
+
+// PointsAdjustmentRequest 手动积分调整请求
+type PointsAdjustmentRequest struct {
+	Points int    `json:"points" binding:"required"` // 正数为补偿，负数为扣减
+	Reason string `json:"reason" binding:"required,max=200"`
+}
+
+// PointsAdjustmentReviewRequest 积分调整审批请求
+type PointsAdjustmentReviewRequest struct {
+	Note string `json:"note" binding:"max=255"`
+}
+
+// pointsAdjustmentErrorStatus 积分调整错误对应的HTTP状态码
+func pointsAdjustmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrPointsAdjustmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPointsAdjustmentReviewed):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrPointsAdjustmentSelfReview):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// AdjustUserPoints 手动调整用户积分（管理员）
+// 超过审批阈值的调整进入待审批状态，由另一位管理员审批后到账
+func AdjustUserPoints(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能调整会员积分") {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"用户ID格式错误"},
+		})
+		return
+	}
+
+	var req PointsAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	operatorID, _ := middleware.GetUserID(c)
+	adjustment, err := services.NewPointsAdjustmentService().Request(database.GetDB(), uint(userID), req.Points, req.Reason, operatorID)
+	if err != nil {
+		c.JSON(pointsAdjustmentErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if adjustment.Status == models.PointsAdjustmentPending {
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "调整数量超过审批阈值，已提交另一位管理员审批",
+			"data":    adjustment,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "积分调整成功",
+		"data":    adjustment,
+	})
+}
+
+// GetPointsAdjustments 获取积分调整记录（管理员），可按状态和用户筛选
+func GetPointsAdjustments(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能调整会员积分") {
+		return
+	}
+
+	query := database.GetDB().Order("id DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var adjustments []models.PointsAdjustment
+	query.Limit(200).Find(&adjustments)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    adjustments,
+	})
+}
+
+// ApprovePointsAdjustment 审批通过积分调整（管理员，不能审批自己发起的调整）
+func ApprovePointsAdjustment(c *gin.Context) {
+	reviewPointsAdjustment(c, true)
+}
+
+// RejectPointsAdjustment 驳回积分调整（管理员，不能审批自己发起的调整）
+func RejectPointsAdjustment(c *gin.Context) {
+	reviewPointsAdjustment(c, false)
+}
+
+// reviewPointsAdjustment 审批或驳回积分调整
+func reviewPointsAdjustment(c *gin.Context, approve bool) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能调整会员积分") {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"调整记录ID格式错误"},
+		})
+		return
+	}
+
+	var req PointsAdjustmentReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"请求参数错误: " + err.Error()},
+			})
+			return
+		}
+	}
+
+	reviewerID, _ := middleware.GetUserID(c)
+	adjustmentService := services.NewPointsAdjustmentService()
+	var adjustment *models.PointsAdjustment
+	message := "积分调整已审批到账"
+	if approve {
+		adjustment, err = adjustmentService.Approve(database.GetDB(), uint(id), reviewerID, req.Note)
+	} else {
+		adjustment, err = adjustmentService.Reject(database.GetDB(), uint(id), reviewerID, req.Note)
+		message = "积分调整已驳回"
+	}
+	if err != nil {
+		c.JSON(pointsAdjustmentErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    adjustment,
+	})
+}
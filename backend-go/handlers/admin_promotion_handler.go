@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PromotionRequest 优惠活动请求
+type PromotionRequest struct {
+	Name              string               `json:"name" binding:"required,max=100"`
+	Description       string               `json:"description"`
+	Code              string               `json:"code" binding:"max=50"` // 为空表示自动促销
+	Type              models.PromotionType `json:"type" binding:"required"`
+	Category          string               `json:"category"`
+	MenuItemID        *uint                `json:"menu_item_id"`
+	DiscountValue     float64              `json:"discount_value"`
+	MaxDiscountAmount float64              `json:"max_discount_amount"`
+	BuyQuantity       int                  `json:"buy_quantity"`
+	GetQuantity       int                  `json:"get_quantity"`
+	MinSpend          float64              `json:"min_spend"`
+	MemberLevels      []string             `json:"member_levels"`
+	StartsAt          *time.Time           `json:"starts_at"`
+	EndsAt            *time.Time           `json:"ends_at"`
+	UsageLimit        int                  `json:"usage_limit"`
+	PerUserLimit      int                  `json:"per_user_limit"`
+	IsActive          *bool                `json:"is_active"`
+}
+
+// toModel 转换为优惠活动模型
+func (r *PromotionRequest) toModel() models.Promotion {
+	promotion := models.Promotion{
+		Name:              r.Name,
+		Description:       r.Description,
+		Type:              r.Type,
+		Category:          r.Category,
+		MenuItemID:        r.MenuItemID,
+		DiscountValue:     r.DiscountValue,
+		MaxDiscountAmount: r.MaxDiscountAmount,
+		BuyQuantity:       r.BuyQuantity,
+		GetQuantity:       r.GetQuantity,
+		MinSpend:          r.MinSpend,
+		MemberLevels:      strings.Join(r.MemberLevels, ","),
+		StartsAt:          r.StartsAt,
+		EndsAt:            r.EndsAt,
+		UsageLimit:        r.UsageLimit,
+		PerUserLimit:      r.PerUserLimit,
+		IsActive:          r.IsActive == nil || *r.IsActive,
+	}
+	if code := services.NormalizeCode(r.Code); code != "" {
+		promotion.Code = &code
+	}
+	return promotion
+}
+
+// promotionCodeTaken 券码是否已被其他优惠活动使用
+func promotionCodeTaken(p *models.Promotion) bool {
+	if p.Code == nil {
+		return false
+	}
+	var count int64
+	database.GetDB().Model(&models.Promotion{}).Where("code = ? AND id <> ?", *p.Code, p.ID).Count(&count)
+	return count > 0
+}
+
+// GetPromotions 获取优惠活动列表（管理员），含有效使用次数
+func GetPromotions(c *gin.Context) {
+	db := database.GetDB()
+	query := db.Order("id DESC")
+
+	switch c.Query("kind") {
+	case "coupon":
+		query = query.Where("code IS NOT NULL AND code <> ''")
+	case "auto":
+		query = query.Where("code IS NULL OR code = ''")
+	}
+	if active := c.Query("is_active"); active != "" {
+		query = query.Where("is_active = ?", active == "true")
+	}
+
+	var promotions []models.Promotion
+	query.Find(&promotions)
+
+	promotionService := services.NewPromotionService()
+	result := make([]gin.H, 0, len(promotions))
+	for _, p := range promotions {
+		used, _ := promotionService.UsageCount(db, p.ID, nil)
+		result = append(result, gin.H{
+			"promotion":  p,
+			"used_count": used,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// CreatePromotion 创建优惠活动（管理员）
+func CreatePromotion(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能维护优惠活动") {
+		return
+	}
+
+	var req PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	promotion := req.toModel()
+	if err := services.NewPromotionService().Validate(&promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if promotionCodeTaken(&promotion) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"优惠券码已存在"},
+		})
+		return
+	}
+
+	if err := database.GetDB().Create(&promotion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"创建优惠活动失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "优惠活动创建成功",
+		"data":    promotion,
+	})
+}
+
+// UpdatePromotion 更新优惠活动（管理员）
+func UpdatePromotion(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能维护优惠活动") {
+		return
+	}
+
+	db := database.GetDB()
+	var existing models.Promotion
+	if err := db.First(&existing, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"优惠活动不存在"},
+		})
+		return
+	}
+
+	var req PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	promotion := req.toModel()
+	promotion.ID = existing.ID
+	promotion.CreatedAt = existing.CreatedAt
+	if req.IsActive == nil {
+		promotion.IsActive = existing.IsActive
+	}
+	if err := services.NewPromotionService().Validate(&promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if promotionCodeTaken(&promotion) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"优惠券码已存在"},
+		})
+		return
+	}
+
+	if err := db.Save(&promotion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新优惠活动失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "优惠活动更新成功",
+		"data":    promotion,
+	})
+}
+
+// DeletePromotion 删除优惠活动（管理员），已被订单使用的只能停用
+func DeletePromotion(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能维护优惠活动") {
+		return
+	}
+
+	db := database.GetDB()
+	var promotion models.Promotion
+	if err := db.First(&promotion, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"优惠活动不存在"},
+		})
+		return
+	}
+
+	var count int64
+	db.Model(&models.OrderDiscount{}).Where("promotion_id = ?", promotion.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"该优惠活动已被订单使用，无法删除，请改为停用"},
+		})
+		return
+	}
+
+	if err := db.Delete(&promotion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"删除优惠活动失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "优惠活动删除成功",
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"coffee-ordering-backend/testutil"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCreatePromotionKeepsInactiveCoupon(t *testing.T) {
+	db := testutil.NewDB(t)
+
+	body := `{"name":"停用券","code":"off10","type":"fixed_off","discount_value":10,"is_active":false}`
+	c, w := newJSONContext(http.MethodPost, "/api/admin/promotions", body, nil)
+
+	CreatePromotion(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建优惠券返回 %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored models.Promotion
+	if err := db.Where("code = ?", "OFF10").First(&stored).Error; err != nil {
+		t.Fatalf("查询优惠券失败: %v", err)
+	}
+	if stored.IsActive {
+		t.Fatal("is_active=false 的优惠券被保存为启用")
+	}
+
+	priced := &services.PricedOrder{Total: 30}
+	ctx := services.PromotionContext{CouponCode: "off10", Now: time.Now()}
+	_, err := services.NewPromotionService().Evaluate(db, priced, ctx, false)
+	if !errors.Is(err, services.ErrCouponNotApplicable) {
+		t.Fatalf("停用的优惠券应被拒绝，实际错误: %v", err)
+	}
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// hhmmPattern 营业时间格式 HH:MM
+var hhmmPattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// StoreRequest 门店请求
+type StoreRequest struct {
+	Code             string `json:"code" binding:"required,max=20"`
+	Name             string `json:"name" binding:"required,max=100"`
+	Address          string `json:"address"`
+	Phone            string `json:"phone"`
+	Timezone         string `json:"timezone"`
+	OpenTime         string `json:"open_time"`
+	CloseTime        string `json:"close_time"`
+	LastOrderMinutes int    `json:"last_order_minutes" binding:"min=0,max=240"` // 打烊前停止接单的分钟数
+	MaxActiveOrders  int    `json:"max_active_orders" binding:"min=0"`          // 出品队列上限，0 表示不限
+	IsActive         *bool  `json:"is_active"`
+}
+
+// validate 校验门店配置并填充默认值
+func (r *StoreRequest) validate() string {
+	if r.Timezone == "" {
+		r.Timezone = models.DefaultStoreTimezone
+	}
+	if _, err := time.LoadLocation(r.Timezone); err != nil {
+		return "无效的时区: " + r.Timezone
+	}
+	if r.OpenTime == "" {
+		r.OpenTime = "08:00"
+	}
+	if r.CloseTime == "" {
+		r.CloseTime = "22:00"
+	}
+	if !hhmmPattern.MatchString(r.OpenTime) || !hhmmPattern.MatchString(r.CloseTime) {
+		return "营业时间格式应为 HH:MM"
+	}
+	if r.CloseTime <= r.OpenTime {
+		return "打烊时间必须晚于开门时间"
+	}
+	return ""
+}
+
+// StoreMenuItemRequest 门店菜品设置请求
+type StoreMenuItemRequest struct {
+	Price       *float64 `json:"price"` // 为空表示使用菜单价格
+	IsAvailable *bool    `json:"is_available"`
+}
+
+// adminStoreScope 获取管理员绑定的门店，未绑定时可管理全部门店
+func adminStoreScope(c *gin.Context) (uint, bool) {
+	storeID, exists := c.Get("store_id")
+	if !exists {
+		return 0, false
+	}
+	return storeID.(uint), true
+}
+
+// ensureUnscopedAdmin 跨门店生效的操作（会员、积分、优惠活动、全局菜单与库存等）仅限未绑定门店的管理员
+// 绑定门店的管理员返回403，message 为拒绝原因
+func ensureUnscopedAdmin(c *gin.Context, message string) bool {
+	if _, scoped := adminStoreScope(c); scoped {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"errors":  []string{message},
+		})
+		return false
+	}
+	return true
+}
+
+// adminStoreFilter 解析管理员查询的门店范围
+// 绑定门店的管理员只能查询本店；未绑定的管理员可通过 store_id 参数筛选，不传表示全部门店
+func adminStoreFilter(c *gin.Context) (*uint, bool) {
+	var requested *uint
+	if raw := c.Query("store_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"无效的门店ID"},
+			})
+			return nil, false
+		}
+		storeID := uint(id)
+		requested = &storeID
+	}
+
+	scope, scoped := adminStoreScope(c)
+	if !scoped {
+		return requested, true
+	}
+	if requested != nil && *requested != scope {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"errors":  []string{"无权访问其他门店"},
+		})
+		return nil, false
+	}
+	return &scope, true
+}
+
+// ensureAdminStoreAccess 校验管理员可管理指定门店
+func ensureAdminStoreAccess(c *gin.Context, storeID uint) bool {
+	if scope, scoped := adminStoreScope(c); scoped && scope != storeID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"errors":  []string{"无权访问其他门店"},
+		})
+		return false
+	}
+	return true
+}
+
+// ensureAdminOrderAccess 校验管理员可管理指定订单所属门店
+func ensureAdminOrderAccess(c *gin.Context, orderID string) bool {
+	if _, scoped := adminStoreScope(c); !scoped {
+		return true
+	}
+
+	var order models.Order
+	if err := database.GetDB().Select("id", "store_id").First(&order, orderID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"订单不存在"},
+		})
+		return false
+	}
+	return ensureAdminStoreAccess(c, order.StoreID)
+}
+
+// orderStoreScope 按门店筛选订单的查询条件
+func orderStoreScope(storeID *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if storeID == nil {
+			return db
+		}
+		return db.Where("orders.store_id = ?", *storeID)
+	}
+}
+
+// GetStores 获取营业中的门店列表（公开）
+func GetStores(c *gin.Context) {
+	var stores []models.Store
+	database.GetDB().Where("is_active = ?", true).Order("id ASC").Find(&stores)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stores,
+	})
+}
+
+// GetAllStoresAdmin 获取门店列表（管理员）
+func GetAllStoresAdmin(c *gin.Context) {
+	storeID, ok := adminStoreFilter(c)
+	if !ok {
+		return
+	}
+
+	query := database.GetDB().Order("id ASC")
+	if storeID != nil {
+		query = query.Where("id = ?", *storeID)
+	}
+
+	var stores []models.Store
+	query.Find(&stores)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stores,
+	})
+}
+
+// CreateStore 创建门店（仅限未绑定门店的管理员）
+func CreateStore(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能创建门店") {
+		return
+	}
+
+	var req StoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{msg},
+		})
+		return
+	}
+
+	store := models.Store{
+		Code:      req.Code,
+		Name:      req.Name,
+		Address:   req.Address,
+		Phone:     req.Phone,
+		Timezone:  req.Timezone,
+		OpenTime:  req.OpenTime,
+		CloseTime: req.CloseTime,
+		IsActive:  req.IsActive == nil || *req.IsActive,
+
+		LastOrderMinutes: req.LastOrderMinutes,
+		MaxActiveOrders:  req.MaxActiveOrders,
+	}
+
+	if err := database.GetDB().Create(&store).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"创建门店失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "门店创建成功",
+		"data":    store,
+	})
+}
+
+// UpdateStore 更新门店信息（管理员）
+func UpdateStore(c *gin.Context) {
+	db := database.GetDB()
+	var store models.Store
+
+	if err := db.First(&store, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"门店不存在"},
+		})
+		return
+	}
+	if !ensureAdminStoreAccess(c, store.ID) {
+		return
+	}
+
+	var req StoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{msg},
+		})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"code":       req.Code,
+		"name":       req.Name,
+		"address":    req.Address,
+		"phone":      req.Phone,
+		"timezone":   req.Timezone,
+		"open_time":  req.OpenTime,
+		"close_time": req.CloseTime,
+
+		"last_order_minutes": req.LastOrderMinutes,
+		"max_active_orders":  req.MaxActiveOrders,
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if err := db.Model(&store).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新门店失败: " + err.Error()},
+		})
+		return
+	}
+
+	db.First(&store, store.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "门店更新成功",
+		"data":    store,
+	})
+}
+
+// GetStoreMenuItems 获取门店菜品设置（管理员）
+func GetStoreMenuItems(c *gin.Context) {
+	db := database.GetDB()
+	var store models.Store
+
+	if err := db.First(&store, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"门店不存在"},
+		})
+		return
+	}
+	if !ensureAdminStoreAccess(c, store.ID) {
+		return
+	}
+
+	var overrides []models.StoreMenuItem
+	db.Preload("MenuItem").Where("store_id = ?", store.ID).Order("menu_item_id ASC").Find(&overrides)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    overrides,
+	})
+}
+
+// UpdateStoreMenuItem 设置门店菜品价格与上下架（管理员）
+func UpdateStoreMenuItem(c *gin.Context) {
+	db := database.GetDB()
+	var store models.Store
+
+	if err := db.First(&store, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"门店不存在"},
+		})
+		return
+	}
+	if !ensureAdminStoreAccess(c, store.ID) {
+		return
+	}
+
+	var menuItem models.MenuItem
+	if err := db.First(&menuItem, c.Param("menu_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"菜单项不存在"},
+		})
+		return
+	}
+
+	var req StoreMenuItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+	if req.Price != nil && *req.Price < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"价格不能为负数"},
+		})
+		return
+	}
+
+	override := models.StoreMenuItem{StoreID: store.ID, MenuItemID: menuItem.ID, IsAvailable: true}
+	db.Where("store_id = ? AND menu_item_id = ?", store.ID, menuItem.ID).First(&override)
+
+	override.Price = req.Price
+	if req.IsAvailable != nil {
+		override.IsAvailable = *req.IsAvailable
+	}
+
+	// 按门店与菜品唯一键写入，并发保存同一菜品时不会重复插入
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "store_id"}, {Name: "menu_item_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price", "is_available", "updated_at"}),
+	}).Create(&override).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"保存门店菜品设置失败: " + err.Error()},
+		})
+		return
+	}
+	db.Where("store_id = ? AND menu_item_id = ?", store.ID, menuItem.ID).First(&override)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "门店菜品设置已保存",
+		"data":    override,
+	})
+}
+
+// DeleteStoreMenuItem 删除门店菜品设置，恢复使用菜单价格与状态（管理员）
+func DeleteStoreMenuItem(c *gin.Context) {
+	storeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的门店ID"},
+		})
+		return
+	}
+	if !ensureAdminStoreAccess(c, uint(storeID)) {
+		return
+	}
+
+	if err := database.GetDB().Where("store_id = ? AND menu_item_id = ?", storeID, c.Param("menu_id")).
+		Delete(&models.StoreMenuItem{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"删除门店菜品设置失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已恢复使用菜单设置",
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/testutil"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateStoreKeepsInactiveFlag(t *testing.T) {
+	db := testutil.NewDB(t)
+
+	body := `{"code":"S2","name":"筹备中门店","is_active":false}`
+	c, w := newJSONContext(http.MethodPost, "/api/admin/stores", body, nil)
+	CreateStore(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建门店返回 %d: %s", w.Code, w.Body.String())
+	}
+
+	var store models.Store
+	if err := db.Where("code = ?", "S2").First(&store).Error; err != nil {
+		t.Fatal(err)
+	}
+	if store.IsActive {
+		t.Fatal("is_active=false 的门店被保存为营业中")
+	}
+}
+
+func TestUpdateStoreMenuItemAvailability(t *testing.T) {
+	db := testutil.NewDB(t)
+	store := models.Store{Code: "S1", Name: "测试门店", Timezone: "UTC", OpenTime: "08:00", CloseTime: "22:00", IsActive: true}
+	item := models.MenuItem{Name: "拿铁", Price: 18, Category: "coffee", IsAvailable: true}
+	if err := db.Create(&store).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "id", Value: fmt.Sprint(store.ID)}, {Key: "menu_id", Value: fmt.Sprint(item.ID)}}
+
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"首次设置即下架", `{"is_available":false}`, false},
+		{"重新上架", `{"is_available":true}`, true},
+		{"再次下架", `{"is_available":false}`, false},
+		{"仅改价格保留下架状态", `{"price":20}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONContext(http.MethodPut, "/api/admin/stores/menu", tt.body, params)
+			UpdateStoreMenuItem(c)
+			if w.Code != http.StatusOK {
+				t.Fatalf("保存门店菜品设置返回 %d: %s", w.Code, w.Body.String())
+			}
+
+			var overrides []models.StoreMenuItem
+			db.Where("store_id = ? AND menu_item_id = ?", store.ID, item.ID).Find(&overrides)
+			if len(overrides) != 1 {
+				t.Fatalf("门店菜品设置应只有一条，实际 %d 条", len(overrides))
+			}
+			if overrides[0].IsAvailable != tt.want {
+				t.Fatalf("is_available = %v，期望 %v", overrides[0].IsAvailable, tt.want)
+			}
+		})
+	}
+}
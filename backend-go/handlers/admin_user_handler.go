@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/middleware"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UserStatusRequest 启用/禁用用户请求
+type UserStatusRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+// UserRoleRequest 修改用户角色请求
+type UserRoleRequest struct {
+	Role    string `json:"role" binding:"required"` // user、owner、admin、manager、barista、cashier
+	StoreID *uint  `json:"store_id"`                // 仅员工角色有效，为空表示可管理全部门店
+}
+
+// loadAdminUser 按路径参数加载用户，不存在时返回404
+func loadAdminUser(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := database.GetDB().Preload("UserPoints").First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"用户不存在"},
+		})
+		return nil, false
+	}
+	return &user, true
+}
+
+// isSelf 是否为当前登录的管理员本人
+func isSelf(c *gin.Context, user *models.User) bool {
+	operatorID, exists := middleware.GetUserID(c)
+	return exists && operatorID == user.ID
+}
+
+// ensureCanManageUser 操作人不能管理权限高于自己的账户（如非店主管理店主或系统管理员）
+func ensureCanManageUser(c *gin.Context, user *models.User) bool {
+	if !models.CanAssignRole(c.GetString("role"), user.Role) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"errors":  []string{"不能管理权限高于自己的账户"},
+		})
+		return false
+	}
+	return true
+}
+
+// formatAdminUser 管理后台用户信息，在用户资料基础上附加角色与门店
+func formatAdminUser(user *models.User) gin.H {
+	data := gin.H{
+		"user":        user.ToResponse(),
+		"role":        user.Role,
+		"store_id":    user.StoreID,
+		"permissions": models.RolePermissions(user.Role),
+	}
+	if user.UserPoints != nil {
+		data["total_points"] = user.UserPoints.TotalPoints
+		data["lifetime_points"] = user.UserPoints.LifetimePoints
+	}
+	return data
+}
+
+// GetAdminUsers 获取用户列表（管理员）
+// 支持按用户名、姓名、邮箱、手机号搜索（q），按角色（role=staff 表示全部员工）与状态筛选，分页返回
+func GetAdminUsers(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能管理用户") {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	db := database.GetDB()
+	query := db.Model(&models.User{})
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("username LIKE ? OR email LIKE ? OR phone LIKE ? OR first_name LIKE ? OR last_name LIKE ? OR CONCAT(first_name, ' ', last_name) LIKE ?",
+			like, like, like, like, like, like)
+	}
+	switch role := c.Query("role"); role {
+	case "":
+	case "staff":
+		query = query.Where("role IN ?", models.StaffRoles())
+	default:
+		query = query.Where("role = ?", role)
+	}
+	if active := c.Query("is_active"); active != "" {
+		query = query.Where("is_active = ?", active == "true")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var users []models.User
+	query.Preload("UserPoints").Order("id DESC").
+		Offset((page - 1) * perPage).Limit(perPage).Find(&users)
+
+	userList := make([]gin.H, 0, len(users))
+	for i := range users {
+		userList = append(userList, formatAdminUser(&users[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"users":    userList,
+			"total":    total,
+			"page":     page,
+			"per_page": perPage,
+			"pages":    (total + int64(perPage) - 1) / int64(perPage),
+		},
+	})
+}
+
+// GetAdminUserDetail 获取用户详情（管理员），含积分账户、最近订单与积分记录
+func GetAdminUserDetail(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能管理用户") {
+		return
+	}
+
+	user, ok := loadAdminUser(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	var orders []models.Order
+	db.Where("user_id = ?", user.ID).Order("created_at DESC").Limit(20).Find(&orders)
+	orderList := make([]gin.H, 0, len(orders))
+	for _, order := range orders {
+		orderList = append(orderList, gin.H{
+			"id":                   order.ID,
+			"order_number":         order.OrderNumber,
+			"store_id":             order.StoreID,
+			"status":               order.Status,
+			"payment_status":       order.PaymentStatus,
+			"payment_amount":       order.PaymentAmount,
+			"customer_points_used": order.CustomerPointsUsed,
+			"points_earned":        order.PointsEarned,
+			"created_at":           order.CreatedAt,
+		})
+	}
+
+	var orderStats struct {
+		OrderCount int64
+		TotalSpent float64
+	}
+	db.Model(&models.Order{}).
+		Select("COUNT(*) AS order_count, COALESCE(SUM(CASE WHEN payment_status = ? THEN payment_amount ELSE 0 END), 0) AS total_spent", models.OrderPaymentPaid).
+		Where("user_id = ?", user.ID).Scan(&orderStats)
+
+	var transactions []models.PointTransaction
+	db.Where("user_id = ? AND deleted_at IS NULL", user.ID).Order("created_at DESC").Limit(50).Find(&transactions)
+
+	var adjustments []models.PointsAdjustment
+	db.Where("user_id = ?", user.ID).Order("id DESC").Limit(20).Find(&adjustments)
+
+	data := formatAdminUser(user)
+	data["user_points"] = user.UserPoints
+	data["orders"] = orderList
+	data["order_count"] = orderStats.OrderCount
+	data["total_spent"] = orderStats.TotalSpent
+	data["point_transactions"] = transactions
+	data["points_adjustments"] = adjustments
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// UpdateUserStatus 启用或禁用用户（管理员），禁用后已签发的令牌立即失效，且无法登录
+func UpdateUserStatus(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能管理用户") {
+		return
+	}
+
+	user, ok := loadAdminUser(c)
+	if !ok || !ensureCanManageUser(c, user) {
+		return
+	}
+
+	var req UserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	if !*req.IsActive && isSelf(c, user) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"不能禁用自己的账户"},
+		})
+		return
+	}
+
+	// 禁用时递增令牌版本，已签发的令牌立即失效
+	updates := map[string]interface{}{"is_active": *req.IsActive}
+	if !*req.IsActive {
+		updates["token_version"] = gorm.Expr("token_version + 1")
+	}
+	if err := database.GetDB().Model(user).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新用户状态失败: " + err.Error()},
+		})
+		return
+	}
+
+	message := "用户已启用"
+	if !*req.IsActive {
+		message = "用户已禁用"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    formatAdminUser(user),
+	})
+}
+
+// UpdateUserRole 修改用户角色（管理员），不能修改自己的角色
+// 店主与系统管理员仅可由店主授予，其他角色不能超出操作人自身的权限
+func UpdateUserRole(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能管理用户") {
+		return
+	}
+
+	user, ok := loadAdminUser(c)
+	if !ok || !ensureCanManageUser(c, user) {
+		return
+	}
+
+	var req UserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	if !models.IsValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的角色"},
+		})
+		return
+	}
+	if isSelf(c, user) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"不能修改自己的角色"},
+		})
+		return
+	}
+	if !models.CanAssignRole(c.GetString("role"), req.Role) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"errors":  []string{"不能授予超出自身权限的角色，店主与系统管理员仅可由店主授予"},
+		})
+		return
+	}
+
+	db := database.GetDB()
+	storeID := req.StoreID
+	if !models.IsStaffRole(req.Role) {
+		storeID = nil
+	} else if storeID != nil {
+		var count int64
+		db.Model(&models.Store{}).Where("id = ?", *storeID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"门店不存在"},
+			})
+			return
+		}
+	}
+
+	// 递增令牌版本，已签发的令牌立即失效，新角色在重新登录后生效
+	if err := db.Model(user).Updates(map[string]interface{}{
+		"role":          req.Role,
+		"store_id":      storeID,
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"修改用户角色失败: " + err.Error()},
+		})
+		return
+	}
+	user.Role = req.Role
+	user.StoreID = storeID
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "用户角色已更新，该用户原登录状态已失效",
+		"data":    formatAdminUser(user),
+	})
+}
+
+// ForceResetUserPassword 强制重置用户密码（管理员）
+// 原密码立即失效，返回一次性重置令牌，由用户通过 /api/auth/reset-password 设置新密码
+func ForceResetUserPassword(c *gin.Context) {
+	if !ensureUnscopedAdmin(c, "门店管理员不能管理用户") {
+		return
+	}
+
+	user, ok := loadAdminUser(c)
+	if !ok || !ensureCanManageUser(c, user) {
+		return
+	}
+
+	token, expiresAt, err := services.NewPasswordResetService().ForceReset(database.GetDB(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"重置密码失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "原密码已失效，请将重置令牌发送给用户",
+		"data": gin.H{
+			"user_id":     user.ID,
+			"reset_token": token,
+			"expires_at":  expiresAt,
+		},
+	})
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"coffee-ordering-backend/database"
 	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
 	"coffee-ordering-backend/utils"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -53,6 +55,15 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// 设备标识（用于推荐防刷）
+	deviceID := req.DeviceID
+	if deviceID == "" {
+		deviceID = c.GetHeader("X-Device-ID")
+	}
+	if len(deviceID) > 64 {
+		deviceID = deviceID[:64]
+	}
+
 	// 创建用户
 	user := models.User{
 		Username:  req.Username,
@@ -63,6 +74,8 @@ func Register(c *gin.Context) {
 		LastName:  req.LastName,
 		Role:      "user",
 		IsActive:  true,
+
+		RegisterDeviceID: deviceID,
 	}
 
 	// 开始事务
@@ -137,6 +150,43 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// 注册奖励积分作为首个积分批次，按有效期过期
+	if err := services.NewPointsService().CreateLot(tx, user.ID, &pointTransaction.ID, models.TransactionTypeSignupBonus, 50, pointTransaction.CreatedAt); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 推荐关系（被推荐人首笔付费订单完成后双方获得奖励）
+	referralService := services.NewReferralService()
+	if req.ReferralCode != "" {
+		if _, err := referralService.Bind(tx, &user, req.ReferralCode); err != nil {
+			tx.Rollback()
+			status := http.StatusInternalServerError
+			if errors.Is(err, services.ErrInvalidReferralCode) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	// 生成本人的推荐码
+	if _, err := referralService.EnsureCode(tx, &user); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -147,7 +197,7 @@ func Register(c *gin.Context) {
 	}
 
 	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Email, user.Role)
+	token, err := utils.GenerateToken(user.ID, user.Username, user.Email, user.Role, user.StoreID, user.TokenVersion)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -212,7 +262,7 @@ func Login(c *gin.Context) {
 	}
 
 	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Email, user.Role)
+	token, err := utils.GenerateToken(user.ID, user.Username, user.Email, user.Role, user.StoreID, user.TokenVersion)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -264,6 +314,25 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// 已禁用、已删除或令牌版本已变化（改角色、重置密码）的用户不能续期
+	if claims, err := utils.ParseToken(parts[1]); err == nil {
+		var user models.User
+		if err := database.GetDB().Select("id", "is_active", "token_version").First(&user, claims.UserID).Error; err != nil || !user.IsActive {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "账户已被禁用",
+			})
+			return
+		}
+		if user.TokenVersion != claims.TokenVersion {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "认证令牌已失效，请重新登录",
+			})
+			return
+		}
+	}
+
 	newToken, err := utils.RefreshToken(parts[1])
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -283,6 +352,35 @@ func RefreshToken(c *gin.Context) {
 	})
 }
 
+// ResetPassword 使用管理员下发的重置令牌设置新密码
+func ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	if _, err := services.NewPasswordResetService().Reset(database.GetDB(), req.Token, req.NewPassword); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "密码重置成功，请使用新密码登录",
+	})
+}
+
 
 // AdminLoginRequest 管理员登录请求
 type AdminLoginRequest struct {
@@ -290,7 +388,7 @@ type AdminLoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// AdminLogin 管理员登录
+// AdminLogin 管理后台登录，返回当前角色拥有的操作权限
 func AdminLogin(c *gin.Context) {
 	var req AdminLoginRequest
 
@@ -304,9 +402,9 @@ func AdminLogin(c *gin.Context) {
 
 	db := database.GetDB()
 
-	// 查找管理员用户
+	// 查找员工用户（店主、管理员、店长、咖啡师、收银员）
 	var user models.User
-	if err := db.Where("username = ? AND role = ?", req.Username, "admin").First(&user).Error; err != nil {
+	if err := db.Where("username = ? AND role IN ?", req.Username, models.StaffRoles()).First(&user).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "用户名或密码错误",
@@ -333,7 +431,7 @@ func AdminLogin(c *gin.Context) {
 	}
 
 	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Email, user.Role)
+	token, err := utils.GenerateToken(user.ID, user.Username, user.Email, user.Role, user.StoreID, user.TokenVersion)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -347,10 +445,12 @@ func AdminLogin(c *gin.Context) {
 		"message": "登录成功",
 		"data": gin.H{
 			"user": gin.H{
-				"id":       user.ID,
-				"username": user.Username,
-				"email":    user.Email,
-				"role":     user.Role,
+				"id":          user.ID,
+				"username":    user.Username,
+				"email":       user.Email,
+				"role":        user.Role,
+				"store_id":    user.StoreID,
+				"permissions": models.RolePermissions(user.Role),
 			},
 			"token":      token,
 			"expires_in": 7200,
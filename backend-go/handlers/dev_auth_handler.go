@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DevLoginRequest 开发登录请求
+type DevLoginRequest struct {
+	Persona string `json:"persona" binding:"required"`
+}
+
+// GetDevPersonas 获取开发认证模式的预置角色（仅 DEV_AUTH_ENABLED 时注册）
+func GetDevPersonas(c *gin.Context) {
+	personas := services.NewDevAuthService().Personas()
+
+	result := make([]gin.H, 0, len(personas))
+	for _, persona := range personas {
+		result = append(result, gin.H{
+			"persona":     persona,
+			"permissions": models.RolePermissions(persona.Role),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// DevLogin 以预置角色登录，返回正式签名的令牌（仅 DEV_AUTH_ENABLED 时注册）
+func DevLogin(c *gin.Context) {
+	var req DevLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	user, token, err := services.NewDevAuthService().Login(database.GetDB(), req.Persona)
+	if err != nil {
+		status := http.StatusForbidden
+		if errors.Is(err, services.ErrUnknownPersona) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "开发登录成功",
+		"data": gin.H{
+			"user":        user.ToResponse(),
+			"role":        user.Role,
+			"store_id":    user.StoreID,
+			"permissions": models.RolePermissions(user.Role),
+			"token":       token,
+			"expires_in":  7200,
+		},
+	})
+}
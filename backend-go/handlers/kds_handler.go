@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// kdsErrorStatus KDS 操作错误对应的HTTP状态码
+func kdsErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrOrderItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrOrderNotInQueue), errors.Is(err, services.ErrIllegalStatusTransition),
+		errors.Is(err, services.ErrOrderUnpaid), errors.Is(err, services.ErrInvalidOrderItemStatus),
+		errors.Is(err, services.ErrIllegalItemStatusTransition):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetKDSQueue 获取出品队列（先进先出，含定制选项、备注与等待时长）
+func GetKDSQueue(c *gin.Context) {
+	storeID, ok := adminStoreFilter(c)
+	if !ok {
+		return
+	}
+
+	orders, err := services.NewKDSService().Queue(database.GetDB(), storeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"获取出品队列失败: " + err.Error()},
+		})
+		return
+	}
+
+	now := time.Now()
+	sla := time.Duration(config.AppConfig.KDSOrderSLAMinutes) * time.Minute
+
+	queue := make([]gin.H, 0, len(orders))
+	overdueCount := 0
+	for _, order := range orders {
+		// 进入队列时间以支付完成为准，预约订单以放行时间为准
+		queuedAt := order.CreatedAt
+		if order.PaidAt != nil {
+			queuedAt = *order.PaidAt
+		}
+		if order.ReleasedAt != nil && order.ReleasedAt.After(queuedAt) {
+			queuedAt = *order.ReleasedAt
+		}
+		elapsed := now.Sub(queuedAt)
+
+		// 待取餐订单已出品完成，不计入超时
+		overdue := order.Status != models.OrderStatusReady && sla > 0 && elapsed > sla
+		if overdue {
+			overdueCount++
+		}
+
+		items := make([]gin.H, 0, len(order.OrderItems))
+		doneCount := 0
+		for _, item := range order.OrderItems {
+			options := make([]string, 0, len(item.Options))
+			for _, opt := range item.Options {
+				options = append(options, opt.GroupName+": "+opt.OptionName)
+			}
+			if item.Status == models.OrderItemStatusDone {
+				doneCount++
+			}
+			items = append(items, gin.H{
+				"id":        item.ID,
+				"menu_name": item.MenuItem.Name,
+				"quantity":  item.Quantity,
+				"options":   options,
+				"status":    item.Status,
+				"done_at":   item.DoneAt,
+			})
+		}
+
+		queue = append(queue, gin.H{
+			"id":              order.ID,
+			"order_number":    order.OrderNumber,
+			"store_id":        order.StoreID,
+			"pickup_code":     order.PickupCode,
+			"status":          order.Status,
+			"notes":           order.Notes,
+			"scheduled_at":    order.ScheduledPickupAt, // 预约取餐时间，为空表示尽快制作
+			"items":           items,
+			"item_count":      len(items),
+			"done_item_count": doneCount,
+			"queued_at":       queuedAt,
+			"elapsed_seconds": int(elapsed.Seconds()),
+			"overdue":         overdue,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"orders":        queue,
+			"total":         len(queue),
+			"overdue_count": overdueCount,
+			"sla_minutes":   config.AppConfig.KDSOrderSLAMinutes,
+			"server_time":   now,
+		},
+	})
+}
+
+// BumpKDSOrder 推进订单到下一出品状态（待处理 → 制作中 → 待取餐 → 已完成）
+func BumpKDSOrder(c *gin.Context) {
+	if !ensureAdminOrderAccess(c, c.Param("id")) {
+		return
+	}
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的订单ID"},
+		})
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, err := services.NewKDSService().BumpOrder(tx, uint(orderID), statusActorFromContext(c))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(kdsErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新订单状态失败: " + err.Error()},
+		})
+		return
+	}
+
+	services.PublishOrderEvent(services.OrderEventStatusChanged, order)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "订单状态更新成功",
+		"order": gin.H{
+			"id":           order.ID,
+			"order_number": order.OrderNumber,
+			"pickup_code":  order.PickupCode,
+			"status":       order.Status,
+		},
+	})
+}
+
+// BumpKDSOrderItem 标记单品出品完成
+func BumpKDSOrderItem(c *gin.Context) {
+	if !ensureAdminOrderAccess(c, c.Param("id")) {
+		return
+	}
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的订单ID"},
+		})
+		return
+	}
+	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的订单项ID"},
+		})
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	item, order, err := services.NewKDSService().BumpItem(tx, uint(orderID), uint(itemID), statusActorFromContext(c))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(kdsErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"更新订单项失败: " + err.Error()},
+		})
+		return
+	}
+
+	services.PublishOrderEvent(services.OrderEventItemUpdated, order)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "出品完成",
+		"data": gin.H{
+			"item":         item,
+			"order_status": order.Status,
+		},
+	})
+}
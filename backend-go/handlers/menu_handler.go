@@ -3,12 +3,21 @@ package handlers
 import (
 	"coffee-ordering-backend/database"
 	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// menuStoreID 菜单所属门店，由 store_id 参数指定，默认为默认门店
+func menuStoreID(c *gin.Context) uint {
+	if id, err := strconv.ParseUint(c.Query("store_id"), 10, 32); err == nil && id > 0 {
+		return uint(id)
+	}
+	return services.DefaultStoreID()
+}
+
 // GetMenuItems 获取菜单列表
 func GetMenuItems(c *gin.Context) {
 	// 获取查询参数
@@ -22,11 +31,19 @@ func GetMenuItems(c *gin.Context) {
 	var items []models.MenuItem
 	var total int64
 
+	storeID := menuStoreID(c)
+	storeService := services.NewStoreService()
+
 	// 构建查询
 	query := db.Model(&models.MenuItem{})
 
 	if availableOnly {
 		query = query.Where("is_available = ?", true)
+
+		// 排除门店下架的菜品
+		if ids, err := storeService.UnavailableMenuIDs(db, storeID); err == nil && len(ids) > 0 {
+			query = query.Where("id NOT IN ?", ids)
+		}
 	}
 
 	if category != "" && category != "all" {
@@ -42,7 +59,10 @@ func GetMenuItems(c *gin.Context) {
 
 	// 分页
 	offset := (page - 1) * perPage
-	query.Offset(offset).Limit(perPage).Order("created_at DESC").Find(&items)
+	preloadOptionGroups(query).Offset(offset).Limit(perPage).Order("created_at DESC").Find(&items)
+
+	// 应用门店价格与上下架设置
+	storeService.ApplyMenuOverrides(db, storeID, items)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -52,6 +72,7 @@ func GetMenuItems(c *gin.Context) {
 			"page":     page,
 			"per_page": perPage,
 			"pages":    (total + int64(perPage) - 1) / int64(perPage),
+			"store_id": storeID,
 		},
 	})
 }
@@ -90,7 +111,7 @@ func GetMenuItem(c *gin.Context) {
 	db := database.GetDB()
 	var item models.MenuItem
 
-	if err := db.First(&item, id).Error; err != nil {
+	if err := preloadOptionGroups(db).First(&item, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"message": "商品不存在",
@@ -98,6 +119,10 @@ func GetMenuItem(c *gin.Context) {
 		return
 	}
 
+	items := []models.MenuItem{item}
+	services.NewStoreService().ApplyMenuOverrides(db, menuStoreID(c), items)
+	item = items[0]
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    item,
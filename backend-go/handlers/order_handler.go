@@ -4,10 +4,14 @@ import (
 	"coffee-ordering-backend/database"
 	"coffee-ordering-backend/models"
 	"coffee-ordering-backend/services"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CreateOrderRequest 创建订单请求
@@ -17,16 +21,53 @@ type CreateOrderRequest struct {
 	Notes        string             `json:"notes"`
 	UsePoints    bool               `json:"use_points"`     // 是否使用积分
 	PointsToUse  int                `json:"points_to_use"`  // 使用的积分数量
+	StoreID      uint               `json:"store_id"`       // 下单门店，为空时使用默认门店
+	ScheduledPickupAt *time.Time    `json:"scheduled_pickup_at"` // 预约取餐时间（RFC3339），为空表示尽快制作
+	CouponCode   string             `json:"coupon_code"`    // 优惠券码
 }
 
-// OrderItemRequest 订单项请求
+// OrderItemRequest 订单项请求（单价由服务端按菜单计算）
 type OrderItemRequest struct {
-	MenuID    uint    `json:"menu_id" binding:"required"`
-	Quantity  int     `json:"quantity" binding:"required,min=1"`
-	UnitPrice float64 `json:"unit_price"`
+	MenuID    uint   `json:"menu_id" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,min=1"`
+	OptionIDs []uint `json:"option_ids"` // 所选定制选项ID
 }
 
-// CreateOrder 创建订单（支持积分抵扣）
+// toPricingLines 转换为计价请求行
+func toPricingLines(items []OrderItemRequest) []services.PricingLine {
+	lines := make([]services.PricingLine, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, services.PricingLine{
+			MenuID:    item.MenuID,
+			Quantity:  item.Quantity,
+			OptionIDs: item.OptionIDs,
+		})
+	}
+	return lines
+}
+
+// pricingErrorStatus 计价错误对应的HTTP状态码
+func pricingErrorStatus(err error) int {
+	if errors.Is(err, services.ErrMenuItemNotFound) ||
+		errors.Is(err, services.ErrMenuItemUnavailable) ||
+		errors.Is(err, services.ErrInvalidQuantity) ||
+		errors.Is(err, services.ErrInvalidOption) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// promotionErrorStatus 优惠计算错误对应的HTTP状态码
+func promotionErrorStatus(err error) int {
+	if errors.Is(err, services.ErrCouponNotFound) ||
+		errors.Is(err, services.ErrCouponNotApplicable) ||
+		errors.Is(err, services.ErrPromotionUsageExceeded) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// CreateOrder 创建订单（支持优惠券与积分抵扣）
 func CreateOrder(c *gin.Context) {
 	var req CreateOrderRequest
 
@@ -48,6 +89,16 @@ func CreateOrder(c *gin.Context) {
 
 	db := database.GetDB()
 
+	// 下单门店
+	store, err := services.NewStoreService().ResolveStore(db, req.StoreID)
+	if err != nil {
+		c.JSON(storeErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
 	// 获取用户ID（如果已登录）
 	userID, isLoggedIn := c.Get("user_id")
 	var userIDPtr *uint
@@ -57,11 +108,34 @@ func CreateOrder(c *gin.Context) {
 	if isLoggedIn {
 		uid := userID.(uint)
 		userIDPtr = &uid
+	}
 
-		// 获取用户积分信息
-		userPoints = &models.UserPoints{}
-		if err := db.Where("user_id = ?", uid).First(userPoints).Error; err == nil {
-			memberLevel = userPoints.MemberLevel
+	// 幂等键：重试请求直接返回首次下单的响应
+	idempotencyService := services.NewIdempotencyService()
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	idempotencyScope := idempotencyService.Scope(userIDPtr)
+	requestHash := ""
+	if idempotencyKey != "" {
+		if err := idempotencyService.ValidateKey(idempotencyKey); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{err.Error()},
+			})
+			return
+		}
+
+		var err error
+		requestHash, err = idempotencyService.HashRequest(req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"errors":  []string{"请求摘要计算失败"},
+			})
+			return
+		}
+
+		if replayIdempotentOrder(c, idempotencyService, idempotencyScope, idempotencyKey, requestHash) {
+			return
 		}
 	}
 
@@ -73,29 +147,102 @@ func CreateOrder(c *gin.Context) {
 		}
 	}()
 
+	// 营业时间、暂停接单、出品队列与预约时段容量校验（锁定门店行，避免并发下单超出容量）
+	if storeStatus, err := services.NewStoreService().CheckAcceptingOrders(tx, store.ID, time.Now(), req.ScheduledPickupAt); err != nil {
+		tx.Rollback()
+		c.JSON(storeErrorStatus(err), gin.H{
+			"success":      false,
+			"errors":       []string{err.Error()},
+			"store_status": storeStatus,
+		})
+		return
+	}
+
+	// 锁定用户积分账户后再读取余额，避免并发下单重复使用同一笔积分
+	if userIDPtr != nil {
+		if locked, err := services.NewPointsService().LockUserPoints(tx, *userIDPtr); err == nil {
+			userPoints = locked
+			memberLevel = userPoints.MemberLevel
+		}
+	}
+
+	// 服务端计价，不信任客户端提交的金额
+	pricingService := services.NewPricingService()
+	priced, err := pricingService.PriceLines(tx, store.ID, toPricingLines(req.Items))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(pricingErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := pricingService.VerifyTotal(priced, req.TotalPrice); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":              false,
+			"errors":               []string{err.Error()},
+			"expected_total_price": priced.Total,
+		})
+		return
+	}
+
 	// 生成订单号和取餐码
-	orderNumber := models.GenerateOrderNumber()
-	pickupCode := models.GeneratePickupCode()
+	codes, err := services.NewSequenceService().NextOrderCodes(tx, store, time.Now())
+	if err != nil {
+		tx.Rollback()
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrPickupCodeExhausted) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"errors":  []string{"生成订单号失败: " + err.Error()},
+		})
+		return
+	}
+	orderNumber := codes.OrderNumber
+	pickupCode := codes.PickupCode
+
+	// 计算优惠（优惠券与自动促销），锁定所用优惠避免超出使用次数
+	promotionService := services.NewPromotionService()
+	discounts, err := promotionService.Evaluate(tx, priced, services.PromotionContext{
+		UserID:      userIDPtr,
+		MemberLevel: memberLevel,
+		CouponCode:  req.CouponCode,
+		Now:         time.Now(),
+	}, true)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(promotionErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
 
-	// 计算积分抵扣
-	originalTotal := req.TotalPrice
+	// 计算积分抵扣（按优惠后金额）
+	originalTotal := priced.Total
+	discountedTotal := services.RoundMoney(originalTotal - discounts.Total)
 	pointsDeduction := 0.0
 	pointsUsed := 0
-	finalPayment := originalTotal
+	finalPayment := discountedTotal
 
 	if req.UsePoints && req.PointsToUse > 0 && userPoints != nil {
 		pointsService := services.NewPointsService()
-		discount, err := pointsService.CalculatePointsDiscount(req.PointsToUse, memberLevel, originalTotal)
+		discount, err := pointsService.CalculatePointsDiscount(req.PointsToUse, memberLevel, discountedTotal)
 		if err == nil && userPoints.TotalPoints >= req.PointsToUse {
-			pointsDeduction = discount
+			pointsDeduction = services.RoundMoney(discount)
 			pointsUsed = req.PointsToUse
-			finalPayment = originalTotal - pointsDeduction
+			finalPayment = services.RoundMoney(discountedTotal - pointsDeduction)
 		}
 	}
 
 	// 创建订单
 	order := models.Order{
 		UserID:                userIDPtr,
+		StoreID:               store.ID,
 		OrderNumber:           orderNumber,
 		PickupCode:            pickupCode,
 		CustomerPointsUsed:    pointsUsed,
@@ -103,6 +250,10 @@ func CreateOrder(c *gin.Context) {
 		MemberLevelAtTime:     &memberLevel,
 		Notes:                 req.Notes,
 		Status:                models.OrderStatusPending,
+		DiscountAmount:        discounts.Total,
+		PaymentAmount:         finalPayment,
+		PaymentStatus:         models.OrderPaymentUnpaid,
+		ScheduledPickupAt:     req.ScheduledPickupAt,
 	}
 
 	if err := tx.Create(&order).Error; err != nil {
@@ -114,28 +265,31 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// 创建订单项
-	for _, item := range req.Items {
-		var menuItem models.MenuItem
-		if err := tx.First(&menuItem, item.MenuID).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"errors":  []string{"菜品不存在"},
-			})
-			return
-		}
-
-		unitPrice := item.UnitPrice
-		if unitPrice == 0 {
-			unitPrice = menuItem.Price
-		}
+	if err := promotionService.Record(tx, &order, discounts); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"记录订单优惠失败: " + err.Error()},
+		})
+		return
+	}
 
+	// 创建订单项（单价为下单时菜单价格快照）
+	for _, line := range priced.Lines {
 		orderItem := models.OrderItem{
 			OrderID:   order.ID,
-			MenuID:    item.MenuID,
-			Quantity:  item.Quantity,
-			UnitPrice: unitPrice,
+			MenuID:    line.MenuItem.ID,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+		}
+
+		for _, opt := range line.Options {
+			orderItem.Options = append(orderItem.Options, models.OrderItemOption{
+				OptionID:   opt.Option.ID,
+				GroupName:  opt.GroupName,
+				OptionName: opt.Option.Name,
+				PriceDelta: opt.Option.PriceDelta,
+			})
 		}
 
 		if err := tx.Create(&orderItem).Error; err != nil {
@@ -148,6 +302,24 @@ func CreateOrder(c *gin.Context) {
 		}
 	}
 
+	// 按配方扣减原料库存
+	stockLines := make([]services.StockLine, 0, len(priced.Lines))
+	for _, line := range priced.Lines {
+		stockLines = append(stockLines, services.StockLine{MenuItemID: line.MenuItem.ID, Quantity: line.Quantity})
+	}
+	if err := services.NewInventoryService().ConsumeForOrder(tx, order.ID, stockLines); err != nil {
+		tx.Rollback()
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInsufficientStock) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
 	// 处理积分
 	estimatedPointsEarned := 0
 	if userIDPtr != nil {
@@ -173,31 +345,102 @@ func CreateOrder(c *gin.Context) {
 		tx.Save(&order)
 	}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
+	// 无需支付的订单（积分全额抵扣）直接标记为已支付
+	if finalPayment <= 0 {
+		if err := services.NewPaymentService().MarkPaidWithoutPayment(tx, &order); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"errors":  []string{err.Error()},
+			})
+			return
+		}
+	}
+
+	// 记录初始状态
+	orderService := services.NewOrderService()
+	if err := orderService.RecordCreated(tx, &order, statusActorFromContext(c)); err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"errors":  []string{"提交订单失败: " + err.Error()},
+			"errors":  []string{err.Error()},
 		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"success": true,
 		"message": "订单创建成功",
 		"order": gin.H{
-			"id":                       order.ID,
-			"order_number":             order.OrderNumber,
-			"pickup_code":              order.PickupCode,
-			"original_total_price":     originalTotal,
-			"points_deduction_amount":  pointsDeduction,
-			"final_payment_amount":     finalPayment,
-			"points_used":              pointsUsed,
-			"estimated_points_earned":  estimatedPointsEarned,
-			"status":                   order.Status,
-			"created_at":               order.CreatedAt,
+			"id":                      order.ID,
+			"order_number":            order.OrderNumber,
+			"store_id":                order.StoreID,
+			"pickup_code":             order.PickupCode,
+			"original_total_price":    originalTotal,
+			"discount_amount":         discounts.Total,
+			"discounts":               discounts.Discounts,
+			"points_deduction_amount": pointsDeduction,
+			"final_payment_amount":    finalPayment,
+			"points_used":             pointsUsed,
+			"estimated_points_earned": estimatedPointsEarned,
+			"status":                  order.Status,
+			"payment_status":          order.PaymentStatus,
+			"scheduled_pickup_at":     order.ScheduledPickupAt,
+			"created_at":              order.CreatedAt,
 		},
-	})
+	}
+
+	// 保存幂等记录，与订单同一事务提交
+	if idempotencyKey != "" {
+		if err := idempotencyService.Save(tx, idempotencyScope, idempotencyKey, requestHash, &order.ID, http.StatusCreated, response); err != nil {
+			tx.Rollback()
+			// 并发的相同请求已先提交，回放其结果
+			if replayIdempotentOrder(c, idempotencyService, idempotencyScope, idempotencyKey, requestHash) {
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"errors":  []string{"保存幂等记录失败: " + err.Error()},
+			})
+			return
+		}
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"提交订单失败: " + err.Error()},
+		})
+		return
+	}
+
+	services.PublishOrderEvent(services.OrderEventCreated, &order)
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// replayIdempotentOrder 回放幂等键对应的下单响应，已处理请求时返回 true
+func replayIdempotentOrder(c *gin.Context, s *services.IdempotencyService, scope, key, requestHash string) bool {
+	record, err := s.Lookup(database.GetDB(), scope, key, requestHash)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrIdempotencyKeyConflict) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return true
+	}
+	if record == nil {
+		return false
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.StatusCode, "application/json; charset=utf-8", []byte(record.ResponseBody))
+	return true
 }
 
 // GetOrder 获取订单详情
@@ -207,7 +450,7 @@ func GetOrder(c *gin.Context) {
 	db := database.GetDB()
 	var order models.Order
 
-	if err := db.Preload("OrderItems.MenuItem").First(&order, id).Error; err != nil {
+	if err := db.Preload("OrderItems.MenuItem").Preload("OrderItems.Options").Preload("Discounts").First(&order, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"errors":  []string{"订单不存在"},
@@ -216,36 +459,33 @@ func GetOrder(c *gin.Context) {
 	}
 
 	// 格式化订单项并计算总价
-	orderItems := make([]gin.H, 0)
-	totalPrice := 0.0
-	for _, item := range order.OrderItems {
-		subtotal := item.GetSubtotal()
-		totalPrice += subtotal
-		orderItems = append(orderItems, gin.H{
-			"id":         item.ID,
-			"menu_id":    item.MenuID,
-			"menu_name":  item.MenuItem.Name,
-			"quantity":   item.Quantity,
-			"unit_price": item.UnitPrice,
-			"subtotal":   subtotal,
-		})
-	}
+	orderItems, totalPrice := formatOrderItems(order.OrderItems)
+
+	// 计算最终支付金额（扣除优惠与积分抵扣）
+	finalPrice := totalPrice - order.DiscountAmount - order.PointsDeductionAmount
 
-	// 计算最终支付金额（扣除积分抵扣）
-	finalPrice := totalPrice - order.PointsDeductionAmount
+	// 状态变更历史
+	history, _ := services.NewOrderService().GetStatusHistory(db, order.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"order": gin.H{
 			"id":                      order.ID,
 			"order_number":            order.OrderNumber,
+			"store_id":                order.StoreID,
 			"pickup_code":             order.PickupCode,
 			"original_total_price":    totalPrice,
+			"discount_amount":         order.DiscountAmount,
+			"discounts":               formatOrderDiscounts(order.Discounts),
 			"points_deduction_amount": order.PointsDeductionAmount,
 			"final_payment_amount":    finalPrice,
 			"status":                  order.Status,
+			"payment_status":          order.PaymentStatus,
+			"scheduled_pickup_at":     order.ScheduledPickupAt,
 			"notes":                   order.Notes,
 			"items":                   orderItems,
+			"item_progress":           itemProgress(order.OrderItems),
+			"status_history":          formatStatusHistory(history),
 			"created_at":              order.CreatedAt,
 		},
 	})
@@ -258,7 +498,16 @@ func GetOrderByPickupCode(c *gin.Context) {
 	db := database.GetDB()
 	var order models.Order
 
-	if err := db.Preload("OrderItems.MenuItem").Where("pickup_code = ?", pickupCode).First(&order).Error; err != nil {
+	store, err := pickupStoreFromQuery(c)
+	if err != nil {
+		c.JSON(storeErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := findOrderByPickupCode(db.Preload("OrderItems.MenuItem").Preload("OrderItems.Options").Preload("Discounts"), store, pickupCode, &order); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"errors":  []string{"取餐码不存在"},
@@ -267,46 +516,185 @@ func GetOrderByPickupCode(c *gin.Context) {
 	}
 
 	// 格式化订单项并计算总价
-	orderItems := make([]gin.H, 0)
-	totalPrice := 0.0
-	for _, item := range order.OrderItems {
-		subtotal := item.GetSubtotal()
-		totalPrice += subtotal
-		orderItems = append(orderItems, gin.H{
-			"id":         item.ID,
-			"menu_id":    item.MenuID,
-			"menu_name":  item.MenuItem.Name,
-			"quantity":   item.Quantity,
-			"unit_price": item.UnitPrice,
-			"subtotal":   subtotal,
-		})
-	}
+	orderItems, totalPrice := formatOrderItems(order.OrderItems)
+
+	// 计算最终支付金额（扣除优惠与积分抵扣）
+	finalPrice := totalPrice - order.DiscountAmount - order.PointsDeductionAmount
 
-	// 计算最终支付金额（扣除积分抵扣）
-	finalPrice := totalPrice - order.PointsDeductionAmount
+	// 状态变更历史
+	history, _ := services.NewOrderService().GetStatusHistory(db, order.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"order": gin.H{
 			"id":                      order.ID,
 			"order_number":            order.OrderNumber,
+			"store_id":                order.StoreID,
 			"pickup_code":             order.PickupCode,
 			"original_total_price":    totalPrice,
+			"discount_amount":         order.DiscountAmount,
+			"discounts":               formatOrderDiscounts(order.Discounts),
 			"points_deduction_amount": order.PointsDeductionAmount,
 			"final_payment_amount":    finalPrice,
 			"status":                  order.Status,
+			"payment_status":          order.PaymentStatus,
+			"scheduled_pickup_at":     order.ScheduledPickupAt,
 			"notes":                   order.Notes,
 			"items":                   orderItems,
+			"item_progress":           itemProgress(order.OrderItems),
+			"status_history":          formatStatusHistory(history),
 			"created_at":              order.CreatedAt,
 		},
 	})
 }
 
+// pickupStoreFromQuery 取餐码所属门店，由 store_id 参数指定，默认为默认门店
+func pickupStoreFromQuery(c *gin.Context) (*models.Store, error) {
+	storeID := services.DefaultStoreID()
+	if raw := c.Query("store_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return nil, services.ErrStoreNotFound
+		}
+		storeID = uint(id)
+	}
+
+	var store models.Store
+	if err := database.GetDB().First(&store, storeID).Error; err != nil {
+		return nil, services.ErrStoreNotFound
+	}
+	return &store, nil
+}
+
+// findOrderByPickupCode 按门店取餐码查找订单
+// 取餐码按门店按天循环使用：优先匹配本店当天占用该码的订单（含预约当天取餐的订单），否则取本店最近的同码订单
+func findOrderByPickupCode(db *gorm.DB, store *models.Store, pickupCode string, order *models.Order) error {
+	db = db.Session(&gorm.Session{})
+	now := time.Now().In(store.Location())
+	err := db.Scopes(services.PickupCodeDayScope(store.ID, pickupCode, now)).
+		Order("created_at DESC").First(order).Error
+	if err != nil {
+		err = db.Where("store_id = ? AND pickup_code = ?", store.ID, pickupCode).
+			Order("created_at DESC").First(order).Error
+	}
+	return err
+}
+
+// storeErrorStatus 门店错误对应的HTTP状态码
+func storeErrorStatus(err error) int {
+	if errors.Is(err, services.ErrStoreNotFound) || errors.Is(err, services.ErrStoreInactive) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, services.ErrStoreNotAccepting) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, services.ErrInvalidPickupTime) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, services.ErrPickupSlotFull) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// statusActorFromContext 从上下文获取状态变更操作人
+func statusActorFromContext(c *gin.Context) services.StatusActor {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return services.StatusActor{Role: "guest"}
+	}
+
+	uid := userID.(uint)
+	role := c.GetString("role")
+	if role == "" {
+		role = "user"
+	}
+	return services.StatusActor{UserID: &uid, Role: role}
+}
+
+// formatOrderItems 格式化订单项（含定制选项）并计算原价合计
+func formatOrderItems(items []models.OrderItem) ([]gin.H, float64) {
+	result := make([]gin.H, 0, len(items))
+	totalPrice := 0.0
+	for _, item := range items {
+		subtotal := item.GetSubtotal()
+		totalPrice += subtotal
+
+		options := make([]gin.H, 0, len(item.Options))
+		for _, opt := range item.Options {
+			options = append(options, gin.H{
+				"option_id":   opt.OptionID,
+				"group_name":  opt.GroupName,
+				"option_name": opt.OptionName,
+				"price_delta": opt.PriceDelta,
+			})
+		}
+
+		result = append(result, gin.H{
+			"id":         item.ID,
+			"menu_id":    item.MenuID,
+			"menu_name":  item.MenuItem.Name,
+			"quantity":   item.Quantity,
+			"unit_price": item.UnitPrice,
+			"subtotal":   subtotal,
+			"options":    options,
+			"status":     item.Status,
+			"done_at":    item.DoneAt,
+		})
+	}
+	return result, totalPrice
+}
+
+// itemProgress 统计单品出品进度（不含作废单品）
+func itemProgress(items []models.OrderItem) gin.H {
+	total, done := 0, 0
+	for _, item := range items {
+		if item.Status == models.OrderItemStatusVoided {
+			continue
+		}
+		total++
+		if item.Status == models.OrderItemStatusDone {
+			done++
+		}
+	}
+	return gin.H{"done": done, "total": total}
+}
+
+// formatOrderDiscounts 格式化订单优惠明细
+func formatOrderDiscounts(discounts []models.OrderDiscount) []gin.H {
+	result := make([]gin.H, 0, len(discounts))
+	for _, d := range discounts {
+		result = append(result, gin.H{
+			"promotion_id": d.PromotionID,
+			"code":         d.Code,
+			"name":         d.Name,
+			"type":         d.Type,
+			"amount":       d.Amount,
+		})
+	}
+	return result
+}
+
+// formatStatusHistory 格式化顾客可见的状态变更历史
+func formatStatusHistory(history []models.OrderStatusHistory) []gin.H {
+	result := make([]gin.H, 0, len(history))
+	for _, h := range history {
+		result = append(result, gin.H{
+			"from_status": h.FromStatus,
+			"to_status":   h.ToStatus,
+			"reason":      h.Reason,
+			"created_at":  h.CreatedAt,
+		})
+	}
+	return result
+}
 
 // CalculatePointsRequest 积分计算请求
 type CalculatePointsRequest struct {
 	Items       []OrderItemRequest `json:"items" binding:"required"`
 	PointsToUse int                `json:"points_to_use"`
+	StoreID     uint               `json:"store_id"`
+	CouponCode  string             `json:"coupon_code"`
 }
 
 // CalculatePointsForOrder 积分使用预估
@@ -343,29 +731,42 @@ func CalculatePointsForOrder(c *gin.Context) {
 		return
 	}
 
-	// 计算订单总价
-	var originalTotal float64
-	for _, item := range req.Items {
-		var menuItem models.MenuItem
-		if err := db.First(&menuItem, item.MenuID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "菜品不存在",
-			})
-			return
-		}
-
-		unitPrice := item.UnitPrice
-		if unitPrice == 0 {
-			unitPrice = menuItem.Price
-		}
-		originalTotal += float64(item.Quantity) * unitPrice
+	// 服务端计算订单总价
+	storeID := req.StoreID
+	if storeID == 0 {
+		storeID = services.DefaultStoreID()
 	}
+	priced, err := services.NewPricingService().PriceLines(db, storeID, toPricingLines(req.Items))
+	if err != nil {
+		c.JSON(pricingErrorStatus(err), gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	originalTotal := priced.Total
+
+	// 计算优惠
+	uid := userID.(uint)
+	discounts, err := services.NewPromotionService().Evaluate(db, priced, services.PromotionContext{
+		UserID:      &uid,
+		MemberLevel: userPoints.MemberLevel,
+		CouponCode:  req.CouponCode,
+		Now:         time.Now(),
+	}, false)
+	if err != nil {
+		c.JSON(promotionErrorStatus(err), gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	discountedTotal := services.RoundMoney(originalTotal - discounts.Total)
 
 	pointsService := services.NewPointsService()
 
 	// 获取最大可用积分
-	maxUsablePoints, _ := pointsService.GetMaxUsablePoints(originalTotal, userPoints.MemberLevel, userPoints.TotalPoints)
+	maxUsablePoints, _ := pointsService.GetMaxUsablePoints(discountedTotal, userPoints.MemberLevel, userPoints.TotalPoints)
 
 	// 计算积分抵扣
 	pointsToUse := req.PointsToUse
@@ -375,10 +776,10 @@ func CalculatePointsForOrder(c *gin.Context) {
 
 	pointsValue := 0.0
 	if pointsToUse > 0 {
-		pointsValue, _ = pointsService.CalculatePointsDiscount(pointsToUse, userPoints.MemberLevel, originalTotal)
+		pointsValue, _ = pointsService.CalculatePointsDiscount(pointsToUse, userPoints.MemberLevel, discountedTotal)
 	}
 
-	finalTotal := originalTotal - pointsValue
+	finalTotal := services.RoundMoney(discountedTotal - pointsValue)
 
 	// 计算可获得积分
 	estimatedPointsEarned, _ := pointsService.CalculateEarnedPoints(finalTotal, userPoints.MemberLevel)
@@ -387,6 +788,8 @@ func CalculatePointsForOrder(c *gin.Context) {
 		"success": true,
 		"data": gin.H{
 			"original_total":          originalTotal,
+			"discount_amount":         discounts.Total,
+			"discounts":               discounts.Discounts,
 			"max_usable_points":       maxUsablePoints,
 			"points_to_use":           pointsToUse,
 			"points_value":            pointsValue,
@@ -397,3 +800,75 @@ func CalculatePointsForOrder(c *gin.Context) {
 		},
 	})
 }
+
+// DiscountPreviewRequest 优惠预估请求
+type DiscountPreviewRequest struct {
+	Items      []OrderItemRequest `json:"items" binding:"required"`
+	StoreID    uint               `json:"store_id"`
+	CouponCode string             `json:"coupon_code"`
+}
+
+// PreviewOrderDiscounts 优惠预估（支持游客），下单前展示可享受的优惠与券码校验结果
+func PreviewOrderDiscounts(c *gin.Context) {
+	var req DiscountPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	db := database.GetDB()
+	store, err := services.NewStoreService().ResolveStore(db, req.StoreID)
+	if err != nil {
+		c.JSON(storeErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	priced, err := services.NewPricingService().PriceLines(db, store.ID, toPricingLines(req.Items))
+	if err != nil {
+		c.JSON(pricingErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	ctx := services.PromotionContext{
+		MemberLevel: models.MemberLevelBronze,
+		CouponCode:  req.CouponCode,
+		Now:         time.Now(),
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		uid := userID.(uint)
+		ctx.UserID = &uid
+
+		var userPoints models.UserPoints
+		if err := db.Where("user_id = ?", uid).First(&userPoints).Error; err == nil {
+			ctx.MemberLevel = userPoints.MemberLevel
+		}
+	}
+
+	discounts, err := services.NewPromotionService().Evaluate(db, priced, ctx, false)
+	if err != nil {
+		c.JSON(promotionErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"original_total":  priced.Total,
+			"discount_amount": discounts.Total,
+			"discounts":       discounts.Discounts,
+			"final_total":     services.RoundMoney(priced.Total - discounts.Total),
+		},
+	})
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paymentErrorStatus 支付错误对应的HTTP状态码
+func paymentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrPaymentNotFound),
+		errors.Is(err, services.ErrPaymentProviderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrOrderAlreadyPaid), errors.Is(err, services.ErrOrderNotPayable):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidWebhookSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrInvalidWebhookPayload):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// authorizeOrderPayment 校验当前用户可以支付该订单（会员订单仅限本人支付）
+func authorizeOrderPayment(c *gin.Context) (uint, bool) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的订单ID"},
+		})
+		return 0, false
+	}
+
+	var order models.Order
+	if err := database.GetDB().Select("id", "user_id").First(&order, orderID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"订单不存在"},
+		})
+		return 0, false
+	}
+
+	if order.UserID != nil {
+		userID, exists := c.Get("user_id")
+		if !exists || userID.(uint) != *order.UserID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"errors":  []string{"无权支付该订单"},
+			})
+			return 0, false
+		}
+	}
+
+	return order.ID, true
+}
+
+// CreateOrderPayment 为订单发起支付
+func CreateOrderPayment(c *gin.Context) {
+	orderID, ok := authorizeOrderPayment(c)
+	if !ok {
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	payment, intent, err := services.NewPaymentService().CreatePayment(tx, orderID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(paymentErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"创建支付失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "支付单创建成功",
+		"payment": gin.H{
+			"id":            payment.ID,
+			"order_id":      payment.OrderID,
+			"provider":      payment.Provider,
+			"amount":        payment.Amount,
+			"status":        payment.Status,
+			"client_secret": intent.ClientSecret,
+		},
+	})
+}
+
+// ConfirmOrderPayment 确认订单支付结果
+func ConfirmOrderPayment(c *gin.Context) {
+	orderID, ok := authorizeOrderPayment(c)
+	if !ok {
+		return
+	}
+
+	paymentID, err := strconv.ParseUint(c.Param("payment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的支付单ID"},
+		})
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	payment, err := services.NewPaymentService().ConfirmPayment(tx, orderID, uint(paymentID))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(paymentErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"确认支付失败: " + err.Error()},
+		})
+		return
+	}
+
+	publishOrderEventByID(services.OrderEventPaymentUpdate, payment.OrderID)
+	// 订单已取消后才完成的支付会登记退款，提交后立即执行
+	services.NewPaymentService().ProcessOrderRefunds(database.GetDB(), payment.OrderID)
+
+	if payment.Status == models.PaymentStatusFailed {
+		c.JSON(http.StatusPaymentRequired, gin.H{
+			"success": false,
+			"errors":  []string{"支付失败: " + payment.FailureReason},
+			"payment": payment,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "支付成功",
+		"payment": payment,
+	})
+}
+
+// PaymentWebhook 接收支付渠道回调
+func PaymentWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"读取回调报文失败"},
+		})
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	payment, err := services.NewPaymentService().HandleWebhook(tx, c.Param("provider"), payload, c.GetHeader("X-Payment-Signature"))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(paymentErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"处理回调失败: " + err.Error()},
+		})
+		return
+	}
+
+	publishOrderEventByID(services.OrderEventPaymentUpdate, payment.OrderID)
+	// 订单已取消后才完成的支付会登记退款，提交后立即执行
+	services.NewPaymentService().ProcessOrderRefunds(database.GetDB(), payment.OrderID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"status":  payment.Status,
+	})
+}
+
+// GetOrderPayments 获取订单支付记录与退款记录（管理员）
+func GetOrderPayments(c *gin.Context) {
+	if !ensureAdminOrderAccess(c, c.Param("id")) {
+		return
+	}
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"无效的订单ID"},
+		})
+		return
+	}
+
+	paymentService := services.NewPaymentService()
+	payments, err := paymentService.GetOrderPayments(database.GetDB(), uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"获取支付记录失败: " + err.Error()},
+		})
+		return
+	}
+	refunds, err := paymentService.GetOrderRefunds(database.GetDB(), uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"获取退款记录失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    payments,
+		"refunds": refunds,
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"coffee-ordering-backend/database"
 	"coffee-ordering-backend/middleware"
 	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -49,6 +51,16 @@ func GetUserPoints(c *gin.Context) {
 		}
 	}
 
+	// 即将过期的积分
+	expiring, err := services.NewPointsService().ExpiringSoon(db, userID, time.Now())
+	if err != nil {
+		expiring = []models.ExpiringPoints{}
+	}
+	expiryNotice := ""
+	if len(expiring) > 0 {
+		expiryNotice = fmt.Sprintf("%d 积分将于 %s 过期", expiring[0].Points, expiring[0].ExpiresOn)
+	}
+
 	// 构建响应
 	response := models.UserPointsResponse{
 		TotalPoints:     userPoints.TotalPoints,
@@ -60,6 +72,8 @@ func GetUserPoints(c *gin.Context) {
 			MaxDiscountRate:   levelConfig.MaxDiscountPercentage,
 			Benefits:          []string{"积分累积", "生日礼品"}, // 简化版本
 		},
+		ExpiringPoints: expiring,
+		ExpiryNotice:   expiryNotice,
 	}
 
 	c.JSON(http.StatusOK, gin.H{
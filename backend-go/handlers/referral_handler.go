@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/middleware"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserReferrals 获取我的推荐码、邀请记录与奖励
+func GetUserReferrals(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "未授权",
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "用户不存在",
+		})
+		return
+	}
+
+	code, err := services.NewReferralService().EnsureCode(db, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var referrals []models.Referral
+	db.Preload("Referee").Where("referrer_id = ?", user.ID).Order("created_at DESC").Find(&referrals)
+
+	invites := make([]gin.H, 0, len(referrals))
+	pending, rewarded, totalPoints := 0, 0, 0
+	for _, r := range referrals {
+		switch r.Status {
+		case models.ReferralStatusPending:
+			pending++
+		case models.ReferralStatusRewarded:
+			rewarded++
+			totalPoints += r.ReferrerPoints
+		}
+
+		invites = append(invites, gin.H{
+			"id":            r.ID,
+			"referee":       maskUsername(r.Referee),
+			"status":        r.Status,
+			"reject_reason": r.RejectReason,
+			"points":        r.ReferrerPoints,
+			"created_at":    r.CreatedAt,
+			"rewarded_at":   r.RewardedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"referral_code": code,
+			"rewards": gin.H{
+				"referrer_points": config.AppConfig.ReferralReferrerPoints,
+				"referee_points":  config.AppConfig.ReferralRefereePoints,
+				"max_rewarded":    config.AppConfig.ReferralMaxPerReferrer,
+			},
+			"summary": gin.H{
+				"invited":       len(referrals),
+				"pending":       pending,
+				"rewarded":      rewarded,
+				"points_earned": totalPoints,
+			},
+			"invites": invites,
+		},
+	})
+}
+
+// maskUsername 隐藏被推荐人用户名中间部分
+func maskUsername(user *models.User) string {
+	if user == nil {
+		return ""
+	}
+	name := []rune(user.Username)
+	if len(name) <= 2 {
+		return string(name[:1]) + "*"
+	}
+	return string(name[:1]) + "***" + string(name[len(name)-1:])
+}
@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StoreOpeningHourRequest 单日营业时间
+type StoreOpeningHourRequest struct {
+	Weekday   int    `json:"weekday" binding:"min=0,max=6"` // 0=周日 ... 6=周六
+	OpenTime  string `json:"open_time"`
+	CloseTime string `json:"close_time"`
+	IsClosed  bool   `json:"is_closed"`
+}
+
+// UpdateStoreHoursRequest 设置每周营业时间请求，未提交的星期使用门店默认营业时间
+type UpdateStoreHoursRequest struct {
+	Hours []StoreOpeningHourRequest `json:"hours"`
+}
+
+// StoreClosureRequest 停业日期请求
+type StoreClosureRequest struct {
+	StartDate string `json:"start_date" binding:"required"` // 格式: 2006-01-02
+	EndDate   string `json:"end_date"`                      // 为空表示仅停业一天
+	Reason    string `json:"reason" binding:"max=255"`
+}
+
+// PauseStoreRequest 暂停接单请求
+type PauseStoreRequest struct {
+	Reason  string `json:"reason" binding:"required,max=255"`
+	Minutes int    `json:"minutes" binding:"min=0"` // 暂停分钟数，0 表示需手动恢复
+}
+
+// loadAdminStore 获取管理员可管理的门店
+func loadAdminStore(c *gin.Context) (*models.Store, bool) {
+	var store models.Store
+	if err := database.GetDB().First(&store, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"门店不存在"},
+		})
+		return nil, false
+	}
+	if !ensureAdminStoreAccess(c, store.ID) {
+		return nil, false
+	}
+	return &store, true
+}
+
+// GetStoreStatus 获取门店营业状态（公开，用于前端营业提示）
+func GetStoreStatus(c *gin.Context) {
+	store, err := pickupStoreFromQuery(c)
+	if err != nil {
+		c.JSON(storeErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	status, err := services.NewStoreService().Status(database.GetDB(), store, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"获取门店状态失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// GetPickupSlots 获取门店预约取餐时段（公开），date 格式 YYYY-MM-DD，默认为门店当天
+func GetPickupSlots(c *gin.Context) {
+	store, err := pickupStoreFromQuery(c)
+	if err != nil {
+		c.JSON(storeErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	now := time.Now()
+	day := now.In(store.Location())
+	if raw := c.Query("date"); raw != "" {
+		day, err = time.ParseInLocation("2006-01-02", raw, store.Location())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"日期格式应为 YYYY-MM-DD"},
+			})
+			return
+		}
+	}
+
+	slots, err := services.NewScheduleService().PickupSlots(database.GetDB(), store, day, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"获取预约时段失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"store_id": store.ID,
+			"date":     day.Format("2006-01-02"),
+			"slots":    slots,
+		},
+	})
+}
+
+// GetStoreHours 获取门店每周营业时间（管理员）
+func GetStoreHours(c *gin.Context) {
+	store, ok := loadAdminStore(c)
+	if !ok {
+		return
+	}
+
+	var hours []models.StoreOpeningHour
+	database.GetDB().Where("store_id = ?", store.ID).Order("weekday ASC").Find(&hours)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"store_id":           store.ID,
+			"default_open_time":  store.OpenTime,
+			"default_close_time": store.CloseTime,
+			"last_order_minutes": store.LastOrderMinutes,
+			"hours":              hours,
+		},
+	})
+}
+
+// UpdateStoreHours 设置门店每周营业时间，整体替换（管理员）
+func UpdateStoreHours(c *gin.Context) {
+	store, ok := loadAdminStore(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateStoreHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	seen := make(map[int]bool, len(req.Hours))
+	hours := make([]models.StoreOpeningHour, 0, len(req.Hours))
+	for _, h := range req.Hours {
+		if seen[h.Weekday] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"同一星期不能重复设置营业时间"},
+			})
+			return
+		}
+		seen[h.Weekday] = true
+
+		if h.IsClosed {
+			h.OpenTime, h.CloseTime = store.OpenTime, store.CloseTime
+		} else if !hhmmPattern.MatchString(h.OpenTime) || !hhmmPattern.MatchString(h.CloseTime) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"营业时间格式应为 HH:MM"},
+			})
+			return
+		} else if h.CloseTime <= h.OpenTime {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"errors":  []string{"打烊时间必须晚于开门时间"},
+			})
+			return
+		}
+
+		hours = append(hours, models.StoreOpeningHour{
+			StoreID:   store.ID,
+			Weekday:   h.Weekday,
+			OpenTime:  h.OpenTime,
+			CloseTime: h.CloseTime,
+			IsClosed:  h.IsClosed,
+		})
+	}
+
+	tx := database.GetDB().Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Where("store_id = ?", store.ID).Delete(&models.StoreOpeningHour{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"保存营业时间失败: " + err.Error()},
+		})
+		return
+	}
+	if len(hours) > 0 {
+		if err := tx.Create(&hours).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"errors":  []string{"保存营业时间失败: " + err.Error()},
+			})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"提交事务失败"},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "营业时间已保存",
+		"data":    hours,
+	})
+}
+
+// GetStoreClosures 获取门店停业日期（管理员），默认只返回未结束的停业安排
+func GetStoreClosures(c *gin.Context) {
+	store, ok := loadAdminStore(c)
+	if !ok {
+		return
+	}
+
+	query := database.GetDB().Where("store_id = ?", store.ID)
+	if c.Query("all") != "true" {
+		today := time.Now().In(store.Location()).Format("2006-01-02")
+		query = query.Where("end_date >= ?", today)
+	}
+
+	var closures []models.StoreClosure
+	query.Order("start_date ASC").Find(&closures)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    closures,
+	})
+}
+
+// CreateStoreClosure 新增门店停业日期（管理员）
+func CreateStoreClosure(c *gin.Context) {
+	store, ok := loadAdminStore(c)
+	if !ok {
+		return
+	}
+
+	var req StoreClosureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+	if req.EndDate == "" {
+		req.EndDate = req.StartDate
+	}
+
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"开始日期格式应为 YYYY-MM-DD"},
+		})
+		return
+	}
+	end, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"结束日期格式应为 YYYY-MM-DD"},
+		})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"结束日期不能早于开始日期"},
+		})
+		return
+	}
+
+	closure := models.StoreClosure{
+		StoreID:   store.ID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Reason:    req.Reason,
+	}
+	if err := database.GetDB().Create(&closure).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"保存停业日期失败: " + err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "停业日期已添加",
+		"data":    closure,
+	})
+}
+
+// DeleteStoreClosure 删除门店停业日期（管理员）
+func DeleteStoreClosure(c *gin.Context) {
+	store, ok := loadAdminStore(c)
+	if !ok {
+		return
+	}
+
+	result := database.GetDB().Where("id = ? AND store_id = ?", c.Param("closure_id"), store.ID).
+		Delete(&models.StoreClosure{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"删除停业日期失败: " + result.Error.Error()},
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"停业日期不存在"},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "停业日期已删除",
+	})
+}
+
+// PauseStoreOrdering 暂停门店接单（管理员）
+func PauseStoreOrdering(c *gin.Context) {
+	store, ok := loadAdminStore(c)
+	if !ok {
+		return
+	}
+
+	var req PauseStoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"errors":  []string{"请求参数错误: " + err.Error()},
+		})
+		return
+	}
+
+	var pausedUntil *time.Time
+	if req.Minutes > 0 {
+		until := time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+		pausedUntil = &until
+	}
+
+	db := database.GetDB()
+	if err := db.Model(store).Updates(map[string]interface{}{
+		"ordering_paused": true,
+		"pause_reason":    req.Reason,
+		"paused_until":    pausedUntil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"暂停接单失败: " + err.Error()},
+		})
+		return
+	}
+
+	db.First(store, store.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "门店已暂停接单",
+		"data":    store,
+	})
+}
+
+// ResumeStoreOrdering 恢复门店接单（管理员）
+func ResumeStoreOrdering(c *gin.Context) {
+	store, ok := loadAdminStore(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+	if err := db.Model(store).Updates(map[string]interface{}{
+		"ordering_paused": false,
+		"pause_reason":    "",
+		"paused_until":    nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"errors":  []string{"恢复接单失败: " + err.Error()},
+		})
+		return
+	}
+
+	db.First(store, store.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "门店已恢复接单",
+		"data":    store,
+	})
+}
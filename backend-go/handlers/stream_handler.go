@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/middleware"
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/services"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeatInterval 心跳间隔，防止代理断开空闲连接
+const streamHeartbeatInterval = 25 * time.Second
+
+// StreamOrder 订阅本人订单的状态事件（SSE），游客订单只能通过取餐码订阅
+func StreamOrder(c *gin.Context) {
+	id := c.Param("id")
+
+	var order models.Order
+	if err := database.GetDB().First(&order, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"订单不存在"},
+		})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists || order.UserID == nil || *order.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"errors":  []string{"无权订阅该订单"},
+		})
+		return
+	}
+
+	streamSingleOrder(c, &order)
+}
+
+// StreamOrderByPickupCode 通过取餐码订阅订单状态事件（SSE）
+func StreamOrderByPickupCode(c *gin.Context) {
+	store, err := pickupStoreFromQuery(c)
+	if err != nil {
+		c.JSON(storeErrorStatus(err), gin.H{
+			"success": false,
+			"errors":  []string{err.Error()},
+		})
+		return
+	}
+
+	var order models.Order
+	if err := findOrderByPickupCode(database.GetDB(), store, c.Param("pickup_code"), &order); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"errors":  []string{"取餐码不存在"},
+		})
+		return
+	}
+
+	streamSingleOrder(c, &order)
+}
+
+// StreamAdminOrders 订阅门店订单事件（管理员，SSE）
+// 绑定门店的管理员只接收本店事件，未绑定的管理员可通过 store_id 参数筛选
+func StreamAdminOrders(c *gin.Context) {
+	storeID, ok := adminStoreFilter(c)
+	if !ok {
+		return
+	}
+
+	var filter services.EventFilter
+	if storeID != nil {
+		scope := *storeID
+		filter = func(event services.OrderEvent) bool {
+			return event.StoreID == scope
+		}
+	}
+
+	sub := services.GetEventBroker().Subscribe(filter)
+	streamEvents(c, sub, nil)
+}
+
+// streamSingleOrder 订阅指定订单，取餐码循环使用时按订单ID过滤避免串单
+func streamSingleOrder(c *gin.Context, order *models.Order) {
+	orderID := order.ID
+	sub := services.GetEventBroker().Subscribe(func(event services.OrderEvent) bool {
+		return event.OrderID == orderID
+	})
+
+	snapshot := services.NewOrderEvent(services.OrderEventSnapshot, order)
+	streamEvents(c, sub, &snapshot)
+}
+
+// streamEvents 以 SSE 格式持续推送事件，客户端断开后取消订阅
+func streamEvents(c *gin.Context, sub services.Subscription, initial *services.OrderEvent) {
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	if initial != nil {
+		c.SSEvent(initial.Type, initial)
+		c.Writer.Flush()
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.Events():
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"time": time.Now()})
+			return true
+		}
+	})
+}
+
+// publishOrderEventByID 重新查询订单并发布事件（事务提交后调用）
+func publishOrderEventByID(eventType string, orderID uint) {
+	var order models.Order
+	if err := database.GetDB().First(&order, orderID).Error; err != nil {
+		return
+	}
+	services.PublishOrderEvent(eventType, &order)
+}
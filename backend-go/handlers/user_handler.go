@@ -222,6 +222,7 @@ func GetUserOrders(c *gin.Context) {
 	var orders []models.Order
 	offset := (page - 1) * perPage
 	query.Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Options").
 		Order("created_at DESC").
 		Offset(offset).
 		Limit(perPage).
@@ -230,38 +231,24 @@ func GetUserOrders(c *gin.Context) {
 	// 格式化响应
 	orderList := make([]gin.H, 0)
 	for _, order := range orders {
-		items := make([]gin.H, 0)
-		totalPrice := 0.0
-		for _, item := range order.OrderItems {
-			menuName := ""
-			if item.MenuItem.ID > 0 {
-				menuName = item.MenuItem.Name
-			}
-			subtotal := item.GetSubtotal()
-			totalPrice += subtotal
-			items = append(items, gin.H{
-				"id":         item.ID,
-				"menu_id":    item.MenuID,
-				"menu_name":  menuName,
-				"quantity":   item.Quantity,
-				"unit_price": item.UnitPrice,
-				"subtotal":   subtotal,
-			})
-		}
+		items, totalPrice := formatOrderItems(order.OrderItems)
 
 		// 计算最终支付金额
-		finalPrice := totalPrice - order.PointsDeductionAmount
+		finalPrice := totalPrice - order.DiscountAmount - order.PointsDeductionAmount
 
 		orderList = append(orderList, gin.H{
 			"id":                      order.ID,
 			"order_number":            order.OrderNumber,
 			"pickup_code":             order.PickupCode,
 			"original_total_price":    totalPrice,
+			"discount_amount":         order.DiscountAmount,
 			"points_deduction_amount": order.PointsDeductionAmount,
 			"final_payment_amount":    finalPrice,
 			"points_used":             order.CustomerPointsUsed,
 			"points_earned":           order.PointsEarned,
 			"status":                  order.Status,
+			"payment_status":          order.PaymentStatus,
+			"scheduled_pickup_at":     order.ScheduledPickupAt,
 			"notes":                   order.Notes,
 			"items":                   items,
 			"item_count":              len(items),
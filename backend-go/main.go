@@ -4,8 +4,11 @@ import (
 	"coffee-ordering-backend/config"
 	"coffee-ordering-backend/database"
 	"coffee-ordering-backend/routes"
+	"coffee-ordering-backend/services"
+	"context"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -15,12 +18,35 @@ func main() {
 	// 加载配置
 	config.LoadConfig()
 
+	// 拒绝不安全的配置组合（如 release 模式开启开发认证）
+	if err := config.AppConfig.Validate(); err != nil {
+		log.Fatalf("配置错误: %v", err)
+	}
+
 	// 设置 Gin 模式
 	gin.SetMode(config.AppConfig.GinMode)
 
 	// 初始化数据库
 	database.InitDB()
 
+	// 开发认证模式：创建预置角色账号
+	if config.AppConfig.DevAuthEnabled {
+		log.Println("警告：已开启开发认证模式（DEV_AUTH_ENABLED），仅限本地开发使用")
+		if err := services.NewDevAuthService().SeedPersonas(database.GetDB()); err != nil {
+			log.Fatalf("开发角色账号创建失败: %v", err)
+		}
+	}
+
+	// 启动后台定时任务
+	scheduler := services.NewScheduler(database.GetDB(), time.Duration(config.AppConfig.SchedulerIntervalSeconds)*time.Second)
+	scheduler.Register("release_scheduled_orders", 0, services.ReleaseScheduledOrdersJob)
+	scheduler.Register("cancel_unpaid_orders", 0, services.CancelUnpaidOrdersJob)
+	scheduler.Register("process_payment_refunds", 0, services.PaymentRefundJob)
+	scheduler.Register("purge_idempotency_keys", time.Hour, services.PurgeIdempotencyKeysJob)
+	scheduler.Register("birthday_bonus", time.Hour, services.BirthdayBonusJob)
+	scheduler.Register("expire_points", time.Hour, services.PointsExpiryJob)
+	scheduler.Start(context.Background())
+
 	// 创建 Gin 引擎
 	r := gin.Default()
 
@@ -29,7 +55,8 @@ func main() {
 	origins := strings.Split(config.AppConfig.CORSOrigins, ",")
 	corsConfig.AllowOrigins = origins
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "Idempotency-Key"}
+	corsConfig.ExposeHeaders = []string{"Idempotent-Replayed"}
 	r.Use(cors.New(corsConfig))
 
 	// 设置路由
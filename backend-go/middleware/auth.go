@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"coffee-ordering-backend/models"
 	"coffee-ordering-backend/utils"
 	"net/http"
 	"strings"
@@ -8,7 +9,8 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AdminRequired 管理员权限验证中间件（JWT版）
+// AdminRequired 管理后台认证中间件（JWT版），允许所有员工角色登录
+// 具体操作权限由 RequirePermission 按接口校验
 func AdminRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取 Authorization header
@@ -35,15 +37,6 @@ func AdminRequired() gin.HandlerFunc {
 			return
 		}
 
-		// 兼容旧的假token（用于开发测试）
-		if strings.HasPrefix(token, "fake-admin-token-") {
-			c.Set("user_id", uint(0))
-			c.Set("username", "admin")
-			c.Set("role", "admin")
-			c.Next()
-			return
-		}
-
 		// JWT验证
 		claims, err := utils.ParseToken(token)
 		if err != nil {
@@ -55,8 +48,18 @@ func AdminRequired() gin.HandlerFunc {
 			return
 		}
 
-		// 检查是否是管理员
-		if claims.Role != "admin" {
+		user, ok := loadTokenUser(claims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "认证令牌已失效，请重新登录",
+			})
+			c.Abort()
+			return
+		}
+
+		// 检查是否是员工角色（以数据库中的当前角色为准）
+		if !models.IsStaffRole(user.Role) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"success": false,
 				"message": "需要管理员权限",
@@ -65,12 +68,43 @@ func AdminRequired() gin.HandlerFunc {
 			return
 		}
 
-		// 将用户信息存入上下文
+		// 将用户信息存入上下文，角色与所属门店以数据库为准
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
+		c.Set("role", user.Role)
+		if user.StoreID != nil {
+			c.Set("store_id", *user.StoreID)
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission 操作权限校验中间件，须在 AdminRequired 之后使用
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !models.HasPermission(c.GetString("role"), permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "没有操作权限: " + string(permission),
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
 
+// TokenFromQuery 从查询参数 token 读取认证令牌
+// 浏览器 EventSource 无法设置请求头，事件流接口通过该中间件兼容
+func TokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
 		c.Next()
 	}
 }
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
 	"coffee-ordering-backend/utils"
 	"net/http"
 	"strings"
@@ -45,11 +47,21 @@ func UserAuthRequired() gin.HandlerFunc {
 			return
 		}
 
-		// 将用户信息存入上下文
+		user, ok := loadTokenUser(claims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "认证令牌已失效，请重新登录",
+			})
+			c.Abort()
+			return
+		}
+
+		// 将用户信息存入上下文，角色以数据库为准
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
+		c.Set("role", user.Role)
 
 		c.Next()
 	}
@@ -68,10 +80,12 @@ func OptionalUserAuth() gin.HandlerFunc {
 		if len(parts) == 2 && parts[0] == "Bearer" {
 			claims, err := utils.ParseToken(parts[1])
 			if err == nil {
-				c.Set("user_id", claims.UserID)
-				c.Set("username", claims.Username)
-				c.Set("email", claims.Email)
-				c.Set("role", claims.Role)
+				if user, ok := loadTokenUser(claims); ok {
+					c.Set("user_id", claims.UserID)
+					c.Set("username", claims.Username)
+					c.Set("email", claims.Email)
+					c.Set("role", user.Role)
+				}
 			}
 		}
 
@@ -79,6 +93,20 @@ func OptionalUserAuth() gin.HandlerFunc {
 	}
 }
 
+// loadTokenUser 加载令牌对应的用户
+// 用户被禁用、删除，或令牌版本已变化（改角色、重置密码、禁用）时令牌立即失效
+func loadTokenUser(claims *utils.Claims) (*models.User, bool) {
+	var user models.User
+	if err := database.GetDB().Select("id", "role", "store_id", "is_active", "token_version").
+		First(&user, claims.UserID).Error; err != nil {
+		return nil, false
+	}
+	if !user.IsActive || user.TokenVersion != claims.TokenVersion {
+		return nil, false
+	}
+	return &user, true
+}
+
 // GetUserID 从上下文获取用户ID
 func GetUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get("user_id")
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey 下单幂等键（保存首次请求的响应用于重试回放）
+type IdempotencyKey struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Scope        string    `gorm:"size:50;not null;uniqueIndex:idx_idempotency_scope_key" json:"scope"` // user:<id> 或 guest
+	Key          string    `gorm:"size:100;not null;uniqueIndex:idx_idempotency_scope_key" json:"key"`
+	RequestHash  string    `gorm:"size:64;not null" json:"request_hash"`
+	OrderID      *uint     `gorm:"index" json:"order_id"`
+	StatusCode   int       `gorm:"not null" json:"status_code"`
+	ResponseBody string    `gorm:"type:text;not null" json:"-"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+)
+
+// Ingredient 原料模型（咖啡豆、牛奶、杯子、糕点等）
+type Ingredient struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	Name              string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Unit              string    `gorm:"size:20;not null" json:"unit"` // g, ml, 个
+	Stock             float64   `gorm:"type:decimal(12,3);default:0;not null" json:"stock"`
+	LowStockThreshold float64   `gorm:"type:decimal(12,3);default:0;not null" json:"low_stock_threshold"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Ingredient) TableName() string {
+	return "ingredients"
+}
+
+// IsLowStock 是否低于低库存阈值
+func (i *Ingredient) IsLowStock() bool {
+	return i.Stock <= i.LowStockThreshold
+}
+
+// RecipeItem 菜品配方（每份菜品消耗的原料数量）
+type RecipeItem struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	MenuItemID   uint      `gorm:"not null;uniqueIndex:idx_menu_ingredient" json:"menu_item_id"`
+	IngredientID uint      `gorm:"not null;uniqueIndex:idx_menu_ingredient;index" json:"ingredient_id"`
+	Quantity     float64   `gorm:"type:decimal(12,3);not null" json:"quantity"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// 关联
+	Ingredient *Ingredient `gorm:"foreignKey:IngredientID" json:"ingredient,omitempty"`
+}
+
+// TableName 指定表名
+func (RecipeItem) TableName() string {
+	return "menu_recipes"
+}
+
+// StockMovementType 库存变动类型
+type StockMovementType string
+
+const (
+	StockMovementOrderConsume StockMovementType = "order_consume" // 下单扣减
+	StockMovementOrderRestore StockMovementType = "order_restore" // 取消订单恢复
+	StockMovementStockIn      StockMovementType = "stock_in"      // 入库
+	StockMovementStocktake    StockMovementType = "stocktake"     // 盘点调整
+)
+
+// StockMovement 库存变动记录
+type StockMovement struct {
+	ID             uint              `gorm:"primaryKey" json:"id"`
+	IngredientID   uint              `gorm:"not null;index" json:"ingredient_id"`
+	MovementType   StockMovementType `gorm:"type:enum('order_consume','order_restore','stock_in','stocktake');not null;index" json:"movement_type"`
+	QuantityChange float64           `gorm:"type:decimal(12,3);not null" json:"quantity_change"` // 正数为增加，负数为扣减
+	StockAfter     float64           `gorm:"type:decimal(12,3);not null" json:"stock_after"`
+	OrderID        *uint             `gorm:"index" json:"order_id"`
+	OperatorID     *uint             `json:"operator_id"`
+	Note           string            `gorm:"size:255" json:"note"`
+	CreatedAt      time.Time         `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (StockMovement) TableName() string {
+	return "stock_movements"
+}
@@ -12,10 +12,11 @@ type MemberLevelConfig struct {
 	MinPoints             int         `gorm:"not null;index" json:"min_points"`
 	PointsEarningRate     float64     `gorm:"type:decimal(5,4);default:1.0000" json:"points_earning_rate"`
 	PointsDiscountRate    float64     `gorm:"type:decimal(5,4);default:0.0000" json:"points_discount_rate"`
-	MaxDiscountPercentage float64     `gorm:"type:decimal(5,2);default:20.00" json:"max_discount_percentage"`
-	Benefits              string      `gorm:"type:json" json:"benefits"` // JSON格式
+	MaxDiscountPercentage float64     `gorm:"type:decimal(5,2);not null" json:"max_discount_percentage"` // 不设 gorm 默认值，否则创建时 0 会被替换为 20
+	BirthdayMultiplier    float64     `gorm:"type:decimal(5,2);not null" json:"birthday_multiplier"`     // 生日积分倍数，不设 gorm 默认值，否则创建时 0 会被替换为 1
+	Benefits              string      `gorm:"type:json" json:"benefits"`                                 // JSON格式
 	LevelIcon             string      `gorm:"size:255" json:"level_icon"`
-	IsActive              bool        `gorm:"default:true;index" json:"is_active"`
+	IsActive              bool        `gorm:"index" json:"is_active"` // 不设 gorm 默认值，否则创建时 false 会被替换为 true
 	SortOrder             int         `gorm:"default:0;index" json:"sort_order"`
 	CreatedAt             time.Time   `json:"created_at"`
 	UpdatedAt             time.Time   `json:"updated_at"`
@@ -44,3 +45,19 @@ type MemberLevelHistory struct {
 func (MemberLevelHistory) TableName() string {
 	return "member_level_history"
 }
+
+// BirthdayBonusGrant 生日积分发放记录，每位会员每年一条，防止重复发放
+type BirthdayBonusGrant struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	UserID      uint        `gorm:"not null;uniqueIndex:idx_user_year" json:"user_id"`
+	Year        int         `gorm:"not null;uniqueIndex:idx_user_year" json:"year"`
+	MemberLevel MemberLevel `gorm:"type:enum('bronze','silver','gold','platinum');not null" json:"member_level"`
+	Multiplier  float64     `gorm:"type:decimal(5,2);not null" json:"multiplier"`
+	Points      int         `gorm:"not null" json:"points"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// TableName 指定表名
+func (BirthdayBonusGrant) TableName() string {
+	return "birthday_bonus_grants"
+}
@@ -13,11 +13,13 @@ type MenuItem struct {
 	Category    string    `gorm:"size:50;not null;default:'coffee';index" json:"category"`
 	ImageURL    string    `gorm:"size:255" json:"image_url"`
 	IsAvailable bool      `gorm:"default:true;not null;index" json:"is_available"`
+	SoldOut     bool      `gorm:"default:false;not null" json:"sold_out"` // 因原料不足自动下架，补货后自动恢复
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
 	// 关联
-	OrderItems []OrderItem `gorm:"foreignKey:MenuID;constraint:OnDelete:RESTRICT" json:"-"`
+	OrderItems   []OrderItem       `gorm:"foreignKey:MenuID;constraint:OnDelete:RESTRICT" json:"-"`
+	OptionGroups []MenuOptionGroup `gorm:"foreignKey:MenuItemID;constraint:OnDelete:CASCADE" json:"option_groups,omitempty"`
 }
 
 // TableName 指定表名
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// OptionSelectionType 选项组选择方式
+type OptionSelectionType string
+
+const (
+	OptionSelectionSingle OptionSelectionType = "single"
+	OptionSelectionMulti  OptionSelectionType = "multi"
+)
+
+// MenuOptionGroup 菜品定制选项组（杯型、奶类、甜度、温度、加浓缩等）
+type MenuOptionGroup struct {
+	ID            uint                `gorm:"primaryKey" json:"id"`
+	MenuItemID    uint                `gorm:"not null;index" json:"menu_item_id"`
+	Name          string              `gorm:"size:50;not null" json:"name"`
+	SelectionType OptionSelectionType `gorm:"type:enum('single','multi');default:'single';not null" json:"selection_type"`
+	IsRequired    bool                `gorm:"default:false;not null" json:"is_required"`
+	MaxSelect     int                 `gorm:"default:0;not null" json:"max_select"` // 多选上限，0为不限
+	SortOrder     int                 `gorm:"default:0" json:"sort_order"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+
+	// 关联
+	Options []MenuOption `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE" json:"options"`
+}
+
+// TableName 指定表名
+func (MenuOptionGroup) TableName() string {
+	return "menu_option_groups"
+}
+
+// MenuOption 定制选项
+type MenuOption struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	GroupID     uint      `gorm:"not null;index" json:"group_id"`
+	Name        string    `gorm:"size:50;not null" json:"name"`
+	PriceDelta  float64   `gorm:"type:decimal(10,2);default:0.00;not null" json:"price_delta"` // 相对基础价格的加价
+	IsDefault   bool      `gorm:"default:false;not null" json:"is_default"`
+	IsAvailable bool      `gorm:"not null" json:"is_available"` // 不设 gorm 默认值，否则创建时 false 会被替换为 true
+	SortOrder   int       `gorm:"default:0" json:"sort_order"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (MenuOption) TableName() string {
+	return "menu_options"
+}
+
+// OrderItemOption 订单项所选定制选项（下单时快照）
+type OrderItemOption struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	OrderItemID uint      `gorm:"not null;index" json:"order_item_id"`
+	OptionID    uint      `gorm:"not null;index" json:"option_id"`
+	GroupName   string    `gorm:"size:50;not null" json:"group_name"`
+	OptionName  string    `gorm:"size:50;not null" json:"option_name"`
+	PriceDelta  float64   `gorm:"type:decimal(10,2);default:0.00;not null" json:"price_delta"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (OrderItemOption) TableName() string {
+	return "order_item_options"
+}
@@ -2,7 +2,6 @@ package models
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 )
 
@@ -17,10 +16,50 @@ const (
 	OrderStatusCancelled OrderStatus = "cancelled"
 )
 
+// orderStatusTransitions 订单状态合法流转
+// 已完成订单允许取消（退单场景），已取消为终态
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:   {OrderStatusPreparing, OrderStatusCancelled},
+	OrderStatusPreparing: {OrderStatusReady, OrderStatusCancelled},
+	OrderStatusReady:     {OrderStatusCompleted, OrderStatusCancelled},
+	OrderStatusCompleted: {OrderStatusCancelled},
+	OrderStatusCancelled: {},
+}
+
+// IsValid 是否为有效的订单状态
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo 是否允许从当前状态变更为目标状态
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range orderStatusTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// orderStatusFlow 正常出品流程中的下一状态
+var orderStatusFlow = map[OrderStatus]OrderStatus{
+	OrderStatusPending:   OrderStatusPreparing,
+	OrderStatusPreparing: OrderStatusReady,
+	OrderStatusReady:     OrderStatusCompleted,
+}
+
+// NextInFlow 正常出品流程中的下一状态，已完成或已取消时返回 false
+func (s OrderStatus) NextInFlow() (OrderStatus, bool) {
+	next, ok := orderStatusFlow[s]
+	return next, ok
+}
+
 // Order 订单模型
 type Order struct {
 	ID                    uint         `gorm:"primaryKey" json:"id"`
 	UserID                *uint        `gorm:"index" json:"user_id"` // 用户ID，可为空（游客订单）
+	StoreID               uint         `gorm:"not null;default:1;index" json:"store_id"`
 	OrderNumber           string       `gorm:"size:50;uniqueIndex;not null" json:"order_number"`
 	PickupCode            string       `gorm:"size:10;index;not null" json:"pickup_code"`
 	Status                OrderStatus  `gorm:"type:enum('pending','preparing','ready','completed','cancelled');default:'pending';index" json:"status"`
@@ -33,13 +72,29 @@ type Order struct {
 	PointsDeductionAmount float64      `gorm:"type:decimal(10,2);default:0.00" json:"points_deduction_amount"`   // 积分抵扣金额
 	PointsEarned          int          `gorm:"default:0;index" json:"points_earned"`                             // 获得的积分数量
 	MemberLevelAtTime     *MemberLevel `gorm:"type:enum('bronze','silver','gold','platinum')" json:"member_level_at_time"` // 下单时会员等级
+	PointsAwardedAt       *time.Time   `json:"points_awarded_at"`                                                 // 积分实际发放时间（防止重复发放）
+	PointsRefundedAt      *time.Time   `json:"points_refunded_at"`                                                // 取消订单退还抵扣积分时间
+	PointsReversedAt      *time.Time   `json:"points_reversed_at"`                                                // 取消订单扣回已发放积分时间
+
+	// 优惠
+	DiscountAmount float64 `gorm:"type:decimal(10,2);default:0.00" json:"discount_amount"` // 优惠券与促销活动减免金额
+
+	// 支付相关字段
+	PaymentAmount float64            `gorm:"type:decimal(10,2);default:0.00" json:"payment_amount"`                         // 应付金额（下单时计算）
+	PaymentStatus OrderPaymentStatus `gorm:"type:enum('unpaid','paid','refunded');default:'unpaid';index" json:"payment_status"` // 支付状态
+	PaidAt        *time.Time         `json:"paid_at"`                                                                        // 支付完成时间
+
+	// 预约取餐
+	ScheduledPickupAt *time.Time `gorm:"index" json:"scheduled_pickup_at"` // 预约取餐时间，为空表示尽快制作
+	ReleasedAt        *time.Time `json:"released_at"`                      // 预约订单进入出品队列时间
 
 	CreatedAt             time.Time    `gorm:"index" json:"created_at"`
 	UpdatedAt             time.Time    `json:"updated_at"`
 
 	// 关联
-	User       *User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	OrderItems []OrderItem `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"order_items,omitempty"`
+	User       *User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	OrderItems []OrderItem     `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"order_items,omitempty"`
+	Discounts  []OrderDiscount `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"discounts,omitempty"`
 }
 
 // TableName 指定表名
@@ -47,17 +102,26 @@ func (Order) TableName() string {
 	return "orders"
 }
 
-// GenerateOrderNumber 生成订单号
-func GenerateOrderNumber() string {
-	datePrefix := time.Now().Format("20060102")
-	randomNum := rand.Intn(10000)
-	return fmt.Sprintf("CO%s%04d", datePrefix, randomNum)
+// PickupCodeCapacity 取餐码容量（A000-Z999），用尽后从A000循环
+const PickupCodeCapacity = 26 * 1000
+
+// ActiveOrderStatuses 进行中（未完成、未取消）的订单状态
+var ActiveOrderStatuses = []OrderStatus{OrderStatusPending, OrderStatusPreparing, OrderStatusReady}
+
+// IsHeld 预约订单是否尚未进入出品队列
+func (o *Order) IsHeld() bool {
+	return o.ScheduledPickupAt != nil && o.ReleasedAt == nil
+}
+
+// FormatOrderNumber 根据日期、门店和门店当日序号生成订单号，如 CO20240101001-000001
+// 使用完整门店ID并以连字符分隔序号，门店ID或序号位数增加时也不会与其他门店重复
+func FormatOrderNumber(date time.Time, storeID uint, seq int) string {
+	return fmt.Sprintf("CO%s%03d-%06d", date.Format("20060102"), storeID, seq)
 }
 
-// GeneratePickupCode 生成取餐码
-func GeneratePickupCode() string {
-	hour := time.Now().Hour()
-	letter := rune(65 + (hour % 26)) // A-Z
-	randomNum := rand.Intn(1000)
-	return fmt.Sprintf("%c%03d", letter, randomNum)
+// FormatPickupCode 根据序号生成取餐码（A000, A001 ... Z999）
+func FormatPickupCode(seq int) string {
+	n := seq % PickupCodeCapacity
+	letter := rune('A' + n/1000)
+	return fmt.Sprintf("%c%03d", letter, n%1000)
 }
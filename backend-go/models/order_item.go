@@ -4,17 +4,56 @@ import (
 	"time"
 )
 
+// OrderItemStatus 订单项出品状态
+type OrderItemStatus string
+
+const (
+	OrderItemStatusQueued OrderItemStatus = "queued"
+	OrderItemStatusMaking OrderItemStatus = "making"
+	OrderItemStatusDone   OrderItemStatus = "done"
+	OrderItemStatusVoided OrderItemStatus = "voided"
+)
+
+// orderItemStatusTransitions 订单项状态合法流转，出品完成和作废为终态
+var orderItemStatusTransitions = map[OrderItemStatus][]OrderItemStatus{
+	OrderItemStatusQueued: {OrderItemStatusMaking, OrderItemStatusDone, OrderItemStatusVoided},
+	OrderItemStatusMaking: {OrderItemStatusDone, OrderItemStatusVoided},
+	OrderItemStatusDone:   {},
+	OrderItemStatusVoided: {},
+}
+
+// IsValid 是否为有效的订单项状态
+func (s OrderItemStatus) IsValid() bool {
+	_, ok := orderItemStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo 是否允许从当前状态变更为目标状态
+func (s OrderItemStatus) CanTransitionTo(to OrderItemStatus) bool {
+	for _, next := range orderItemStatusTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
 // OrderItem 订单项模型
 type OrderItem struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	OrderID   uint      `gorm:"not null;index" json:"order_id"`
-	MenuID    uint      `gorm:"column:menu_item_id;not null;index" json:"menu_id"`
-	Quantity  int       `gorm:"not null" json:"quantity"`
-	UnitPrice float64   `gorm:"type:decimal(10,2);not null" json:"unit_price"` // 下单时商品单价（历史快照）
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	OrderID   uint            `gorm:"not null;index" json:"order_id"`
+	MenuID    uint            `gorm:"column:menu_item_id;not null;index" json:"menu_id"`
+	Quantity  int             `gorm:"not null" json:"quantity"`
+	UnitPrice float64         `gorm:"type:decimal(10,2);not null" json:"unit_price"` // 下单时商品单价（含定制选项加价，历史快照）
+	Status    OrderItemStatus `gorm:"type:enum('queued','making','done','voided');default:'queued';not null" json:"status"`
+	StartedAt *time.Time      `json:"started_at"` // 开始制作时间
+	DoneAt    *time.Time      `json:"done_at"`    // 出品完成时间
+	VoidedAt  *time.Time      `json:"voided_at"`  // 作废时间
+	CreatedAt time.Time       `json:"created_at"`
 
 	// 关联
-	MenuItem MenuItem `gorm:"foreignKey:MenuID" json:"menu_item,omitempty"`
+	MenuItem MenuItem          `gorm:"foreignKey:MenuID" json:"menu_item,omitempty"`
+	Options  []OrderItemOption `gorm:"foreignKey:OrderItemID;constraint:OnDelete:CASCADE" json:"options,omitempty"`
 }
 
 // GetSubtotal 计算小计（数量 × 单价）
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderSequence 门店每日订单序列（订单号与取餐码均由此生成，按门店按天重置）
+type OrderSequence struct {
+	StoreID   uint      `gorm:"primaryKey;autoIncrement:false" json:"store_id"`
+	SeqDate   string    `gorm:"size:8;primaryKey" json:"seq_date"` // 格式: 20060102（门店时区）
+	OrderSeq  int       `gorm:"default:0;not null" json:"order_seq"`
+	PickupSeq int       `gorm:"default:0;not null" json:"pickup_seq"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (OrderSequence) TableName() string {
+	return "order_sequences"
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderStatusHistory 订单状态变更记录
+type OrderStatusHistory struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	OrderID    uint         `gorm:"not null;index" json:"order_id"`
+	FromStatus *OrderStatus `gorm:"type:enum('pending','preparing','ready','completed','cancelled')" json:"from_status"` // 下单时为空
+	ToStatus   OrderStatus  `gorm:"type:enum('pending','preparing','ready','completed','cancelled');not null" json:"to_status"`
+	ActorID    *uint        `gorm:"index" json:"actor_id"`              // 操作人ID，游客或系统操作为空
+	ActorRole  string       `gorm:"size:20;not null" json:"actor_role"` // admin, user, guest, system
+	Reason     string       `gorm:"size:255" json:"reason"`
+	CreatedAt  time.Time    `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
@@ -0,0 +1,29 @@
+package models
+
+import "testing"
+
+func TestOrderStatusCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from OrderStatus
+		to   OrderStatus
+		want bool
+	}{
+		{OrderStatusPending, OrderStatusPreparing, true},
+		{OrderStatusPending, OrderStatusReady, false},
+		{OrderStatusPending, OrderStatusCancelled, true},
+		{OrderStatusPreparing, OrderStatusReady, true},
+		{OrderStatusPreparing, OrderStatusPending, false},
+		{OrderStatusReady, OrderStatusCompleted, true},
+		{OrderStatusCompleted, OrderStatusCancelled, true},
+		{OrderStatusCompleted, OrderStatusReady, false},
+		{OrderStatusCancelled, OrderStatusPending, false},
+		{OrderStatusCancelled, OrderStatusCancelled, false},
+		{OrderStatus("unknown"), OrderStatusPending, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s -> %s = %v，期望 %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
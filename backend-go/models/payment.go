@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderPaymentStatus 订单支付状态
+type OrderPaymentStatus string
+
+const (
+	OrderPaymentUnpaid   OrderPaymentStatus = "unpaid"
+	OrderPaymentPaid     OrderPaymentStatus = "paid"
+	OrderPaymentRefunded OrderPaymentStatus = "refunded"
+)
+
+// PaymentStatus 支付单状态
+type PaymentStatus string
+
+const (
+	PaymentStatusPending   PaymentStatus = "pending"
+	PaymentStatusSucceeded PaymentStatus = "succeeded"
+	PaymentStatusFailed    PaymentStatus = "failed"
+	PaymentStatusRefunded  PaymentStatus = "refunded"
+)
+
+// Payment 支付单模型
+type Payment struct {
+	ID            uint          `gorm:"primaryKey" json:"id"`
+	OrderID       uint          `gorm:"not null;index" json:"order_id"`
+	Provider      string        `gorm:"size:30;not null;uniqueIndex:idx_provider_ref" json:"provider"`
+	ProviderRef   string        `gorm:"size:100;not null;uniqueIndex:idx_provider_ref" json:"provider_ref"` // 支付渠道侧的支付单号
+	Amount        float64       `gorm:"type:decimal(10,2);not null" json:"amount"`
+	Status        PaymentStatus `gorm:"type:enum('pending','succeeded','failed','refunded');default:'pending';not null;index" json:"status"`
+	FailureReason string        `gorm:"size:255" json:"failure_reason,omitempty"`
+	PaidAt        *time.Time    `json:"paid_at"`
+	RefundedAt    *time.Time    `json:"refunded_at"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Payment) TableName() string {
+	return "payments"
+}
+
+// PaymentRefundStatus 退款单状态
+type PaymentRefundStatus string
+
+const (
+	PaymentRefundPending   PaymentRefundStatus = "pending"
+	PaymentRefundSucceeded PaymentRefundStatus = "succeeded"
+	PaymentRefundFailed    PaymentRefundStatus = "failed" // 重试次数用尽，需人工处理
+)
+
+// PaymentRefund 退款单（发件箱）
+// 订单取消事务内写入，事务提交后再调用支付渠道退款，失败按退避时间重试；
+// 每个支付单只会生成一条退款单，渠道退款以退款单ID作为幂等键
+type PaymentRefund struct {
+	ID            uint                `gorm:"primaryKey" json:"id"`
+	PaymentID     uint                `gorm:"not null;uniqueIndex" json:"payment_id"`
+	OrderID       uint                `gorm:"not null;index" json:"order_id"`
+	Amount        float64             `gorm:"type:decimal(10,2);not null" json:"amount"`
+	Status        PaymentRefundStatus `gorm:"type:enum('pending','succeeded','failed');default:'pending';not null;index:idx_status_next_attempt" json:"status"`
+	Attempts      int                 `gorm:"default:0;not null" json:"attempts"`
+	LastError     string              `gorm:"size:255" json:"last_error,omitempty"`
+	NextAttemptAt time.Time           `gorm:"not null;index:idx_status_next_attempt" json:"next_attempt_at"`
+	RefundedAt    *time.Time          `json:"refunded_at"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PaymentRefund) TableName() string {
+	return "payment_refunds"
+}
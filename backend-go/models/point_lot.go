@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// PointLot 积分批次，每次获得积分生成一个批次，使用时按先到期先扣减
+type PointLot struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	UserID        uint            `gorm:"not null;index:idx_user_remaining" json:"user_id"`
+	TransactionID *uint           `gorm:"index" json:"transaction_id"` // 来源积分变动记录
+	SourceType    TransactionType `gorm:"size:30;not null" json:"source_type"`
+	Points        int             `gorm:"not null" json:"points"`                             // 批次原始积分
+	Remaining     int             `gorm:"not null;index:idx_user_remaining" json:"remaining"` // 剩余可用积分
+	EarnedAt      time.Time       `gorm:"not null" json:"earned_at"`
+	ExpiresAt     *time.Time      `gorm:"index" json:"expires_at"` // 为空表示永不过期
+	ExpiredAt     *time.Time      `json:"expired_at"`              // 实际过期处理时间
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PointLot) TableName() string {
+	return "point_lots"
+}
@@ -12,9 +12,11 @@ const (
 	TransactionTypeUsed          TransactionType = "used"
 	TransactionTypeExpired       TransactionType = "expired"
 	TransactionTypeRefunded      TransactionType = "refunded"
+	TransactionTypeReversed      TransactionType = "reversed" // 订单取消扣回已发放积分
 	TransactionTypeSignupBonus   TransactionType = "signup_bonus"
 	TransactionTypeBirthdayBonus TransactionType = "birthday_bonus"
 	TransactionTypeReferralBonus TransactionType = "referral_bonus"
+	TransactionTypeAdjustment    TransactionType = "adjustment" // 管理员手动调整
 )
 
 // PointTransaction 积分变动记录模型
@@ -22,7 +24,7 @@ type PointTransaction struct {
 	ID              uint            `gorm:"primaryKey" json:"id"`
 	UserID          uint            `gorm:"not null;index" json:"user_id"`
 	OrderID         *uint           `gorm:"index" json:"order_id"`
-	TransactionType TransactionType `gorm:"type:enum('earned','used','expired','refunded','signup_bonus','birthday_bonus','referral_bonus');not null" json:"transaction_type"`
+	TransactionType TransactionType `gorm:"type:enum('earned','used','expired','refunded','reversed','signup_bonus','birthday_bonus','referral_bonus','adjustment');not null" json:"transaction_type"`
 	PointsChange    int             `gorm:"not null" json:"points_change"`      // 正数为获得，负数为使用
 	PointsBalance   int             `gorm:"not null" json:"points_balance"`     // 变动后余额
 	Description     string          `gorm:"size:255;not null" json:"description"`
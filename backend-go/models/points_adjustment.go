@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// PointsAdjustmentStatus 积分调整状态
+type PointsAdjustmentStatus string
+
+const (
+	PointsAdjustmentPending  PointsAdjustmentStatus = "pending"  // 待第二位管理员审批
+	PointsAdjustmentApplied  PointsAdjustmentStatus = "applied"  // 已调整到账
+	PointsAdjustmentRejected PointsAdjustmentStatus = "rejected" // 已驳回
+)
+
+// PointsAdjustment 管理员手动积分调整记录
+type PointsAdjustment struct {
+	ID            uint                   `gorm:"primaryKey" json:"id"`
+	UserID        uint                   `gorm:"not null;index" json:"user_id"`
+	Points        int                    `gorm:"not null" json:"points"` // 正数为补偿，负数为扣减
+	Reason        string                 `gorm:"size:255;not null" json:"reason"`
+	Status        PointsAdjustmentStatus `gorm:"type:enum('pending','applied','rejected');default:'pending';index" json:"status"`
+	RequestedBy   uint                   `gorm:"not null;index" json:"requested_by"`
+	ReviewedBy    *uint                  `json:"reviewed_by"` // 审批人，未超过审批阈值时为空
+	ReviewNote    string                 `gorm:"size:255" json:"review_note"`
+	ReviewedAt    *time.Time             `json:"reviewed_at"`
+	TransactionID *uint                  `json:"transaction_id"` // 调整到账后对应的积分变动记录
+	AppliedAt     *time.Time             `json:"applied_at"`
+	CreatedAt     time.Time              `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+
+	// 关联
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 指定表名
+func (PointsAdjustment) TableName() string {
+	return "points_adjustments"
+}
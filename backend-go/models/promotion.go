@@ -0,0 +1,122 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// PromotionType 优惠规则类型
+type PromotionType string
+
+const (
+	PromotionPercentOff PromotionType = "percent_off" // 折扣（按百分比减免）
+	PromotionFixedOff   PromotionType = "fixed_off"   // 立减固定金额
+	PromotionBuyXGetY   PromotionType = "buy_x_get_y" // 买X送Y（赠送适用商品中价格最低的Y件）
+)
+
+// IsValid 是否为有效的优惠规则类型
+func (t PromotionType) IsValid() bool {
+	switch t {
+	case PromotionPercentOff, PromotionFixedOff, PromotionBuyXGetY:
+		return true
+	}
+	return false
+}
+
+// Promotion 优惠活动模型
+// Code 为空的为自动促销（满足条件自动生效），否则为优惠券（需输入券码）
+type Promotion struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	Name        string        `gorm:"size:100;not null" json:"name"`
+	Description string        `gorm:"type:text" json:"description"`
+	Code        *string       `gorm:"size:50;uniqueIndex" json:"code"` // 优惠券码（不区分大小写，统一保存为大写）
+	Type        PromotionType `gorm:"type:enum('percent_off','fixed_off','buy_x_get_y');not null" json:"type"`
+
+	// 适用范围：均为空时适用全部商品
+	Category   string `gorm:"size:50" json:"category"` // 适用菜品分类
+	MenuItemID *uint  `json:"menu_item_id"`            // 适用菜品
+
+	// 优惠规则
+	DiscountValue     float64 `gorm:"type:decimal(10,2);default:0.00" json:"discount_value"`      // 折扣百分比（0-100）或立减金额
+	MaxDiscountAmount float64 `gorm:"type:decimal(10,2);default:0.00" json:"max_discount_amount"` // 折扣封顶金额，0 表示不封顶
+	BuyQuantity       int     `gorm:"default:0" json:"buy_quantity"`                              // 买X
+	GetQuantity       int     `gorm:"default:0" json:"get_quantity"`                              // 送Y
+	MinSpend          float64 `gorm:"type:decimal(10,2);default:0.00" json:"min_spend"`           // 订单最低消费
+
+	// 使用条件
+	MemberLevels string     `gorm:"size:100" json:"member_levels"` // 限定会员等级，逗号分隔，为空表示不限
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	UsageLimit   int        `gorm:"default:0" json:"usage_limit"`    // 总使用次数上限，0 表示不限
+	PerUserLimit int        `gorm:"default:0" json:"per_user_limit"` // 每位会员使用次数上限，0 表示不限
+
+	IsActive  bool      `gorm:"not null;index" json:"is_active"` // 不设 gorm 默认值，否则创建时 false 会被替换为 true
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Promotion) TableName() string {
+	return "promotions"
+}
+
+// IsCoupon 是否为需要券码的优惠券
+func (p *Promotion) IsCoupon() bool {
+	return p.Code != nil && *p.Code != ""
+}
+
+// AllowsLevel 会员等级是否满足使用条件
+func (p *Promotion) AllowsLevel(level MemberLevel) bool {
+	if strings.TrimSpace(p.MemberLevels) == "" {
+		return true
+	}
+	for _, l := range strings.Split(p.MemberLevels, ",") {
+		if MemberLevel(strings.TrimSpace(l)) == level {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveAt 指定时间是否在活动有效期内
+func (p *Promotion) ActiveAt(now time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo 菜品是否在优惠适用范围内
+func (p *Promotion) AppliesTo(item *MenuItem) bool {
+	if p.MenuItemID != nil && *p.MenuItemID != item.ID {
+		return false
+	}
+	if p.Category != "" && p.Category != item.Category {
+		return false
+	}
+	return true
+}
+
+// OrderDiscount 订单优惠明细（下单时快照）
+type OrderDiscount struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	OrderID     uint          `gorm:"not null;index" json:"order_id"`
+	PromotionID uint          `gorm:"not null;index" json:"promotion_id"`
+	UserID      *uint         `gorm:"index" json:"user_id"` // 使用人，用于统计每位会员的使用次数
+	Code        string        `gorm:"size:50" json:"code"`
+	Name        string        `gorm:"size:100;not null" json:"name"`
+	Type        PromotionType `gorm:"type:enum('percent_off','fixed_off','buy_x_get_y');not null" json:"type"`
+	Amount      float64       `gorm:"type:decimal(10,2);not null" json:"amount"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// TableName 指定表名
+func (OrderDiscount) TableName() string {
+	return "order_discounts"
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// ReferralStatus 推荐状态
+type ReferralStatus string
+
+const (
+	ReferralStatusPending  ReferralStatus = "pending"  // 等待被推荐人完成首笔付费订单
+	ReferralStatusRewarded ReferralStatus = "rewarded" // 双方奖励已发放
+	ReferralStatusRejected ReferralStatus = "rejected" // 未通过防刷校验或超出奖励上限
+)
+
+// Referral 推荐关系，每位被推荐人只能有一条
+type Referral struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	ReferrerID     uint           `gorm:"not null;index" json:"referrer_id"`
+	RefereeID      uint           `gorm:"not null;uniqueIndex" json:"referee_id"`
+	Code           string         `gorm:"size:16;not null" json:"code"`
+	Status         ReferralStatus `gorm:"type:enum('pending','rewarded','rejected');default:'pending';not null;index" json:"status"`
+	RejectReason   string         `gorm:"size:255" json:"reject_reason"`
+	DeviceID       string         `gorm:"size:64" json:"-"`
+	ReferrerPoints int            `gorm:"default:0" json:"referrer_points"` // 推荐人获得积分
+	RefereePoints  int            `gorm:"default:0" json:"referee_points"`  // 被推荐人获得积分
+	OrderID        *uint          `json:"order_id"`                         // 触发奖励的订单
+	RewardedAt     *time.Time     `json:"rewarded_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+
+	// 关联
+	Referee *User `gorm:"foreignKey:RefereeID" json:"-"`
+}
+
+// TableName 指定表名
+func (Referral) TableName() string {
+	return "referrals"
+}
@@ -0,0 +1,128 @@
+package models
+
+// 用户角色
+const (
+	RoleUser    = "user"    // 顾客
+	RoleOwner   = "owner"   // 店主，拥有全部权限
+	RoleAdmin   = "admin"   // 系统管理员，权限同店主（兼容原有管理员账号）
+	RoleManager = "manager" // 店长
+	RoleBarista = "barista" // 咖啡师
+	RoleCashier = "cashier" // 收银员
+)
+
+// Permission 后台操作权限
+type Permission string
+
+const (
+	PermissionMenuWrite          Permission = "menu:write"           // 维护菜单、定制选项与配方
+	PermissionInventoryRead      Permission = "inventory:read"       // 查看原料库存
+	PermissionInventoryWrite     Permission = "inventory:write"      // 入库、盘点、维护原料
+	PermissionStoresWrite        Permission = "stores:write"         // 维护门店、营业时间与门店菜单
+	PermissionStoresPause        Permission = "stores:pause"         // 暂停/恢复接单
+	PermissionPromotionsWrite    Permission = "promotions:write"     // 维护优惠活动
+	PermissionMemberLevelsWrite  Permission = "member_levels:write"  // 维护会员等级
+	PermissionPointsManage       Permission = "points:manage"        // 积分调整、对账与生日积分
+	PermissionOrdersRead         Permission = "orders:read"          // 查看订单与出品队列
+	PermissionOrdersUpdateStatus Permission = "orders:update_status" // 更新订单与出品状态
+	PermissionOrdersDelete       Permission = "orders:delete"        // 删除订单
+	PermissionPaymentsRead       Permission = "payments:read"        // 查看支付记录
+	PermissionStatsRead          Permission = "stats:read"           // 查看经营统计
+	PermissionUsersManage        Permission = "users:manage"         // 管理用户、角色与密码
+)
+
+// allPermissions 全部后台权限
+var allPermissions = []Permission{
+	PermissionMenuWrite,
+	PermissionInventoryRead,
+	PermissionInventoryWrite,
+	PermissionStoresWrite,
+	PermissionStoresPause,
+	PermissionPromotionsWrite,
+	PermissionMemberLevelsWrite,
+	PermissionPointsManage,
+	PermissionOrdersRead,
+	PermissionOrdersUpdateStatus,
+	PermissionOrdersDelete,
+	PermissionPaymentsRead,
+	PermissionStatsRead,
+	PermissionUsersManage,
+}
+
+// rolePermissions 各员工角色拥有的权限，顾客没有后台权限
+var rolePermissions = map[string][]Permission{
+	RoleOwner: allPermissions,
+	RoleAdmin: allPermissions,
+	RoleManager: {
+		PermissionMenuWrite,
+		PermissionInventoryRead,
+		PermissionInventoryWrite,
+		PermissionStoresWrite,
+		PermissionStoresPause,
+		PermissionPromotionsWrite,
+		PermissionOrdersRead,
+		PermissionOrdersUpdateStatus,
+		PermissionOrdersDelete,
+		PermissionPaymentsRead,
+		PermissionStatsRead,
+	},
+	RoleBarista: {
+		PermissionInventoryRead,
+		PermissionOrdersRead,
+		PermissionOrdersUpdateStatus,
+	},
+	RoleCashier: {
+		PermissionStoresPause,
+		PermissionOrdersRead,
+		PermissionOrdersUpdateStatus,
+		PermissionPaymentsRead,
+	},
+}
+
+// IsValidRole 是否为有效的用户角色
+func IsValidRole(role string) bool {
+	if role == RoleUser {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsStaffRole 是否为可登录管理后台的员工角色
+func IsStaffRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// StaffRoles 全部员工角色
+func StaffRoles() []string {
+	return []string{RoleOwner, RoleAdmin, RoleManager, RoleBarista, RoleCashier}
+}
+
+// RolePermissions 角色拥有的权限
+func RolePermissions(role string) []Permission {
+	return rolePermissions[role]
+}
+
+// CanAssignRole 操作人能否授予（或管理持有）指定角色
+// 店主与系统管理员只能由店主授予；其他角色的权限不能超出操作人自身的权限
+func CanAssignRole(operatorRole, role string) bool {
+	if role == RoleOwner || role == RoleAdmin {
+		return operatorRole == RoleOwner
+	}
+	for _, p := range rolePermissions[role] {
+		if !HasPermission(operatorRole, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasPermission 角色是否拥有指定权限
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
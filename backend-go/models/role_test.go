@@ -0,0 +1,55 @@
+package models
+
+import "testing"
+
+func TestCanAssignRole(t *testing.T) {
+	tests := []struct {
+		operator string
+		role     string
+		want     bool
+	}{
+		{RoleOwner, RoleOwner, true},
+		{RoleOwner, RoleAdmin, true},
+		{RoleAdmin, RoleOwner, false},
+		{RoleAdmin, RoleAdmin, false},
+		{RoleAdmin, RoleManager, true},
+		{RoleManager, RoleManager, true},
+		{RoleManager, RoleBarista, true},
+		{RoleManager, RoleCashier, true},
+		{RoleBarista, RoleCashier, false}, // 收银员可暂停接单、查看支付，超出咖啡师权限
+		{RoleCashier, RoleBarista, false}, // 咖啡师可查看库存，超出收银员权限
+		{RoleUser, RoleBarista, false},
+		{RoleManager, RoleUser, true},
+	}
+
+	for _, tt := range tests {
+		if got := CanAssignRole(tt.operator, tt.role); got != tt.want {
+			t.Errorf("CanAssignRole(%s, %s) = %v，期望 %v", tt.operator, tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission Permission
+		want       bool
+	}{
+		{RoleOwner, PermissionUsersManage, true},
+		{RoleAdmin, PermissionPointsManage, true},
+		{RoleManager, PermissionUsersManage, false},
+		{RoleManager, PermissionOrdersDelete, true},
+		{RoleBarista, PermissionOrdersUpdateStatus, true},
+		{RoleBarista, PermissionPaymentsRead, false},
+		{RoleCashier, PermissionStoresPause, true},
+		{RoleCashier, PermissionMenuWrite, false},
+		{RoleUser, PermissionOrdersRead, false},
+		{"unknown", PermissionOrdersRead, false},
+	}
+
+	for _, tt := range tests {
+		if got := HasPermission(tt.role, tt.permission); got != tt.want {
+			t.Errorf("HasPermission(%s, %s) = %v，期望 %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
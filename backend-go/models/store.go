@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+)
+
+// DefaultStoreTimezone 门店默认时区
+const DefaultStoreTimezone = "Asia/Shanghai"
+
+// Store 门店模型
+type Store struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Code      string `gorm:"size:20;not null;uniqueIndex" json:"code"`
+	Name      string `gorm:"size:100;not null" json:"name"`
+	Address   string `gorm:"size:255" json:"address"`
+	Phone     string `gorm:"size:20" json:"phone"`
+	Timezone  string `gorm:"size:50;not null;default:'Asia/Shanghai'" json:"timezone"`
+	OpenTime  string `gorm:"size:5;not null;default:'08:00'" json:"open_time"`  // 营业开始时间 HH:MM（门店时区）
+	CloseTime string `gorm:"size:5;not null;default:'22:00'" json:"close_time"` // 营业结束时间 HH:MM（门店时区）
+	IsActive  bool   `gorm:"not null;index" json:"is_active"`                   // 不设 gorm 默认值，否则创建时 false 会被替换为 true
+
+	// 接单控制
+	LastOrderMinutes int        `gorm:"default:0;not null" json:"last_order_minutes"` // 打烊前停止接单的分钟数
+	MaxActiveOrders  int        `gorm:"default:0;not null" json:"max_active_orders"`  // 出品队列中进行中订单上限，0 表示不限
+	OrderingPaused   bool       `gorm:"default:false;not null" json:"ordering_paused"`
+	PauseReason      string     `gorm:"size:255" json:"pause_reason"`
+	PausedUntil      *time.Time `json:"paused_until"` // 暂停接单截止时间，为空表示需手动恢复
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Store) TableName() string {
+	return "stores"
+}
+
+// Location 门店时区，配置无效时使用默认时区
+func (s *Store) Location() *time.Location {
+	if loc, err := time.LoadLocation(s.Timezone); err == nil {
+		return loc
+	}
+	if loc, err := time.LoadLocation(DefaultStoreTimezone); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+// IsPaused 门店当前是否暂停接单
+func (s *Store) IsPaused(now time.Time) bool {
+	if !s.OrderingPaused {
+		return false
+	}
+	return s.PausedUntil == nil || now.Before(*s.PausedUntil)
+}
+
+// StoreOpeningHour 门店每周营业时间，未配置的星期使用门店默认营业时间
+type StoreOpeningHour struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	StoreID   uint      `gorm:"not null;uniqueIndex:idx_store_weekday" json:"store_id"`
+	Weekday   int       `gorm:"not null;uniqueIndex:idx_store_weekday" json:"weekday"` // 0=周日 ... 6=周六
+	OpenTime  string    `gorm:"size:5;not null" json:"open_time"`
+	CloseTime string    `gorm:"size:5;not null" json:"close_time"`
+	IsClosed  bool      `gorm:"default:false;not null" json:"is_closed"` // 当天休息
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (StoreOpeningHour) TableName() string {
+	return "store_opening_hours"
+}
+
+// StoreClosure 门店停业日期（节假日、装修等），日期为门店时区
+type StoreClosure struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	StoreID   uint      `gorm:"not null;index" json:"store_id"`
+	StartDate string    `gorm:"size:10;not null;index" json:"start_date"` // 格式: 2006-01-02
+	EndDate   string    `gorm:"size:10;not null;index" json:"end_date"`   // 格式: 2006-01-02（含当天）
+	Reason    string    `gorm:"size:255" json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (StoreClosure) TableName() string {
+	return "store_closures"
+}
+
+// StoreMenuItem 门店菜品设置（覆盖菜单的价格与上下架状态）
+type StoreMenuItem struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	StoreID     uint      `gorm:"not null;uniqueIndex:idx_store_menu" json:"store_id"`
+	MenuItemID  uint      `gorm:"not null;uniqueIndex:idx_store_menu;index" json:"menu_item_id"`
+	Price       *float64  `gorm:"type:decimal(10,2)" json:"price"` // 门店价格，为空时使用菜单价格
+	IsAvailable bool      `gorm:"not null" json:"is_available"`    // 不设 gorm 默认值，否则创建时 false 会被替换为 true
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// 关联
+	Store    *Store    `gorm:"foreignKey:StoreID;constraint:OnDelete:CASCADE" json:"-"`
+	MenuItem *MenuItem `gorm:"foreignKey:MenuItemID;constraint:OnDelete:CASCADE" json:"menu_item,omitempty"`
+}
+
+// TableName 指定表名
+func (StoreMenuItem) TableName() string {
+	return "store_menu_items"
+}
+
+// ApplyTo 将门店设置应用到菜品
+func (s *StoreMenuItem) ApplyTo(item *MenuItem) {
+	if s.Price != nil {
+		item.Price = *s.Price
+	}
+	if !s.IsAvailable {
+		item.IsAvailable = false
+	}
+}
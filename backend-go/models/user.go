@@ -11,7 +11,8 @@ type User struct {
 	Email                   string         `gorm:"size:100;not null;uniqueIndex" json:"email"`
 	Password                string         `gorm:"size:255;not null" json:"-"` // 不返回密码
 	Phone                   string         `gorm:"size:20" json:"phone"`
-	Role                    string         `gorm:"size:20;default:'user'" json:"role"` // admin, user
+	Role                    string         `gorm:"size:20;default:'user'" json:"role"` // user, owner, admin, manager, barista, cashier
+	StoreID                 *uint          `gorm:"index" json:"store_id"`              // 管理员所属门店，为空表示可管理全部门店
 	FirstName               string         `gorm:"size:50" json:"first_name"`
 	LastName                string         `gorm:"size:50" json:"last_name"`
 	AvatarURL               string         `gorm:"size:255" json:"avatar_url"`
@@ -23,6 +24,9 @@ type User struct {
 	ResetPasswordToken      string         `gorm:"size:255" json:"-"`
 	ResetPasswordExpiresAt  *time.Time     `json:"-"`
 	IsActive                bool           `gorm:"default:true" json:"is_active"`
+	TokenVersion            int            `gorm:"default:0;not null" json:"-"` // 令牌版本，禁用、改角色、重置密码时递增使已签发令牌失效
+	ReferralCode            *string        `gorm:"size:16;uniqueIndex" json:"referral_code"` // 推荐码，首次使用时生成
+	RegisterDeviceID        string         `gorm:"size:64;index" json:"-"`                   // 注册设备标识（推荐防刷）
 	CreatedAt               time.Time      `json:"created_at"`
 	UpdatedAt               time.Time      `json:"updated_at"`
 
@@ -45,6 +49,8 @@ type UserRegisterRequest struct {
 	Phone     string `json:"phone"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	ReferralCode string `json:"referral_code"` // 推荐人的推荐码
+	DeviceID     string `json:"device_id"`     // 设备标识，未提交时读取 X-Device-ID 请求头
 }
 
 // UserLoginRequest 用户登录请求
@@ -69,6 +75,12 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password" binding:"required,min=8"`
 }
 
+// ResetPasswordRequest 使用重置令牌设置新密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
 // UserResponse 用户响应（不包含敏感信息）
 type UserResponse struct {
 	ID          uint       `json:"id"`
@@ -84,6 +96,7 @@ type UserResponse struct {
 	IsVerified  bool       `json:"is_verified"`
 	IsActive    bool       `json:"is_active"`
 	MemberLevel string     `json:"member_level,omitempty"`
+	ReferralCode string    `json:"referral_code,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 }
 
@@ -116,6 +129,9 @@ func (u *User) ToResponse() UserResponse {
 	if u.UserPoints != nil {
 		resp.MemberLevel = string(u.UserPoints.MemberLevel)
 	}
+	if u.ReferralCode != nil {
+		resp.ReferralCode = *u.ReferralCode
+	}
 
 	return resp
 }
@@ -14,6 +14,30 @@ const (
 	MemberLevelPlatinum MemberLevel = "platinum"
 )
 
+// IsValid 是否为有效的会员等级
+func (l MemberLevel) IsValid() bool {
+	switch l {
+	case MemberLevelBronze, MemberLevelSilver, MemberLevelGold, MemberLevelPlatinum:
+		return true
+	}
+	return false
+}
+
+// Rank 等级高低顺序，青铜最低
+func (l MemberLevel) Rank() int {
+	switch l {
+	case MemberLevelBronze:
+		return 1
+	case MemberLevelSilver:
+		return 2
+	case MemberLevelGold:
+		return 3
+	case MemberLevelPlatinum:
+		return 4
+	}
+	return 0
+}
+
 // UserPoints 用户积分模型
 type UserPoints struct {
 	ID               uint        `gorm:"primaryKey" json:"id"`
@@ -41,6 +65,15 @@ type UserPointsResponse struct {
 	MemberLevel      string                 `json:"member_level"`
 	NextLevel        *NextLevelInfo         `json:"next_level,omitempty"`
 	LevelBenefits    *MemberLevelBenefits   `json:"level_benefits"`
+	ExpiringPoints   []ExpiringPoints       `json:"expiring_points"`         // 提醒期内即将过期的积分，按日期汇总
+	ExpiryNotice     string                 `json:"expiry_notice,omitempty"` // 最近一批过期提醒，如 "120 积分将于 2025-03-31 过期"
+}
+
+// ExpiringPoints 即将过期的积分
+type ExpiringPoints struct {
+	Points    int       `json:"points"`
+	ExpiresOn string    `json:"expires_on"` // 最后可用日期
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // NextLevelInfo 下一等级信息
@@ -1,8 +1,10 @@
 package routes
 
 import (
+	"coffee-ordering-backend/config"
 	"coffee-ordering-backend/handlers"
 	"coffee-ordering-backend/middleware"
+	"coffee-ordering-backend/models"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -32,6 +34,9 @@ func SetupRoutes(r *gin.Engine) {
 		})
 	})
 
+	// 后台接口按操作权限校验，各角色权限见 models.RolePermissions
+	perm := middleware.RequirePermission
+
 	// API 路由组
 	api := r.Group("/api")
 	{
@@ -42,9 +47,19 @@ func SetupRoutes(r *gin.Engine) {
 			auth.POST("/login", handlers.Login)
 			auth.POST("/logout", handlers.Logout)
 			auth.POST("/refresh", handlers.RefreshToken)
+			auth.POST("/reset-password", handlers.ResetPassword)
 			auth.POST("/admin/login", handlers.AdminLogin)
 		}
 
+		// 开发认证（仅 DEV_AUTH_ENABLED 开启时注册，release 模式下服务拒绝启动）
+		if config.AppConfig.DevAuthEnabled {
+			dev := api.Group("/dev")
+			{
+				dev.GET("/personas", handlers.GetDevPersonas)
+				dev.POST("/login", handlers.DevLogin)
+			}
+		}
+
 		// 菜单路由（公开）
 		menu := api.Group("/menu")
 		{
@@ -53,6 +68,11 @@ func SetupRoutes(r *gin.Engine) {
 			menu.GET("/:id", handlers.GetMenuItem)
 		}
 
+		// 门店路由（公开）
+		api.GET("/stores", handlers.GetStores)
+		api.GET("/store/status", handlers.GetStoreStatus)
+		api.GET("/store/pickup-slots", handlers.GetPickupSlots)
+
 		// 订单路由（支持可选认证）
 		orders := api.Group("/orders")
 		orders.Use(middleware.OptionalUserAuth())
@@ -60,9 +80,32 @@ func SetupRoutes(r *gin.Engine) {
 			orders.POST("", handlers.CreateOrder)
 			orders.GET("/:id", handlers.GetOrder)
 			orders.GET("/pickup/:pickup_code", handlers.GetOrderByPickupCode)
+			orders.POST("/:id/payments", handlers.CreateOrderPayment)
+			orders.POST("/:id/payments/:payment_id/confirm", handlers.ConfirmOrderPayment)
 			orders.POST("/points-calculation", middleware.UserAuthRequired(), handlers.CalculatePointsForOrder)
+			orders.POST("/discount-preview", handlers.PreviewOrderDiscounts)
+		}
+
+		// 出品显示（KDS，吧台平板使用）
+		kds := api.Group("/kds")
+		kds.Use(middleware.AdminRequired())
+		{
+			kds.GET("/queue", perm(models.PermissionOrdersRead), handlers.GetKDSQueue)
+			kds.POST("/orders/:id/bump", perm(models.PermissionOrdersUpdateStatus), handlers.BumpKDSOrder)
+			kds.POST("/orders/:id/items/:item_id/bump", perm(models.PermissionOrdersUpdateStatus), handlers.BumpKDSOrderItem)
 		}
 
+		// 订单事件推送（SSE）
+		stream := api.Group("/stream")
+		{
+			stream.GET("/orders/:id", middleware.TokenFromQuery(), middleware.UserAuthRequired(), handlers.StreamOrder)
+			stream.GET("/pickup/:pickup_code", handlers.StreamOrderByPickupCode)
+			stream.GET("/admin/orders", middleware.TokenFromQuery(), middleware.AdminRequired(), perm(models.PermissionOrdersRead), handlers.StreamAdminOrders)
+		}
+
+		// 支付渠道回调（公开，由渠道签名校验）
+		api.POST("/payments/webhook/:provider", handlers.PaymentWebhook)
+
 		// 用户路由（需要认证）
 		user := api.Group("/user")
 		user.Use(middleware.UserAuthRequired())
@@ -77,29 +120,112 @@ func SetupRoutes(r *gin.Engine) {
 			// 积分相关
 			user.GET("/points", handlers.GetUserPoints)
 			user.GET("/points/transactions", handlers.GetPointTransactions)
+			user.GET("/referrals", handlers.GetUserReferrals)
 		}
 
-		// 管理员路由（需要认证）
+		// 管理后台路由（员工登录，按接口校验权限）
 		admin := api.Group("/admin")
 		admin.Use(middleware.AdminRequired())
 		{
 			// 菜单管理
 			adminMenu := admin.Group("/menu")
 			{
-				adminMenu.GET("", handlers.GetAllMenuItemsAdmin)
-				adminMenu.POST("", handlers.CreateMenuItem)
-				adminMenu.PUT("/:id", handlers.UpdateMenuItem)
-				adminMenu.DELETE("/:id", handlers.DeleteMenuItem)
-				adminMenu.PATCH("/:id/toggle", handlers.ToggleMenuItemAvailability)
+				adminMenu.GET("", perm(models.PermissionMenuWrite), handlers.GetAllMenuItemsAdmin)
+				adminMenu.POST("", perm(models.PermissionMenuWrite), handlers.CreateMenuItem)
+				adminMenu.PUT("/:id", perm(models.PermissionMenuWrite), handlers.UpdateMenuItem)
+				adminMenu.DELETE("/:id", perm(models.PermissionMenuWrite), handlers.DeleteMenuItem)
+				adminMenu.PATCH("/:id/toggle", perm(models.PermissionMenuWrite), handlers.ToggleMenuItemAvailability)
+
+				// 定制选项
+				adminMenu.GET("/:id/options", perm(models.PermissionMenuWrite), handlers.GetMenuItemOptions)
+				adminMenu.POST("/:id/options", perm(models.PermissionMenuWrite), handlers.CreateMenuItemOptionGroup)
+				adminMenu.PUT("/:id/options/:group_id", perm(models.PermissionMenuWrite), handlers.UpdateMenuItemOptionGroup)
+				adminMenu.DELETE("/:id/options/:group_id", perm(models.PermissionMenuWrite), handlers.DeleteMenuItemOptionGroup)
+
+				// 配方
+				adminMenu.GET("/:id/recipe", perm(models.PermissionInventoryRead), handlers.GetMenuItemRecipe)
+				adminMenu.PUT("/:id/recipe", perm(models.PermissionMenuWrite), handlers.UpdateMenuItemRecipe)
+			}
+
+			// 库存管理
+			adminInventory := admin.Group("/inventory")
+			{
+				adminInventory.GET("/ingredients", perm(models.PermissionInventoryRead), handlers.GetIngredients)
+				adminInventory.POST("/ingredients", perm(models.PermissionInventoryWrite), handlers.CreateIngredient)
+				adminInventory.PUT("/ingredients/:id", perm(models.PermissionInventoryWrite), handlers.UpdateIngredient)
+				adminInventory.PUT("/ingredients/:id/threshold", perm(models.PermissionInventoryWrite), handlers.UpdateIngredientThreshold)
+				adminInventory.POST("/ingredients/:id/stock-in", perm(models.PermissionInventoryWrite), handlers.StockInIngredient)
+				adminInventory.POST("/ingredients/:id/stocktake", perm(models.PermissionInventoryWrite), handlers.StocktakeIngredient)
+				adminInventory.GET("/ingredients/:id/movements", perm(models.PermissionInventoryRead), handlers.GetIngredientMovements)
+			}
+
+			// 会员积分管理
+			adminPoints := admin.Group("/points")
+			{
+				adminPoints.POST("/birthday-bonus/run", perm(models.PermissionPointsManage), handlers.RunBirthdayBonus)
+				adminPoints.POST("/reconcile", perm(models.PermissionPointsManage), handlers.ReconcilePoints)
+				adminPoints.GET("/adjustments", perm(models.PermissionPointsManage), handlers.GetPointsAdjustments)
+				adminPoints.POST("/adjustments/:id/approve", perm(models.PermissionPointsManage), handlers.ApprovePointsAdjustment)
+				adminPoints.POST("/adjustments/:id/reject", perm(models.PermissionPointsManage), handlers.RejectPointsAdjustment)
+			}
+
+			// 用户管理
+			adminUsers := admin.Group("/users")
+			{
+				adminUsers.GET("", perm(models.PermissionUsersManage), handlers.GetAdminUsers)
+				adminUsers.GET("/:id", perm(models.PermissionUsersManage), handlers.GetAdminUserDetail)
+				adminUsers.PUT("/:id/status", perm(models.PermissionUsersManage), handlers.UpdateUserStatus)
+				adminUsers.PUT("/:id/role", perm(models.PermissionUsersManage), handlers.UpdateUserRole)
+				adminUsers.POST("/:id/reset-password", perm(models.PermissionUsersManage), handlers.ForceResetUserPassword)
+				adminUsers.POST("/:id/points/adjust", perm(models.PermissionPointsManage), handlers.AdjustUserPoints)
+			}
+
+			// 会员等级配置
+			adminMemberLevels := admin.Group("/member-levels")
+			{
+				adminMemberLevels.GET("", perm(models.PermissionMemberLevelsWrite), handlers.GetMemberLevels)
+				adminMemberLevels.POST("", perm(models.PermissionMemberLevelsWrite), handlers.CreateMemberLevel)
+				adminMemberLevels.PUT("/:id", perm(models.PermissionMemberLevelsWrite), handlers.UpdateMemberLevel)
+				adminMemberLevels.DELETE("/:id", perm(models.PermissionMemberLevelsWrite), handlers.DeleteMemberLevel)
+			}
+
+			// 优惠活动管理
+			adminPromotions := admin.Group("/promotions")
+			{
+				adminPromotions.GET("", perm(models.PermissionPromotionsWrite), handlers.GetPromotions)
+				adminPromotions.POST("", perm(models.PermissionPromotionsWrite), handlers.CreatePromotion)
+				adminPromotions.PUT("/:id", perm(models.PermissionPromotionsWrite), handlers.UpdatePromotion)
+				adminPromotions.DELETE("/:id", perm(models.PermissionPromotionsWrite), handlers.DeletePromotion)
+			}
+
+			// 门店管理
+			adminStores := admin.Group("/stores")
+			{
+				adminStores.GET("", perm(models.PermissionOrdersRead), handlers.GetAllStoresAdmin)
+				adminStores.POST("", perm(models.PermissionStoresWrite), handlers.CreateStore)
+				adminStores.PUT("/:id", perm(models.PermissionStoresWrite), handlers.UpdateStore)
+				adminStores.GET("/:id/menu", perm(models.PermissionStoresWrite), handlers.GetStoreMenuItems)
+				adminStores.PUT("/:id/menu/:menu_id", perm(models.PermissionStoresWrite), handlers.UpdateStoreMenuItem)
+				adminStores.DELETE("/:id/menu/:menu_id", perm(models.PermissionStoresWrite), handlers.DeleteStoreMenuItem)
+				adminStores.GET("/:id/hours", perm(models.PermissionStoresWrite), handlers.GetStoreHours)
+				adminStores.PUT("/:id/hours", perm(models.PermissionStoresWrite), handlers.UpdateStoreHours)
+				adminStores.GET("/:id/closures", perm(models.PermissionStoresWrite), handlers.GetStoreClosures)
+				adminStores.POST("/:id/closures", perm(models.PermissionStoresWrite), handlers.CreateStoreClosure)
+				adminStores.DELETE("/:id/closures/:closure_id", perm(models.PermissionStoresWrite), handlers.DeleteStoreClosure)
+				adminStores.POST("/:id/pause", perm(models.PermissionStoresPause), handlers.PauseStoreOrdering)
+				adminStores.POST("/:id/resume", perm(models.PermissionStoresPause), handlers.ResumeStoreOrdering)
 			}
 
 			// 订单管理
 			adminOrders := admin.Group("/orders")
 			{
-				adminOrders.GET("", handlers.GetAllOrders)
-				adminOrders.PUT("/:id/status", handlers.UpdateOrderStatus)
-				adminOrders.DELETE("/:id", handlers.DeleteOrder)
-				adminOrders.GET("/statistics", handlers.GetOrderStatistics)
+				adminOrders.GET("", perm(models.PermissionOrdersRead), handlers.GetAllOrders)
+				adminOrders.PUT("/:id/status", perm(models.PermissionOrdersUpdateStatus), handlers.UpdateOrderStatus)
+				adminOrders.GET("/:id/history", perm(models.PermissionOrdersRead), handlers.GetOrderStatusHistory)
+				adminOrders.PUT("/:id/items/:item_id/status", perm(models.PermissionOrdersUpdateStatus), handlers.UpdateOrderItemStatus)
+				adminOrders.GET("/:id/payments", perm(models.PermissionPaymentsRead), handlers.GetOrderPayments)
+				adminOrders.DELETE("/:id", perm(models.PermissionOrdersDelete), handlers.DeleteOrder)
+				adminOrders.GET("/statistics", perm(models.PermissionStatsRead), handlers.GetOrderStatistics)
 			}
 		}
 	}
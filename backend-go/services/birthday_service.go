@@ -0,0 +1,181 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/models"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BirthdayBonusCandidate 生日积分发放对象
+type BirthdayBonusCandidate struct {
+	UserID         uint               `json:"user_id"`
+	Username       string             `json:"username"`
+	MemberLevel    models.MemberLevel `json:"member_level"`
+	Multiplier     float64            `json:"multiplier"`
+	Points         int                `json:"points"`
+	AlreadyGranted bool               `json:"already_granted"`
+}
+
+// BirthdayBonusResult 生日积分发放结果
+type BirthdayBonusResult struct {
+	Date       string                   `json:"date"`
+	DryRun     bool                     `json:"dry_run"`
+	Candidates []BirthdayBonusCandidate `json:"candidates"`
+	Granted    int                      `json:"granted"`
+	Skipped    int                      `json:"skipped"` // 当年已发放
+}
+
+// BirthdayService 生日积分服务
+type BirthdayService struct{}
+
+// birthdayBasePoints 生日积分基数
+func birthdayBasePoints() int {
+	if config.AppConfig != nil && config.AppConfig.BirthdayBonusBasePoints > 0 {
+		return config.AppConfig.BirthdayBonusBasePoints
+	}
+	return 100
+}
+
+// Run 为指定日期生日的会员发放生日积分，每位会员每年只发放一次，可重复执行
+// 2月29日生日的会员在平年于2月28日发放；dryRun 为 true 时只计算不发放
+func (s *BirthdayService) Run(db *gorm.DB, day time.Time, dryRun bool) (*BirthdayBonusResult, error) {
+	result := &BirthdayBonusResult{
+		Date:       day.Format("2006-01-02"),
+		DryRun:     dryRun,
+		Candidates: make([]BirthdayBonusCandidate, 0),
+	}
+
+	users, err := s.birthdayUsers(db, day)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return result, nil
+	}
+
+	var levels []models.MemberLevelConfig
+	if err := db.Where("is_active = ?", true).Find(&levels).Error; err != nil {
+		return nil, err
+	}
+	multipliers := make(map[models.MemberLevel]float64, len(levels))
+	for _, level := range levels {
+		multipliers[level.LevelName] = level.BirthdayMultiplier
+	}
+
+	year := day.Year()
+	base := birthdayBasePoints()
+	for _, user := range users {
+		if user.UserPoints == nil {
+			continue
+		}
+
+		level := user.UserPoints.MemberLevel
+		multiplier, ok := multipliers[level]
+		if !ok || multiplier <= 0 {
+			multiplier = 1
+		}
+		candidate := BirthdayBonusCandidate{
+			UserID:      user.ID,
+			Username:    user.Username,
+			MemberLevel: level,
+			Multiplier:  multiplier,
+			Points:      int(math.Round(float64(base) * multiplier)),
+		}
+
+		var granted int64
+		if err := db.Model(&models.BirthdayBonusGrant{}).Where("user_id = ? AND year = ?", user.ID, year).Count(&granted).Error; err != nil {
+			return nil, err
+		}
+		candidate.AlreadyGranted = granted > 0
+
+		if candidate.AlreadyGranted {
+			result.Skipped++
+		} else if !dryRun {
+			ok, err := s.grant(db, &candidate, year)
+			if err != nil {
+				return result, err
+			}
+			if ok {
+				result.Granted++
+			} else {
+				candidate.AlreadyGranted = true
+				result.Skipped++
+			}
+		}
+		result.Candidates = append(result.Candidates, candidate)
+	}
+
+	return result, nil
+}
+
+// grant 发放单个会员的生日积分，发放记录与积分变动在同一事务内写入
+// 并发执行时以发放记录的唯一索引为准，未写入记录的一方不发放
+func (s *BirthdayService) grant(db *gorm.DB, candidate *BirthdayBonusCandidate, year int) (bool, error) {
+	granted := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		record := models.BirthdayBonusGrant{
+			UserID:      candidate.UserID,
+			Year:        year,
+			MemberLevel: candidate.MemberLevel,
+			Multiplier:  candidate.Multiplier,
+			Points:      candidate.Points,
+		}
+		insert := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if insert.Error != nil {
+			return insert.Error
+		}
+		if insert.RowsAffected == 0 {
+			return nil
+		}
+
+		description := fmt.Sprintf("%d年生日积分（%s × %.1f倍）", year, candidate.MemberLevel, candidate.Multiplier)
+		if err := NewPointsService().EarnPoints(tx, candidate.UserID, candidate.Points, nil, models.TransactionTypeBirthdayBonus, description); err != nil {
+			return err
+		}
+		granted = true
+		return nil
+	})
+	return granted, err
+}
+
+// birthdayUsers 查询指定日期生日的有效会员
+func (s *BirthdayService) birthdayUsers(db *gorm.DB, day time.Time) ([]models.User, error) {
+	month, date := int(day.Month()), day.Day()
+
+	query := db.Where("role = ? AND is_active = ? AND birth_date IS NOT NULL", "user", true)
+	if isLastDayOfFebInCommonYear(day) {
+		query = query.Where("MONTH(birth_date) = 2 AND DAY(birth_date) IN (28, 29)")
+	} else {
+		query = query.Where("MONTH(birth_date) = ? AND DAY(birth_date) = ?", month, date)
+	}
+
+	var users []models.User
+	if err := query.Preload("UserPoints").Order("id ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// isLastDayOfFebInCommonYear 是否为平年的2月28日
+func isLastDayOfFebInCommonYear(day time.Time) bool {
+	if day.Month() != time.February || day.Day() != 28 {
+		return false
+	}
+	return day.AddDate(0, 0, 1).Month() == time.March
+}
+
+// BirthdayBonusJob 定时任务：发放当天的生日积分
+func BirthdayBonusJob(db *gorm.DB, now time.Time) error {
+	_, err := NewBirthdayService().Run(db, now, false)
+	return err
+}
+
+// NewBirthdayService 创建生日积分服务实例
+func NewBirthdayService() *BirthdayService {
+	return &BirthdayService{}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/utils"
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownPersona 开发角色不存在
+	ErrUnknownPersona = errors.New("开发角色不存在")
+)
+
+// DevPersona 开发认证模式下的预置角色账号
+type DevPersona struct {
+	Name        string `json:"name"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	Role        string `json:"role"`
+	Description string `json:"description"`
+}
+
+// devPersonas 预置角色账号，覆盖全部员工角色和一位顾客
+var devPersonas = []DevPersona{
+	{Name: "owner", Username: "dev-owner", Email: "dev-owner@dev.local", Role: models.RoleOwner, Description: "店主，拥有全部权限"},
+	{Name: "manager", Username: "dev-manager", Email: "dev-manager@dev.local", Role: models.RoleManager, Description: "店长"},
+	{Name: "barista", Username: "dev-barista", Email: "dev-barista@dev.local", Role: models.RoleBarista, Description: "咖啡师"},
+	{Name: "cashier", Username: "dev-cashier", Email: "dev-cashier@dev.local", Role: models.RoleCashier, Description: "收银员"},
+	{Name: "customer", Username: "dev-customer", Email: "dev-customer@dev.local", Role: models.RoleUser, Description: "顾客"},
+}
+
+// DevAuthService 开发认证服务，仅在 DEV_AUTH_ENABLED 开启时使用
+type DevAuthService struct{}
+
+// Personas 全部预置角色
+func (s *DevAuthService) Personas() []DevPersona {
+	return devPersonas
+}
+
+// SeedPersonas 创建缺失的预置角色账号，已存在的账号保持不变
+// 账号密码为随机值，只能通过开发登录接口获取令牌
+func (s *DevAuthService) SeedPersonas(db *gorm.DB) error {
+	for _, persona := range devPersonas {
+		var count int64
+		if err := db.Model(&models.User{}).Where("username = ?", persona.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		placeholder, err := randomHex(32)
+		if err != nil {
+			return err
+		}
+		hashed, err := utils.HashPassword(placeholder)
+		if err != nil {
+			return err
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			user := models.User{
+				Username:   persona.Username,
+				Email:      persona.Email,
+				Password:   hashed,
+				Role:       persona.Role,
+				FirstName:  persona.Description,
+				IsVerified: true,
+				IsActive:   true,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+
+			// 注册触发器可能已创建积分账户
+			var accounts int64
+			if err := tx.Model(&models.UserPoints{}).Where("user_id = ?", user.ID).Count(&accounts).Error; err != nil {
+				return err
+			}
+			if accounts > 0 {
+				return nil
+			}
+			return tx.Create(&models.UserPoints{
+				UserID:      user.ID,
+				MemberLevel: models.MemberLevelBronze,
+			}).Error
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("已创建开发角色账号 %s（%s）", persona.Username, persona.Role)
+	}
+	return nil
+}
+
+// Login 为预置角色签发正式令牌，账号须存在且处于启用状态
+func (s *DevAuthService) Login(db *gorm.DB, name string) (*models.User, string, error) {
+	var persona *DevPersona
+	for i := range devPersonas {
+		if devPersonas[i].Name == name {
+			persona = &devPersonas[i]
+			break
+		}
+	}
+	if persona == nil {
+		return nil, "", ErrUnknownPersona
+	}
+
+	var user models.User
+	if err := db.Preload("UserPoints").Where("username = ? AND role = ?", persona.Username, persona.Role).First(&user).Error; err != nil {
+		return nil, "", ErrUnknownPersona
+	}
+	if !user.IsActive {
+		return nil, "", errors.New("账户已被禁用")
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Username, user.Email, user.Role, user.StoreID, user.TokenVersion)
+	if err != nil {
+		return nil, "", err
+	}
+	return &user, token, nil
+}
+
+// NewDevAuthService 创建开发认证服务实例
+func NewDevAuthService() *DevAuthService {
+	return &DevAuthService{}
+}
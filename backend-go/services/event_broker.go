@@ -0,0 +1,155 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"sync"
+	"time"
+)
+
+// 订单事件类型
+const (
+	OrderEventCreated       = "order.created"
+	OrderEventStatusChanged = "order.status_changed"
+	OrderEventPaymentUpdate = "order.payment_updated"
+	OrderEventItemUpdated   = "order.item_updated" // 单品状态变更，事件中的订单状态为汇总后的状态
+	OrderEventReleased      = "order.released"     // 预约订单到达出品准备时间，进入出品队列
+	OrderEventSnapshot      = "order.snapshot"     // 订阅建立时推送的当前状态
+)
+
+// subscriberBuffer 每个订阅者的事件缓冲，消费过慢时丢弃新事件而不阻塞发布方
+const subscriberBuffer = 32
+
+// OrderEvent 订单推送事件
+type OrderEvent struct {
+	Type          string                    `json:"type"`
+	OrderID       uint                      `json:"order_id"`
+	StoreID       uint                      `json:"store_id"`
+	OrderNumber   string                    `json:"order_number"`
+	PickupCode    string                    `json:"pickup_code"`
+	Status        models.OrderStatus        `json:"status"`
+	PaymentStatus models.OrderPaymentStatus `json:"payment_status"`
+	UserID        *uint                     `json:"-"`
+	OccurredAt    time.Time                 `json:"occurred_at"`
+}
+
+// EventFilter 订阅过滤条件，返回 true 的事件才会投递
+type EventFilter func(event OrderEvent) bool
+
+// Subscription 事件订阅
+type Subscription interface {
+	// Events 事件通道，订阅关闭后通道随之关闭
+	Events() <-chan OrderEvent
+	// Close 取消订阅
+	Close()
+}
+
+// EventBroker 事件代理接口
+// 默认为进程内实现，多实例部署时可替换为基于消息队列的实现
+type EventBroker interface {
+	Publish(event OrderEvent)
+	Subscribe(filter EventFilter) Subscription
+}
+
+var (
+	eventBrokerMu sync.RWMutex
+	eventBroker   EventBroker = NewMemoryEventBroker()
+)
+
+// SetEventBroker 替换全局事件代理
+func SetEventBroker(broker EventBroker) {
+	eventBrokerMu.Lock()
+	defer eventBrokerMu.Unlock()
+	eventBroker = broker
+}
+
+// GetEventBroker 获取全局事件代理
+func GetEventBroker() EventBroker {
+	eventBrokerMu.RLock()
+	defer eventBrokerMu.RUnlock()
+	return eventBroker
+}
+
+// NewOrderEvent 由订单构建事件
+func NewOrderEvent(eventType string, order *models.Order) OrderEvent {
+	return OrderEvent{
+		Type:          eventType,
+		OrderID:       order.ID,
+		StoreID:       order.StoreID,
+		OrderNumber:   order.OrderNumber,
+		PickupCode:    order.PickupCode,
+		Status:        order.Status,
+		PaymentStatus: order.PaymentStatus,
+		UserID:        order.UserID,
+		OccurredAt:    time.Now(),
+	}
+}
+
+// PublishOrderEvent 发布订单事件，应在事务提交后调用
+func PublishOrderEvent(eventType string, order *models.Order) {
+	GetEventBroker().Publish(NewOrderEvent(eventType, order))
+}
+
+// MemoryEventBroker 进程内事件代理
+type MemoryEventBroker struct {
+	mu          sync.RWMutex
+	subscribers map[*memorySubscription]struct{}
+}
+
+// memorySubscription 进程内订阅
+type memorySubscription struct {
+	broker *MemoryEventBroker
+	filter EventFilter
+	events chan OrderEvent
+	once   sync.Once
+}
+
+// Publish 向所有匹配的订阅者投递事件
+func (b *MemoryEventBroker) Publish(event OrderEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe 创建订阅
+func (b *MemoryEventBroker) Subscribe(filter EventFilter) Subscription {
+	sub := &memorySubscription{
+		broker: b,
+		filter: filter,
+		events: make(chan OrderEvent, subscriberBuffer),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Events 事件通道
+func (s *memorySubscription) Events() <-chan OrderEvent {
+	return s.events
+}
+
+// Close 取消订阅并关闭通道
+func (s *memorySubscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subscribers, s)
+		s.broker.mu.Unlock()
+		close(s.events)
+	})
+}
+
+// NewMemoryEventBroker 创建进程内事件代理实例
+func NewMemoryEventBroker() *MemoryEventBroker {
+	return &MemoryEventBroker{subscribers: make(map[*memorySubscription]struct{})}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// seedMemberLevels 写入默认的四个会员等级
+func seedMemberLevels(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	levels := []models.MemberLevelConfig{
+		{LevelName: models.MemberLevelBronze, LevelDisplayName: "铜牌会员", MinPoints: 0, PointsEarningRate: 1, MaxDiscountPercentage: 20, BirthdayMultiplier: 1, IsActive: true},
+		{LevelName: models.MemberLevelSilver, LevelDisplayName: "银牌会员", MinPoints: 1000, PointsEarningRate: 1.2, MaxDiscountPercentage: 25, BirthdayMultiplier: 2, IsActive: true},
+		{LevelName: models.MemberLevelGold, LevelDisplayName: "金牌会员", MinPoints: 5000, PointsEarningRate: 1.5, MaxDiscountPercentage: 30, BirthdayMultiplier: 3, IsActive: true},
+		{LevelName: models.MemberLevelPlatinum, LevelDisplayName: "白金会员", MinPoints: 10000, PointsEarningRate: 2, MaxDiscountPercentage: 40, BirthdayMultiplier: 5, IsActive: true},
+	}
+	if err := db.Create(&levels).Error; err != nil {
+		t.Fatalf("写入会员等级失败: %v", err)
+	}
+}
+
+// createMember 创建会员及其积分账户
+func createMember(t *testing.T, db *gorm.DB, name string) *models.User {
+	t.Helper()
+	user := models.User{Username: name, Email: name + "@example.com", Password: "x", Role: "user", IsActive: true}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	points := models.UserPoints{UserID: user.ID, MemberLevel: models.MemberLevelBronze}
+	if err := db.Create(&points).Error; err != nil {
+		t.Fatalf("创建积分账户失败: %v", err)
+	}
+	return &user
+}
+
+// createStore 创建使用 UTC 时区的门店
+func createStore(t *testing.T, db *gorm.DB) *models.Store {
+	t.Helper()
+	store := models.Store{Code: "S1", Name: "测试门店", Timezone: "UTC", OpenTime: "08:00", CloseTime: "22:00", IsActive: true}
+	if err := db.Create(&store).Error; err != nil {
+		t.Fatalf("创建门店失败: %v", err)
+	}
+	return &store
+}
+
+// createOrder 创建指定状态的订单
+func createOrder(t *testing.T, db *gorm.DB, store *models.Store, userID *uint, status models.OrderStatus, amount float64) *models.Order {
+	t.Helper()
+	var count int64
+	db.Model(&models.Order{}).Count(&count)
+	order := models.Order{
+		UserID:        userID,
+		StoreID:       store.ID,
+		OrderNumber:   models.FormatOrderNumber(time.Now(), store.ID, int(count)+1),
+		PickupCode:    models.FormatPickupCode(int(count)),
+		Status:        status,
+		PaymentAmount: amount,
+		PaymentStatus: models.OrderPaymentUnpaid,
+	}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("创建订单失败: %v", err)
+	}
+	return &order
+}
+
+// pointsOf 查询会员可用积分与累计积分
+func pointsOf(t *testing.T, db *gorm.DB, userID uint) models.UserPoints {
+	t.Helper()
+	var points models.UserPoints
+	if err := db.Where("user_id = ?", userID).First(&points).Error; err != nil {
+		t.Fatalf("查询积分账户失败: %v", err)
+	}
+	return points
+}
+
+// updateStatus 在事务内变更订单状态
+func updateStatus(t *testing.T, db *gorm.DB, orderID uint, status models.OrderStatus) *models.Order {
+	t.Helper()
+	var order *models.Order
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = NewOrderService().UpdateStatus(tx, orderID, status, SystemActor, fmt.Sprintf("测试变更为 %s", status))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("订单状态变更为 %s 失败: %v", status, err)
+	}
+	return order
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrIdempotencyKeyConflict 幂等键已用于不同的请求
+	ErrIdempotencyKeyConflict = errors.New("幂等键已被用于不同的请求")
+	// ErrIdempotencyKeyInvalid 幂等键格式无效
+	ErrIdempotencyKeyInvalid = errors.New("幂等键长度必须在16-100之间")
+)
+
+const (
+	// idempotencyKeyMinLength 幂等键最小长度，避免简单键被猜中（建议使用 UUID）
+	idempotencyKeyMinLength = 16
+	// idempotencyKeyMaxLength 幂等键最大长度
+	idempotencyKeyMaxLength = 100
+)
+
+// IdempotencyService 幂等键服务
+type IdempotencyService struct{}
+
+// guestIdempotencyScope 游客幂等键作用域
+const guestIdempotencyScope = "guest"
+
+// Scope 生成幂等键作用域，不同用户的相同键互不影响
+// 游客没有可信的身份，仅按键本身区分：键至少 16 位（建议使用 UUID）且请求内容一致才会回放，
+// 其他游客无法猜中他人的键
+func (s *IdempotencyService) Scope(userID *uint) string {
+	if userID == nil {
+		return guestIdempotencyScope
+	}
+	return fmt.Sprintf("user:%d", *userID)
+}
+
+// ValidateKey 校验幂等键
+func (s *IdempotencyService) ValidateKey(key string) error {
+	if len(key) < idempotencyKeyMinLength || len(key) > idempotencyKeyMaxLength {
+		return ErrIdempotencyKeyInvalid
+	}
+	return nil
+}
+
+// HashRequest 计算请求内容摘要
+func (s *IdempotencyService) HashRequest(request interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Lookup 查找未过期的幂等记录
+// 不存在返回 nil；同一键但请求内容不同返回 ErrIdempotencyKeyConflict；过期记录直接清除
+func (s *IdempotencyService) Lookup(db *gorm.DB, scope, key, requestHash string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := db.Where("scope = ? AND `key` = ?", scope, key).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		if err := db.Delete(&record).Error; err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	if record.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyConflict
+	}
+
+	return &record, nil
+}
+
+// Save 在下单事务内保存幂等记录，并发重复请求由唯一索引拦截
+func (s *IdempotencyService) Save(tx *gorm.DB, scope, key, requestHash string, orderID *uint, statusCode int, response interface{}) error {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	ttlHours := 24
+	if config.AppConfig != nil {
+		ttlHours = config.AppConfig.IdempotencyKeyTTLHours
+	}
+
+	record := models.IdempotencyKey{
+		Scope:        scope,
+		Key:          key,
+		RequestHash:  requestHash,
+		OrderID:      orderID,
+		StatusCode:   statusCode,
+		ResponseBody: string(body),
+		ExpiresAt:    time.Now().Add(time.Duration(ttlHours) * time.Hour),
+	}
+	return tx.Create(&record).Error
+}
+
+// PurgeExpired 清理过期的幂等记录
+func (s *IdempotencyService) PurgeExpired(db *gorm.DB) (int64, error) {
+	result := db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeIdempotencyKeysJob 定时任务：清理过期的幂等记录
+func PurgeIdempotencyKeysJob(db *gorm.DB, now time.Time) error {
+	_, err := NewIdempotencyService().PurgeExpired(db)
+	return err
+}
+
+// NewIdempotencyService 创建幂等键服务实例
+func NewIdempotencyService() *IdempotencyService {
+	return &IdempotencyService{}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"coffee-ordering-backend/testutil"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestIdempotencyLookupByScope(t *testing.T) {
+	const key = "0b6f3c1e-guest-retry"
+	alice, bob := uint(1), uint(2)
+	service := NewIdempotencyService()
+
+	tests := []struct {
+		name       string
+		savedBy    *uint
+		lookupBy   *uint
+		hash       string
+		wantReplay bool
+		wantErr    error
+	}{
+		{name: "游客重试回放首次响应", savedBy: nil, lookupBy: nil, hash: "h1", wantReplay: true},
+		{name: "游客同一键不同请求内容冲突", savedBy: nil, lookupBy: nil, hash: "h2", wantErr: ErrIdempotencyKeyConflict},
+		{name: "登录用户重试回放首次响应", savedBy: &alice, lookupBy: &alice, hash: "h1", wantReplay: true},
+		{name: "其他用户的相同键互不影响", savedBy: &alice, lookupBy: &bob, hash: "h1"},
+		{name: "登录用户与游客的相同键互不影响", savedBy: &alice, lookupBy: nil, hash: "h1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			orderID := uint(10)
+			if err := service.Save(db, service.Scope(tt.savedBy), key, "h1", &orderID, http.StatusCreated, map[string]string{"order_number": "A001"}); err != nil {
+				t.Fatalf("保存幂等记录失败: %v", err)
+			}
+
+			record, err := service.Lookup(db, service.Scope(tt.lookupBy), key, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("查找幂等记录返回 %v，期望 %v", err, tt.wantErr)
+			}
+			if (record != nil) != tt.wantReplay {
+				t.Fatalf("查找到记录 = %v，期望 %v", record != nil, tt.wantReplay)
+			}
+			if record != nil && (record.OrderID == nil || *record.OrderID != orderID || record.StatusCode != http.StatusCreated) {
+				t.Fatalf("回放记录 = %+v，期望首次下单的响应", record)
+			}
+		})
+	}
+}
+
+func TestIdempotencyValidateKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "short", wantErr: true},
+		{key: "123456789012345", wantErr: true},
+		{key: "1234567890123456"},
+		{key: "9a1c5e0e-3d4b-4f7a-9a51-2f0f6c1d8b7e"},
+	}
+
+	for _, tt := range tests {
+		if err := NewIdempotencyService().ValidateKey(tt.key); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateKey(%q) = %v，期望出错 = %v", tt.key, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrIngredientNotFound 原料不存在
+	ErrIngredientNotFound = errors.New("原料不存在")
+	// ErrInsufficientStock 原料库存不足
+	ErrInsufficientStock = errors.New("原料库存不足")
+)
+
+func init() {
+	RegisterOrderStatusHook(models.OrderStatusCancelled, restoreOrderStock)
+}
+
+// StockLine 扣减库存的菜品行
+type StockLine struct {
+	MenuItemID uint
+	Quantity   int
+}
+
+// InventoryService 库存服务
+type InventoryService struct{}
+
+// ConsumeForOrder 按配方扣减订单所需原料
+// 原料按ID顺序加锁，库存不足时返回错误；扣减后原料不足一份的菜品自动售罄下架
+func (s *InventoryService) ConsumeForOrder(tx *gorm.DB, orderID uint, lines []StockLine) error {
+	menuIDs := make([]uint, 0, len(lines))
+	for _, line := range lines {
+		menuIDs = append(menuIDs, line.MenuItemID)
+	}
+
+	var recipes []models.RecipeItem
+	if err := tx.Where("menu_item_id IN ?", menuIDs).Find(&recipes).Error; err != nil {
+		return err
+	}
+	if len(recipes) == 0 {
+		return nil
+	}
+
+	recipesByMenu := make(map[uint][]models.RecipeItem)
+	for _, r := range recipes {
+		recipesByMenu[r.MenuItemID] = append(recipesByMenu[r.MenuItemID], r)
+	}
+
+	required := make(map[uint]float64)
+	for _, line := range lines {
+		for _, r := range recipesByMenu[line.MenuItemID] {
+			required[r.IngredientID] += r.Quantity * float64(line.Quantity)
+		}
+	}
+
+	ingredients, err := s.lockIngredients(tx, required)
+	if err != nil {
+		return err
+	}
+
+	for _, ingredient := range ingredients {
+		need := required[ingredient.ID]
+		if ingredient.Stock < need {
+			return fmt.Errorf("%w: %s", ErrInsufficientStock, ingredient.Name)
+		}
+		if err := s.applyMovement(tx, &ingredient, -need, models.StockMovementOrderConsume, &orderID, nil, "下单扣减"); err != nil {
+			return err
+		}
+	}
+
+	return s.SyncAvailability(tx, sortedIngredientIDs(required))
+}
+
+// RestoreForOrder 恢复订单已扣减的原料（每个订单仅恢复一次）
+func (s *InventoryService) RestoreForOrder(tx *gorm.DB, orderID uint) error {
+	var restored int64
+	if err := tx.Model(&models.StockMovement{}).
+		Where("order_id = ? AND movement_type = ?", orderID, models.StockMovementOrderRestore).
+		Count(&restored).Error; err != nil {
+		return err
+	}
+	if restored > 0 {
+		return nil
+	}
+
+	var consumed []models.StockMovement
+	if err := tx.Where("order_id = ? AND movement_type = ?", orderID, models.StockMovementOrderConsume).
+		Find(&consumed).Error; err != nil {
+		return err
+	}
+	if len(consumed) == 0 {
+		return nil
+	}
+
+	amounts := make(map[uint]float64)
+	for _, m := range consumed {
+		amounts[m.IngredientID] -= m.QuantityChange
+	}
+
+	ingredients, err := s.lockIngredients(tx, amounts)
+	if err != nil {
+		return err
+	}
+
+	for _, ingredient := range ingredients {
+		if err := s.applyMovement(tx, &ingredient, amounts[ingredient.ID], models.StockMovementOrderRestore, &orderID, nil, "取消订单恢复"); err != nil {
+			return err
+		}
+	}
+
+	return s.SyncAvailability(tx, sortedIngredientIDs(amounts))
+}
+
+// StockIn 原料入库
+func (s *InventoryService) StockIn(tx *gorm.DB, ingredientID uint, quantity float64, operatorID *uint, note string) (*models.Ingredient, error) {
+	ingredient, err := s.lockIngredient(tx, ingredientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyMovement(tx, ingredient, quantity, models.StockMovementStockIn, nil, operatorID, note); err != nil {
+		return nil, err
+	}
+
+	if err := s.SyncAvailability(tx, []uint{ingredientID}); err != nil {
+		return nil, err
+	}
+	return ingredient, nil
+}
+
+// Stocktake 盘点调整，将库存修正为实际盘点数量
+func (s *InventoryService) Stocktake(tx *gorm.DB, ingredientID uint, actualStock float64, operatorID *uint, note string) (*models.Ingredient, error) {
+	ingredient, err := s.lockIngredient(tx, ingredientID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := actualStock - ingredient.Stock
+	if err := s.applyMovement(tx, ingredient, diff, models.StockMovementStocktake, nil, operatorID, note); err != nil {
+		return nil, err
+	}
+
+	if err := s.SyncAvailability(tx, []uint{ingredientID}); err != nil {
+		return nil, err
+	}
+	return ingredient, nil
+}
+
+// SyncAvailability 根据原料库存同步相关菜品的售罄状态
+// 原料不足一份时自动下架并标记售罄；仅恢复因售罄下架的菜品，不影响手动下架
+func (s *InventoryService) SyncAvailability(tx *gorm.DB, ingredientIDs []uint) error {
+	if len(ingredientIDs) == 0 {
+		return nil
+	}
+
+	var menuIDs []uint
+	if err := tx.Model(&models.RecipeItem{}).
+		Where("ingredient_id IN ?", ingredientIDs).
+		Distinct("menu_item_id").
+		Pluck("menu_item_id", &menuIDs).Error; err != nil {
+		return err
+	}
+
+	return s.SyncMenuItems(tx, menuIDs)
+}
+
+// SyncMenuItems 根据配方原料库存同步指定菜品的售罄状态
+func (s *InventoryService) SyncMenuItems(tx *gorm.DB, menuIDs []uint) error {
+	for _, menuID := range menuIDs {
+		var shortCount int64
+		if err := tx.Table("menu_recipes").
+			Joins("INNER JOIN ingredients ON menu_recipes.ingredient_id = ingredients.id").
+			Where("menu_recipes.menu_item_id = ? AND ingredients.stock < menu_recipes.quantity", menuID).
+			Count(&shortCount).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if shortCount > 0 {
+			err = tx.Model(&models.MenuItem{}).
+				Where("id = ? AND is_available = ?", menuID, true).
+				Updates(map[string]interface{}{"is_available": false, "sold_out": true}).Error
+		} else {
+			err = tx.Model(&models.MenuItem{}).
+				Where("id = ? AND sold_out = ?", menuID, true).
+				Updates(map[string]interface{}{"is_available": true, "sold_out": false}).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockIngredients 按ID顺序加锁原料，避免并发下单死锁
+func (s *InventoryService) lockIngredients(tx *gorm.DB, amounts map[uint]float64) ([]models.Ingredient, error) {
+	ids := sortedIngredientIDs(amounts)
+	var ingredients []models.Ingredient
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).Order("id ASC").Find(&ingredients).Error; err != nil {
+		return nil, err
+	}
+	return ingredients, nil
+}
+
+// lockIngredient 加锁单个原料
+func (s *InventoryService) lockIngredient(tx *gorm.DB, ingredientID uint) (*models.Ingredient, error) {
+	var ingredient models.Ingredient
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ingredient, ingredientID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIngredientNotFound
+		}
+		return nil, err
+	}
+	return &ingredient, nil
+}
+
+// applyMovement 更新库存并写入变动记录
+func (s *InventoryService) applyMovement(tx *gorm.DB, ingredient *models.Ingredient, change float64, movementType models.StockMovementType, orderID, operatorID *uint, note string) error {
+	ingredient.Stock += change
+	if err := tx.Model(ingredient).Update("stock", ingredient.Stock).Error; err != nil {
+		return errors.New("库存更新失败")
+	}
+
+	movement := models.StockMovement{
+		IngredientID:   ingredient.ID,
+		MovementType:   movementType,
+		QuantityChange: change,
+		StockAfter:     ingredient.Stock,
+		OrderID:        orderID,
+		OperatorID:     operatorID,
+		Note:           note,
+	}
+	if err := tx.Create(&movement).Error; err != nil {
+		return errors.New("库存记录创建失败")
+	}
+	return nil
+}
+
+// StockRestored 订单扣减的原料是否已恢复
+func (s *InventoryService) StockRestored(db *gorm.DB, orderID uint) (bool, error) {
+	var restored int64
+	err := db.Model(&models.StockMovement{}).
+		Where("order_id = ? AND movement_type = ?", orderID, models.StockMovementOrderRestore).
+		Count(&restored).Error
+	return restored > 0, err
+}
+
+// restockSettingKey 事务设置中指定取消订单是否恢复库存的键
+const restockSettingKey = "inventory:restock"
+
+// WithRestock 指定本次取消订单是否恢复原料库存，覆盖按取消前状态判断的默认规则
+func WithRestock(tx *gorm.DB, restock bool) *gorm.DB {
+	return tx.Set(restockSettingKey, restock).Session(&gorm.Session{})
+}
+
+// ShouldRestock 取消订单是否恢复原料库存
+// 默认仅在制作前（待处理）取消时恢复，已开始制作、出品或完成的订单原料视为已消耗；
+// restock 非空时以其为准（如制作中取消但尚未用料）
+func ShouldRestock(from models.OrderStatus, restock *bool) bool {
+	if restock != nil {
+		return *restock
+	}
+	return from == models.OrderStatusPending
+}
+
+// restoreOrderStock 订单取消时按 ShouldRestock 的规则恢复原料库存
+func restoreOrderStock(tx *gorm.DB, order *models.Order, from models.OrderStatus) error {
+	var restock *bool
+	if value, ok := tx.Get(restockSettingKey); ok {
+		if v, ok := value.(bool); ok {
+			restock = &v
+		}
+	}
+	if !ShouldRestock(from, restock) {
+		return nil
+	}
+	return NewInventoryService().RestoreForOrder(tx, order.ID)
+}
+
+// sortedIngredientIDs 返回按升序排列的原料ID
+func sortedIngredientIDs(m map[uint]float64) []uint {
+	result := make([]uint, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// NewInventoryService 创建库存服务实例
+func NewInventoryService() *InventoryService {
+	return &InventoryService{}
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"testing"
+)
+
+func TestShouldRestock(t *testing.T) {
+	yes, no := true, false
+
+	cases := []struct {
+		name    string
+		from    models.OrderStatus
+		restock *bool
+		want    bool
+	}{
+		{"待处理取消默认恢复", models.OrderStatusPending, nil, true},
+		{"制作中取消默认不恢复", models.OrderStatusPreparing, nil, false},
+		{"待取餐取消默认不恢复", models.OrderStatusReady, nil, false},
+		{"已完成取消默认不恢复", models.OrderStatusCompleted, nil, false},
+		{"制作中取消指定恢复", models.OrderStatusPreparing, &yes, true},
+		{"已完成取消指定恢复", models.OrderStatusCompleted, &yes, true},
+		{"待处理取消指定不恢复", models.OrderStatusPending, &no, false},
+	}
+
+	for _, tc := range cases {
+		if got := ShouldRestock(tc.from, tc.restock); got != tc.want {
+			t.Errorf("%s: ShouldRestock(%s) = %t, want %t", tc.name, tc.from, got, tc.want)
+		}
+	}
+}
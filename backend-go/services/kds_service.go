@@ -0,0 +1,69 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOrderItemNotFound 订单项不存在
+	ErrOrderItemNotFound = errors.New("订单项不存在")
+	// ErrOrderNotInQueue 订单不在出品队列中
+	ErrOrderNotInQueue = errors.New("订单不在出品队列中")
+)
+
+// KDSService 出品显示（Kitchen Display System）服务
+type KDSService struct{}
+
+// Queue 获取出品队列：已支付且进行中的订单，按进入队列时间先进先出
+// 未到出品准备时间的预约订单不在队列中；storeID 为空时返回全部门店
+func (s *KDSService) Queue(db *gorm.DB, storeID *uint) ([]models.Order, error) {
+	if storeID != nil {
+		db = db.Where("store_id = ?", *storeID)
+	}
+
+	var orders []models.Order
+	err := db.Where("status IN ? AND payment_status = ?", models.ActiveOrderStatuses, models.OrderPaymentPaid).
+		Where("scheduled_pickup_at IS NULL OR released_at IS NOT NULL").
+		Order("COALESCE(GREATEST(paid_at, released_at), paid_at, created_at) ASC, id ASC").
+		Preload("OrderItems", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id ASC")
+		}).
+		Preload("OrderItems.MenuItem").Preload("OrderItems.Options").
+		Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// BumpOrder 将订单推进到出品流程的下一状态
+func (s *KDSService) BumpOrder(tx *gorm.DB, orderID uint, actor StatusActor) (*models.Order, error) {
+	var order models.Order
+	if err := tx.Select("id", "status").First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	next, ok := order.Status.NextInFlow()
+	if !ok {
+		return nil, fmt.Errorf("%w: 当前状态 %s", ErrOrderNotInQueue, order.Status)
+	}
+
+	return NewOrderService().UpdateStatus(tx, orderID, next, actor, "KDS 出品推进")
+}
+
+// BumpItem 标记单品出品完成，订单状态随单品汇总推进
+func (s *KDSService) BumpItem(tx *gorm.DB, orderID, itemID uint, actor StatusActor) (*models.OrderItem, *models.Order, error) {
+	return NewOrderItemService().UpdateStatus(tx, orderID, itemID, models.OrderItemStatusDone, actor)
+}
+
+// NewKDSService 创建出品显示服务实例
+func NewKDSService() *KDSService {
+	return &KDSService{}
+}
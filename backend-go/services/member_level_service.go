@@ -0,0 +1,91 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidMemberLevel 会员等级配置不合法
+	ErrInvalidMemberLevel = errors.New("会员等级配置不合法")
+)
+
+// MemberLevelService 会员等级配置服务
+type MemberLevelService struct{}
+
+// Validate 校验会员等级配置
+// 启用的等级门槛须按青铜、白银、黄金、白金严格递增，避免高等级门槛低于低等级
+func (s *MemberLevelService) Validate(db *gorm.DB, level *models.MemberLevelConfig) error {
+	invalid := func(msg string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidMemberLevel, msg)
+	}
+
+	if !level.LevelName.IsValid() {
+		return invalid("等级标识无效")
+	}
+	if level.LevelDisplayName == "" {
+		return invalid("等级名称不能为空")
+	}
+	if level.MinPoints < 0 {
+		return invalid("升级门槛不能为负数")
+	}
+	if level.PointsEarningRate <= 0 || level.PointsEarningRate >= 10 {
+		return invalid("积分倍率须大于0且小于10")
+	}
+	if level.PointsDiscountRate < 0 || level.PointsDiscountRate >= 10 {
+		return invalid("积分抵扣比例须在0到10之间")
+	}
+	if level.MaxDiscountPercentage < 0 || level.MaxDiscountPercentage > 100 {
+		return invalid("最高抵扣比例须在0到100之间")
+	}
+	if level.BirthdayMultiplier < 0 || level.BirthdayMultiplier >= 1000 {
+		return invalid("生日积分倍数须在0到1000之间")
+	}
+
+	var others []models.MemberLevelConfig
+	if err := db.Where("is_active = ? AND id <> ?", true, level.ID).Find(&others).Error; err != nil {
+		return err
+	}
+	active := others
+	if level.IsActive {
+		active = append(active, *level)
+	}
+	if len(active) == 0 {
+		return invalid("至少保留一个启用的会员等级")
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].LevelName.Rank() < active[j].LevelName.Rank()
+	})
+	for i := 1; i < len(active); i++ {
+		if active[i].MinPoints <= active[i-1].MinPoints {
+			return invalid(fmt.Sprintf("%s 的升级门槛须高于 %s", active[i].LevelName, active[i-1].LevelName))
+		}
+	}
+	return nil
+}
+
+// CanDelete 删除后须至少保留一个启用的会员等级
+func (s *MemberLevelService) CanDelete(db *gorm.DB, level *models.MemberLevelConfig) error {
+	if !level.IsActive {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.MemberLevelConfig{}).
+		Where("is_active = ? AND id <> ?", true, level.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: 至少保留一个启用的会员等级", ErrInvalidMemberLevel)
+	}
+	return nil
+}
+
+// NewMemberLevelService 创建会员等级配置服务实例
+func NewMemberLevelService() *MemberLevelService {
+	return &MemberLevelService{}
+}
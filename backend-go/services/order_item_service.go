@@ -0,0 +1,155 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidOrderItemStatus 无效的订单项状态
+	ErrInvalidOrderItemStatus = errors.New("无效的订单项状态")
+	// ErrIllegalItemStatusTransition 不允许的订单项状态变更
+	ErrIllegalItemStatusTransition = errors.New("不允许的订单项状态变更")
+)
+
+func init() {
+	RegisterOrderStatusHook(models.OrderStatusReady, completeOrderItems)
+}
+
+// OrderItemService 订单项出品服务
+type OrderItemService struct{}
+
+// UpdateStatus 更新单品出品状态，并将订单状态汇总推进
+// 任一单品开始制作时订单进入制作中，全部有效单品完成时订单进入待取餐
+func (s *OrderItemService) UpdateStatus(tx *gorm.DB, orderID, itemID uint, status models.OrderItemStatus, actor StatusActor) (*models.OrderItem, *models.Order, error) {
+	if !status.IsValid() {
+		return nil, nil, ErrInvalidOrderItemStatus
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrOrderNotFound
+		}
+		return nil, nil, err
+	}
+	if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusPreparing {
+		return nil, nil, fmt.Errorf("%w: 当前状态 %s", ErrOrderNotInQueue, order.Status)
+	}
+	if order.PaymentStatus != models.OrderPaymentPaid {
+		return nil, nil, ErrOrderUnpaid
+	}
+
+	var item models.OrderItem
+	if err := tx.Where("id = ? AND order_id = ?", itemID, orderID).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrOrderItemNotFound
+		}
+		return nil, nil, err
+	}
+
+	if item.Status == status {
+		return &item, &order, nil
+	}
+	if !item.Status.CanTransitionTo(status) {
+		return nil, nil, fmt.Errorf("%w: %s -> %s", ErrIllegalItemStatusTransition, item.Status, status)
+	}
+
+	if err := s.applyStatus(tx, &item, status, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	rolled, err := s.rollUp(tx, &order, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &item, rolled, nil
+}
+
+// rollUp 根据单品状态推进订单状态，仅沿正常出品流程前进
+func (s *OrderItemService) rollUp(tx *gorm.DB, order *models.Order, actor StatusActor) (*models.Order, error) {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	started, active, done := false, 0, 0
+	for _, item := range items {
+		switch item.Status {
+		case models.OrderItemStatusMaking:
+			started = true
+			active++
+		case models.OrderItemStatusDone:
+			started = true
+			active++
+			done++
+		case models.OrderItemStatusQueued:
+			active++
+		}
+	}
+
+	target := order.Status
+	switch {
+	case active > 0 && done == active:
+		target = models.OrderStatusReady
+	case started:
+		target = models.OrderStatusPreparing
+	}
+
+	orderService := NewOrderService()
+	for order.Status != target {
+		next, ok := order.Status.NextInFlow()
+		if !ok {
+			break
+		}
+		updated, err := orderService.UpdateStatus(tx, order.ID, next, actor, "单品出品状态汇总")
+		if err != nil {
+			return nil, err
+		}
+		order = updated
+	}
+	return order, nil
+}
+
+// applyStatus 写入单品状态及对应时间
+func (s *OrderItemService) applyStatus(tx *gorm.DB, item *models.OrderItem, status models.OrderItemStatus, now time.Time) error {
+	updates := map[string]interface{}{"status": status}
+	switch status {
+	case models.OrderItemStatusMaking:
+		updates["started_at"] = now
+		item.StartedAt = &now
+	case models.OrderItemStatusDone:
+		updates["done_at"] = now
+		item.DoneAt = &now
+	case models.OrderItemStatusVoided:
+		updates["voided_at"] = now
+		item.VoidedAt = &now
+	}
+
+	if err := tx.Model(item).Updates(updates).Error; err != nil {
+		return errors.New("订单项状态更新失败")
+	}
+	item.Status = status
+	return nil
+}
+
+// completeOrderItems 订单整单出品时，将未完成的单品一并标记完成
+func completeOrderItems(tx *gorm.DB, order *models.Order, from models.OrderStatus) error {
+	now := time.Now()
+	if err := tx.Model(&models.OrderItem{}).
+		Where("order_id = ? AND status IN ?", order.ID, []models.OrderItemStatus{models.OrderItemStatusQueued, models.OrderItemStatusMaking}).
+		Updates(map[string]interface{}{"status": models.OrderItemStatusDone, "done_at": now}).Error; err != nil {
+		return errors.New("订单项状态更新失败")
+	}
+	return nil
+}
+
+// NewOrderItemService 创建订单项出品服务实例
+func NewOrderItemService() *OrderItemService {
+	return &OrderItemService{}
+}
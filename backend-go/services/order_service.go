@@ -0,0 +1,318 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrOrderNotFound 订单不存在
+	ErrOrderNotFound = errors.New("订单不存在")
+	// ErrInvalidOrderStatus 无效的订单状态
+	ErrInvalidOrderStatus = errors.New("无效的订单状态")
+	// ErrIllegalStatusTransition 不允许的订单状态变更
+	ErrIllegalStatusTransition = errors.New("不允许的订单状态变更")
+	// ErrOrderNotDeletable 订单不满足删除条件
+	ErrOrderNotDeletable = errors.New("仅可删除已取消且支付已失败或已全部退款的订单")
+)
+
+// StatusActor 订单状态变更操作人
+type StatusActor struct {
+	UserID *uint
+	Role   string // admin, user, guest, system
+}
+
+// SystemActor 系统自动操作
+var SystemActor = StatusActor{Role: "system"}
+
+// OrderStatusHook 订单状态变更钩子
+// 在状态变更的同一事务内执行，返回错误会导致整个状态变更回滚
+type OrderStatusHook func(tx *gorm.DB, order *models.Order, from models.OrderStatus) error
+
+// orderStatusHooks 按目标状态注册的钩子
+var orderStatusHooks = map[models.OrderStatus][]OrderStatusHook{}
+
+// RegisterOrderStatusHook 注册订单进入指定状态时执行的钩子
+func RegisterOrderStatusHook(status models.OrderStatus, hook OrderStatusHook) {
+	orderStatusHooks[status] = append(orderStatusHooks[status], hook)
+}
+
+// OrderStatusGuard 订单状态变更前置校验
+// 在状态写入前执行，返回错误会拒绝本次变更
+type OrderStatusGuard func(tx *gorm.DB, order *models.Order, to models.OrderStatus) error
+
+// orderStatusGuards 按目标状态注册的前置校验
+var orderStatusGuards = map[models.OrderStatus][]OrderStatusGuard{}
+
+// RegisterOrderStatusGuard 注册订单进入指定状态前执行的校验
+func RegisterOrderStatusGuard(status models.OrderStatus, guard OrderStatusGuard) {
+	orderStatusGuards[status] = append(orderStatusGuards[status], guard)
+}
+
+func init() {
+	RegisterOrderStatusHook(models.OrderStatusCompleted, awardOrderPoints)
+	RegisterOrderStatusHook(models.OrderStatusCancelled, refundOrderPoints)
+	RegisterOrderStatusHook(models.OrderStatusCancelled, reverseOrderPoints)
+}
+
+// OrderService 订单服务
+type OrderService struct{}
+
+// UpdateStatus 按状态机更新订单状态，通过前置校验后记录变更历史并执行对应钩子
+// 订单行在事务内加锁，状态未变化时不执行钩子也不记录历史
+func (s *OrderService) UpdateStatus(tx *gorm.DB, orderID uint, status models.OrderStatus, actor StatusActor, reason string) (*models.Order, error) {
+	if !status.IsValid() {
+		return nil, ErrInvalidOrderStatus
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	from := order.Status
+	if from == status {
+		return &order, nil
+	}
+
+	if !from.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrIllegalStatusTransition, from, status)
+	}
+
+	for _, guard := range orderStatusGuards[status] {
+		if err := guard(tx, &order, status); err != nil {
+			return nil, err
+		}
+	}
+
+	order.Status = status
+	if err := tx.Model(&order).Update("status", status).Error; err != nil {
+		return nil, fmt.Errorf("更新订单状态失败: %w", err)
+	}
+
+	if err := s.recordHistory(tx, order.ID, &from, status, actor, reason); err != nil {
+		return nil, err
+	}
+
+	for _, hook := range orderStatusHooks[status] {
+		if err := hook(tx, &order, from); err != nil {
+			return nil, err
+		}
+	}
+
+	return &order, nil
+}
+
+// RecordCreated 记录订单创建时的初始状态
+func (s *OrderService) RecordCreated(tx *gorm.DB, order *models.Order, actor StatusActor) error {
+	return s.recordHistory(tx, order.ID, nil, order.Status, actor, "创建订单")
+}
+
+// GetStatusHistory 获取订单状态变更历史（按时间正序）
+func (s *OrderService) GetStatusHistory(db *gorm.DB, orderID uint) ([]models.OrderStatusHistory, error) {
+	var history []models.OrderStatusHistory
+	if err := db.Where("order_id = ?", orderID).Order("created_at ASC, id ASC").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// recordHistory 写入状态变更记录
+func (s *OrderService) recordHistory(tx *gorm.DB, orderID uint, from *models.OrderStatus, to models.OrderStatus, actor StatusActor, reason string) error {
+	history := models.OrderStatusHistory{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorID:    actor.UserID,
+		ActorRole:  actor.Role,
+		Reason:     reason,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return errors.New("订单状态记录创建失败")
+	}
+	return nil
+}
+
+// awardOrderPoints 订单完成时发放积分（每个订单仅发放一次）
+func awardOrderPoints(tx *gorm.DB, order *models.Order, from models.OrderStatus) error {
+	if order.UserID == nil || order.PointsEarned <= 0 || order.PointsAwardedAt != nil {
+		return nil
+	}
+
+	pointsService := NewPointsService()
+	description := fmt.Sprintf("消费奖励 - 订单号: %s", order.OrderNumber)
+	if err := pointsService.EarnPoints(tx, *order.UserID, order.PointsEarned, &order.ID, models.TransactionTypeEarned, description); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := tx.Model(order).Update("points_awarded_at", now).Error; err != nil {
+		return errors.New("积分发放记录更新失败")
+	}
+	order.PointsAwardedAt = &now
+
+	return nil
+}
+
+// refundOrderPoints 订单取消时退还抵扣使用的积分
+func refundOrderPoints(tx *gorm.DB, order *models.Order, from models.OrderStatus) error {
+	if order.UserID == nil || order.CustomerPointsUsed <= 0 || order.PointsRefundedAt != nil {
+		return nil
+	}
+
+	pointsService := NewPointsService()
+	description := fmt.Sprintf("订单取消退还积分 - 订单号: %s", order.OrderNumber)
+	if err := pointsService.RefundPoints(tx, *order.UserID, order.CustomerPointsUsed, order.ID, description); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := tx.Model(order).Update("points_refunded_at", now).Error; err != nil {
+		return errors.New("积分退还记录更新失败")
+	}
+	order.PointsRefundedAt = &now
+
+	return nil
+}
+
+// reverseOrderPoints 已完成订单被取消时扣回已发放的积分
+func reverseOrderPoints(tx *gorm.DB, order *models.Order, from models.OrderStatus) error {
+	if order.UserID == nil || order.PointsAwardedAt == nil || order.PointsReversedAt != nil {
+		return nil
+	}
+
+	pointsService := NewPointsService()
+	description := fmt.Sprintf("订单取消扣回积分 - 订单号: %s", order.OrderNumber)
+	if err := pointsService.ReverseEarnedPoints(tx, *order.UserID, order.PointsEarned, order.ID, description); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := tx.Model(order).Update("points_reversed_at", now).Error; err != nil {
+		return errors.New("积分扣回记录更新失败")
+	}
+	order.PointsReversedAt = &now
+
+	return nil
+}
+
+// unpaidOrderTimeout 未支付订单超时时间，0 表示不自动取消
+func unpaidOrderTimeout() time.Duration {
+	if config.AppConfig != nil {
+		return time.Duration(config.AppConfig.UnpaidOrderTimeoutMinutes) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+// CancelUnpaidOrders 取消超时未支付的待处理订单，返回本次取消的订单
+// 通过状态机取消，库存、优惠券、积分与取餐码随取消钩子一并释放；每个订单单独提交，
+// 事务内重新检查支付状态，避免取消刚刚完成支付的订单
+func (s *OrderService) CancelUnpaidOrders(db *gorm.DB, now time.Time) ([]models.Order, error) {
+	timeout := unpaidOrderTimeout()
+	if timeout <= 0 {
+		return nil, nil
+	}
+
+	var ids []uint
+	if err := db.Model(&models.Order{}).
+		Where("status = ? AND payment_status = ? AND created_at <= ?", models.OrderStatusPending, models.OrderPaymentUnpaid, now.Add(-timeout)).
+		Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	cancelled := make([]models.Order, 0, len(ids))
+	for _, id := range ids {
+		var order *models.Order
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var current models.Order
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, id).Error; err != nil {
+				return err
+			}
+			if current.Status != models.OrderStatusPending || current.PaymentStatus != models.OrderPaymentUnpaid {
+				return nil
+			}
+
+			updated, err := s.UpdateStatus(tx, id, models.OrderStatusCancelled, SystemActor, "超时未支付，自动取消")
+			if err != nil {
+				return err
+			}
+			order = updated
+			return nil
+		})
+		if err != nil {
+			return cancelled, err
+		}
+		if order != nil {
+			cancelled = append(cancelled, *order)
+		}
+	}
+	return cancelled, nil
+}
+
+// CancelUnpaidOrdersJob 定时任务：取消超时未支付的订单并推送事件
+func CancelUnpaidOrdersJob(db *gorm.DB, now time.Time) error {
+	cancelled, err := NewOrderService().CancelUnpaidOrders(db, now)
+	for i := range cancelled {
+		PublishOrderEvent(OrderEventStatusChanged, &cancelled[i])
+	}
+	return err
+}
+
+// DeleteOrder 删除订单
+// 只允许删除已取消的订单（取消钩子已归还库存、积分与优惠券），且支付单均已失败或已退款、
+// 退款单均已完成；支付单与退款单不随订单级联删除，需在此显式清理
+func (s *OrderService) DeleteOrder(db *gorm.DB, orderID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+		if order.Status != models.OrderStatusCancelled || order.PaymentStatus == models.OrderPaymentPaid {
+			return ErrOrderNotDeletable
+		}
+
+		var unsettled int64
+		if err := tx.Model(&models.Payment{}).
+			Where("order_id = ? AND status NOT IN ?", orderID, []models.PaymentStatus{models.PaymentStatusFailed, models.PaymentStatusRefunded}).
+			Count(&unsettled).Error; err != nil {
+			return err
+		}
+		if unsettled > 0 {
+			return ErrOrderNotDeletable
+		}
+		if err := tx.Model(&models.PaymentRefund{}).
+			Where("order_id = ? AND status <> ?", orderID, models.PaymentRefundSucceeded).
+			Count(&unsettled).Error; err != nil {
+			return err
+		}
+		if unsettled > 0 {
+			return ErrOrderNotDeletable
+		}
+
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.PaymentRefund{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.Payment{}).Error; err != nil {
+			return err
+		}
+		// 订单项、状态记录等随订单级联删除
+		return tx.Delete(&order).Error
+	})
+}
+
+// NewOrderService 创建订单服务实例
+func NewOrderService() *OrderService {
+	return &OrderService{}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidResetToken 重置令牌无效或已过期
+	ErrInvalidResetToken = errors.New("重置令牌无效或已过期")
+)
+
+// passwordResetTTL 重置令牌有效期
+const passwordResetTTL = 24 * time.Hour
+
+// PasswordResetService 密码重置服务
+type PasswordResetService struct{}
+
+// hashResetToken 数据库只保存令牌摘要，泄露数据库也无法直接使用令牌
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex 生成 n 字节随机数的十六进制字符串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ForceReset 强制重置密码：原密码与已签发的登录令牌立即失效，返回一次性重置令牌
+// 用户须使用该令牌设置新密码后才能再次登录
+func (s *PasswordResetService) ForceReset(db *gorm.DB, user *models.User) (string, time.Time, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	placeholder, err := randomHex(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	hashed, err := utils.HashPassword(placeholder)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(passwordResetTTL)
+	if err := db.Model(user).Updates(map[string]interface{}{
+		"password":                  hashed,
+		"reset_password_token":      hashResetToken(token),
+		"reset_password_expires_at": expiresAt,
+		"token_version":             gorm.Expr("token_version + 1"),
+	}).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// Reset 使用重置令牌设置新密码，令牌使用后立即失效
+func (s *PasswordResetService) Reset(db *gorm.DB, token, newPassword string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrInvalidResetToken
+	}
+
+	var user models.User
+	if err := db.Where("reset_password_token = ?", hashResetToken(token)).First(&user).Error; err != nil {
+		return nil, ErrInvalidResetToken
+	}
+	if user.ResetPasswordExpiresAt == nil || time.Now().After(*user.ResetPasswordExpiresAt) {
+		return nil, ErrInvalidResetToken
+	}
+
+	hashed, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return nil, errors.New("密码加密失败")
+	}
+
+	// 以令牌为条件更新，避免同一令牌被并发使用两次
+	result := db.Model(&models.User{}).
+		Where("id = ? AND reset_password_token = ?", user.ID, user.ResetPasswordToken).
+		Updates(map[string]interface{}{
+			"password":                  hashed,
+			"reset_password_token":      "",
+			"reset_password_expires_at": nil,
+			"token_version":             gorm.Expr("token_version + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidResetToken
+	}
+	return &user, nil
+}
+
+// NewPasswordResetService 创建密码重置服务实例
+func NewPasswordResetService() *PasswordResetService {
+	return &PasswordResetService{}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+func init() {
+	RegisterPaymentProvider(NewMockPaymentProvider())
+}
+
+// mockIntent 模拟渠道内部的支付单
+type mockIntent struct {
+	amount   float64
+	status   models.PaymentStatus
+	refunded float64
+}
+
+// MockPaymentProvider 本地模拟支付渠道，仅限开发与测试，release 模式下禁止启用
+// 默认确认即成功；测试可通过 FailNextConfirm / FailNextRefund 注入失败，
+// 通过 SignWebhook 构造合法回调。内存中没有的支付单按 payments 表重建，重启后仍可确认和退款
+type MockPaymentProvider struct {
+	mu            sync.Mutex
+	intents       map[string]*mockIntent
+	refundKeys    map[string]bool
+	confirmErrors []string
+	refundErrors  []string
+}
+
+// mockWebhookPayload 模拟渠道回调报文
+type mockWebhookPayload struct {
+	ProviderRef   string `json:"provider_ref"`
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason"`
+}
+
+// Name 渠道名称
+func (p *MockPaymentProvider) Name() string {
+	return "mock"
+}
+
+// CreateIntent 创建模拟支付单
+func (p *MockPaymentProvider) CreateIntent(orderNumber string, amount float64) (*PaymentResult, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	ref := fmt.Sprintf("mock_%s_%s", orderNumber, hex.EncodeToString(buf))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.intents[ref] = &mockIntent{amount: amount, status: models.PaymentStatusPending}
+
+	return &PaymentResult{
+		ProviderRef:  ref,
+		Status:       models.PaymentStatusPending,
+		ClientSecret: ref + "_secret",
+	}, nil
+}
+
+// Confirm 确认模拟支付，存在待注入的失败时返回失败状态
+func (p *MockPaymentProvider) Confirm(providerRef string) (*PaymentResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.lookup(providerRef)
+	if !ok {
+		return nil, fmt.Errorf("模拟支付单不存在: %s", providerRef)
+	}
+
+	result := &PaymentResult{ProviderRef: providerRef}
+	if intent.status == models.PaymentStatusPending {
+		if len(p.confirmErrors) > 0 {
+			result.FailureReason = p.confirmErrors[0]
+			p.confirmErrors = p.confirmErrors[1:]
+			intent.status = models.PaymentStatusFailed
+		} else {
+			intent.status = models.PaymentStatusSucceeded
+		}
+	}
+	result.Status = intent.status
+	return result, nil
+}
+
+// Refund 模拟退款，退款总额不能超过支付金额，已处理的幂等键直接返回成功
+func (p *MockPaymentProvider) Refund(providerRef string, amount float64, idempotencyKey string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.refundErrors) > 0 {
+		reason := p.refundErrors[0]
+		p.refundErrors = p.refundErrors[1:]
+		return errors.New(reason)
+	}
+
+	intent, ok := p.lookup(providerRef)
+	if !ok {
+		return fmt.Errorf("模拟支付单不存在: %s", providerRef)
+	}
+	if intent.status != models.PaymentStatusSucceeded && intent.status != models.PaymentStatusRefunded {
+		return errors.New("支付未成功，无法退款")
+	}
+
+	if p.refundKeys[idempotencyKey] {
+		return nil
+	}
+	if RoundMoney(intent.refunded+amount) > intent.amount {
+		return errors.New("退款金额超过支付金额")
+	}
+
+	intent.refunded = RoundMoney(intent.refunded + amount)
+	intent.status = models.PaymentStatusRefunded
+	p.refundKeys[idempotencyKey] = true
+	return nil
+}
+
+// lookup 查找模拟支付单，内存中没有时按 payments 表与已完成的退款单重建（调用方需持有锁）
+func (p *MockPaymentProvider) lookup(providerRef string) (*mockIntent, bool) {
+	if intent, ok := p.intents[providerRef]; ok {
+		return intent, true
+	}
+
+	db := database.GetDB()
+	if db == nil {
+		return nil, false
+	}
+
+	var payment models.Payment
+	if err := db.Where("provider = ? AND provider_ref = ?", p.Name(), providerRef).First(&payment).Error; err != nil {
+		return nil, false
+	}
+
+	intent := &mockIntent{amount: payment.Amount, status: payment.Status}
+	var refunds []models.PaymentRefund
+	db.Where("payment_id = ? AND status = ?", payment.ID, models.PaymentRefundSucceeded).Find(&refunds)
+	for i := range refunds {
+		intent.refunded = RoundMoney(intent.refunded + refunds[i].Amount)
+		p.refundKeys[refundIdempotencyKey(&refunds[i])] = true
+	}
+	if payment.Status == models.PaymentStatusRefunded && intent.refunded == 0 {
+		intent.refunded = payment.Amount
+	}
+
+	p.intents[providerRef] = intent
+	return intent, true
+}
+
+// VerifyWebhook 校验 HMAC-SHA256 签名并解析回调
+func (p *MockPaymentProvider) VerifyWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	if !hmac.Equal([]byte(p.SignWebhook(payload)), []byte(signature)) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var body mockWebhookPayload
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+	}
+
+	status := models.PaymentStatus(body.Status)
+	if status != models.PaymentStatusSucceeded && status != models.PaymentStatusFailed {
+		return nil, fmt.Errorf("%w: 不支持的状态 %s", ErrInvalidWebhookPayload, body.Status)
+	}
+
+	p.mu.Lock()
+	if intent, ok := p.lookup(body.ProviderRef); ok && intent.status == models.PaymentStatusPending {
+		intent.status = status
+	}
+	p.mu.Unlock()
+
+	return &WebhookEvent{
+		ProviderRef:   body.ProviderRef,
+		Status:        status,
+		FailureReason: body.FailureReason,
+	}, nil
+}
+
+// SignWebhook 计算回调报文签名
+func (p *MockPaymentProvider) SignWebhook(payload []byte) string {
+	secret := "mock-webhook-secret"
+	if config.AppConfig != nil && config.AppConfig.PaymentWebhookSecret != "" {
+		secret = config.AppConfig.PaymentWebhookSecret
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// FailNextConfirm 令下一次确认支付失败
+func (p *MockPaymentProvider) FailNextConfirm(reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.confirmErrors = append(p.confirmErrors, reason)
+}
+
+// FailNextRefund 令下一次退款失败
+func (p *MockPaymentProvider) FailNextRefund(reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refundErrors = append(p.refundErrors, reason)
+}
+
+// RefundedAmount 查询模拟支付单已退款金额
+func (p *MockPaymentProvider) RefundedAmount(providerRef string) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if intent, ok := p.intents[providerRef]; ok {
+		return intent.refunded
+	}
+	return 0
+}
+
+// Reset 清空模拟渠道状态
+func (p *MockPaymentProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.intents = make(map[string]*mockIntent)
+	p.refundKeys = make(map[string]bool)
+	p.confirmErrors = nil
+	p.refundErrors = nil
+}
+
+// NewMockPaymentProvider 创建模拟支付渠道实例
+func NewMockPaymentProvider() *MockPaymentProvider {
+	return &MockPaymentProvider{
+		intents:    make(map[string]*mockIntent),
+		refundKeys: make(map[string]bool),
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/models"
+	"fmt"
+	"sync"
+)
+
+// PaymentResult 支付渠道返回的支付单状态
+type PaymentResult struct {
+	ProviderRef   string
+	Status        models.PaymentStatus
+	ClientSecret  string // 客户端完成支付所需凭证
+	FailureReason string
+}
+
+// WebhookEvent 经过验签的支付回调事件
+type WebhookEvent struct {
+	ProviderRef   string
+	Status        models.PaymentStatus
+	FailureReason string
+}
+
+// PaymentProvider 支付渠道接口
+type PaymentProvider interface {
+	// Name 渠道名称，与支付单的 provider 字段对应
+	Name() string
+	// CreateIntent 创建支付意图
+	CreateIntent(orderNumber string, amount float64) (*PaymentResult, error)
+	// Confirm 确认支付并返回最新状态
+	Confirm(providerRef string) (*PaymentResult, error)
+	// Refund 按金额退款，同一幂等键只退款一次，重复调用返回成功
+	Refund(providerRef string, amount float64, idempotencyKey string) error
+	// VerifyWebhook 校验回调签名并解析事件
+	VerifyWebhook(payload []byte, signature string) (*WebhookEvent, error)
+}
+
+var (
+	paymentProvidersMu sync.RWMutex
+	paymentProviders   = map[string]PaymentProvider{}
+)
+
+// RegisterPaymentProvider 注册支付渠道，同名渠道会被覆盖
+func RegisterPaymentProvider(provider PaymentProvider) {
+	paymentProvidersMu.Lock()
+	defer paymentProvidersMu.Unlock()
+	paymentProviders[provider.Name()] = provider
+}
+
+// GetPaymentProvider 按名称获取支付渠道
+func GetPaymentProvider(name string) (PaymentProvider, error) {
+	paymentProvidersMu.RLock()
+	defer paymentProvidersMu.RUnlock()
+	provider, ok := paymentProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentProviderNotFound, name)
+	}
+	return provider, nil
+}
+
+// DefaultPaymentProvider 获取配置中启用的支付渠道
+func DefaultPaymentProvider() (PaymentProvider, error) {
+	name := "mock"
+	if config.AppConfig != nil && config.AppConfig.PaymentProvider != "" {
+		name = config.AppConfig.PaymentProvider
+	}
+	return GetPaymentProvider(name)
+}
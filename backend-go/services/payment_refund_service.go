@@ -0,0 +1,234 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// refundMaxAttempts 渠道退款最多尝试次数，用尽后标记失败等待人工处理
+	refundMaxAttempts = 10
+	// refundMaxBackoff 重试间隔上限
+	refundMaxBackoff = time.Hour
+	// refundBatchSize 每次定时任务处理的退款单数量
+	refundBatchSize = 100
+)
+
+// enqueueRefund 登记支付单的全额退款，同一支付单只登记一次
+func (s *PaymentService) enqueueRefund(tx *gorm.DB, payment *models.Payment) error {
+	var count int64
+	if err := tx.Model(&models.PaymentRefund{}).Where("payment_id = ?", payment.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	refund := models.PaymentRefund{
+		PaymentID:     payment.ID,
+		OrderID:       payment.OrderID,
+		Amount:        payment.Amount,
+		Status:        models.PaymentRefundPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := tx.Create(&refund).Error; err != nil {
+		return errors.New("退款单创建失败")
+	}
+	return nil
+}
+
+// settleOrderRefund 已取消订单的退款单全部完成后，将订单支付状态标记为已退款
+func (s *PaymentService) settleOrderRefund(tx *gorm.DB, order *models.Order) error {
+	if order.Status != models.OrderStatusCancelled || order.PaymentStatus != models.OrderPaymentPaid {
+		return nil
+	}
+
+	var outstanding int64
+	if err := tx.Model(&models.PaymentRefund{}).
+		Where("order_id = ? AND status <> ?", order.ID, models.PaymentRefundSucceeded).
+		Count(&outstanding).Error; err != nil {
+		return err
+	}
+	if outstanding > 0 {
+		return nil
+	}
+
+	if err := tx.Model(order).Update("payment_status", models.OrderPaymentRefunded).Error; err != nil {
+		return errors.New("订单支付状态更新失败")
+	}
+	order.PaymentStatus = models.OrderPaymentRefunded
+	return nil
+}
+
+// ProcessRefunds 执行到期的待退款单，返回成功退款的数量
+func (s *PaymentService) ProcessRefunds(db *gorm.DB, now time.Time) (int, error) {
+	var ids []uint
+	if err := db.Model(&models.PaymentRefund{}).
+		Where("status = ? AND next_attempt_at <= ?", models.PaymentRefundPending, now).
+		Order("next_attempt_at ASC, id ASC").Limit(refundBatchSize).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	succeeded := 0
+	for _, id := range ids {
+		ok, err := s.processRefund(db, id, now)
+		if err != nil {
+			log.Printf("退款单 %d 处理失败: %v", id, err)
+			continue
+		}
+		if ok {
+			succeeded++
+		}
+	}
+	return succeeded, nil
+}
+
+// ProcessOrderRefunds 立即执行订单的待退款单，应在取消订单的事务提交后调用
+// 失败的退款单保留待重试状态，由定时任务继续处理
+func (s *PaymentService) ProcessOrderRefunds(db *gorm.DB, orderID uint) {
+	now := time.Now()
+
+	var ids []uint
+	if err := db.Model(&models.PaymentRefund{}).
+		Where("order_id = ? AND status = ? AND next_attempt_at <= ?", orderID, models.PaymentRefundPending, now).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("订单 %d 退款单查询失败: %v", orderID, err)
+		return
+	}
+
+	for _, id := range ids {
+		if _, err := s.processRefund(db, id, now); err != nil {
+			log.Printf("退款单 %d 处理失败: %v", id, err)
+		}
+	}
+}
+
+// GetOrderRefunds 获取订单的退款单
+func (s *PaymentService) GetOrderRefunds(db *gorm.DB, orderID uint) ([]models.PaymentRefund, error) {
+	var refunds []models.PaymentRefund
+	if err := db.Where("order_id = ?", orderID).Order("id ASC").Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// processRefund 调用渠道退款并记录结果
+// 退款单行在调用渠道期间保持锁定，多实例不会重复退款；渠道成功但提交失败时，
+// 重试使用相同的幂等键，渠道不会重复退款
+func (s *PaymentService) processRefund(db *gorm.DB, refundID uint, now time.Time) (bool, error) {
+	var (
+		refunded    bool
+		providerErr error
+		order       *models.Order
+	)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var refund models.PaymentRefund
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&refund, refundID).Error; err != nil {
+			return err
+		}
+		if refund.Status != models.PaymentRefundPending || refund.NextAttemptAt.After(now) {
+			return nil
+		}
+
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, refund.PaymentID).Error; err != nil {
+			return err
+		}
+
+		provider, err := GetPaymentProvider(payment.Provider)
+		if err == nil {
+			err = provider.Refund(payment.ProviderRef, refund.Amount, refundIdempotencyKey(&refund))
+		}
+		if err != nil {
+			providerErr = err
+			return s.recordRefundFailure(tx, &refund, err, now)
+		}
+
+		if err := tx.Model(&refund).Updates(map[string]interface{}{
+			"status":      models.PaymentRefundSucceeded,
+			"attempts":    refund.Attempts + 1,
+			"last_error":  "",
+			"refunded_at": now,
+		}).Error; err != nil {
+			return errors.New("退款单状态更新失败")
+		}
+		if err := tx.Model(&payment).Updates(map[string]interface{}{
+			"status":      models.PaymentStatusRefunded,
+			"refunded_at": now,
+		}).Error; err != nil {
+			return errors.New("支付单状态更新失败")
+		}
+
+		locked, err := s.lockOrder(tx, refund.OrderID)
+		if err != nil {
+			return err
+		}
+		if err := s.settleOrderRefund(tx, locked); err != nil {
+			return err
+		}
+		order = locked
+		refunded = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if providerErr != nil {
+		return false, fmt.Errorf("渠道退款失败: %w", providerErr)
+	}
+	if order != nil {
+		PublishOrderEvent(OrderEventPaymentUpdate, order)
+	}
+	return refunded, nil
+}
+
+// recordRefundFailure 记录渠道退款失败并按指数退避安排重试，次数用尽后标记失败
+func (s *PaymentService) recordRefundFailure(tx *gorm.DB, refund *models.PaymentRefund, cause error, now time.Time) error {
+	attempts := refund.Attempts + 1
+	status := models.PaymentRefundPending
+	if attempts >= refundMaxAttempts {
+		status = models.PaymentRefundFailed
+	}
+
+	backoff := time.Minute << uint(attempts-1)
+	if backoff > refundMaxBackoff || backoff <= 0 {
+		backoff = refundMaxBackoff
+	}
+
+	message := cause.Error()
+	if runes := []rune(message); len(runes) > 255 {
+		message = string(runes[:255])
+	}
+
+	if err := tx.Model(refund).Updates(map[string]interface{}{
+		"status":          status,
+		"attempts":        attempts,
+		"last_error":      message,
+		"next_attempt_at": now.Add(backoff),
+	}).Error; err != nil {
+		return errors.New("退款单状态更新失败")
+	}
+	if status == models.PaymentRefundFailed {
+		log.Printf("退款单 %d 已重试 %d 次仍失败，需人工处理: %v", refund.ID, attempts, cause)
+	}
+	return nil
+}
+
+// refundIdempotencyKey 渠道退款幂等键
+func refundIdempotencyKey(refund *models.PaymentRefund) string {
+	return fmt.Sprintf("refund_%d", refund.ID)
+}
+
+// PaymentRefundJob 定时任务：执行到期的待退款单
+func PaymentRefundJob(db *gorm.DB, now time.Time) error {
+	_, err := NewPaymentService().ProcessRefunds(db, now)
+	return err
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPaymentNotFound 支付单不存在
+	ErrPaymentNotFound = errors.New("支付单不存在")
+	// ErrPaymentProviderNotFound 支付渠道不存在
+	ErrPaymentProviderNotFound = errors.New("支付渠道不存在")
+	// ErrOrderAlreadyPaid 订单已支付
+	ErrOrderAlreadyPaid = errors.New("订单已支付")
+	// ErrOrderNotPayable 订单当前状态不可支付
+	ErrOrderNotPayable = errors.New("订单当前状态不可支付")
+	// ErrOrderUnpaid 订单未支付
+	ErrOrderUnpaid = errors.New("订单未支付，不能开始制作")
+	// ErrInvalidWebhookSignature 回调签名无效
+	ErrInvalidWebhookSignature = errors.New("支付回调签名无效")
+	// ErrInvalidWebhookPayload 回调报文无效
+	ErrInvalidWebhookPayload = errors.New("支付回调报文无效")
+)
+
+func init() {
+	RegisterOrderStatusGuard(models.OrderStatusPreparing, requireOrderPaid)
+	RegisterOrderStatusHook(models.OrderStatusCancelled, refundOrderPayment)
+}
+
+// PaymentService 支付服务
+type PaymentService struct{}
+
+// CreatePayment 为订单创建支付单，返回渠道支付意图
+func (s *PaymentService) CreatePayment(tx *gorm.DB, orderID uint) (*models.Payment, *PaymentResult, error) {
+	order, err := s.lockOrder(tx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.PaymentStatus != models.OrderPaymentUnpaid {
+		return nil, nil, ErrOrderAlreadyPaid
+	}
+	if order.Status == models.OrderStatusCancelled {
+		return nil, nil, ErrOrderNotPayable
+	}
+
+	provider, err := DefaultPaymentProvider()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := provider.CreateIntent(order.OrderNumber, order.PaymentAmount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建支付失败: %w", err)
+	}
+
+	payment := models.Payment{
+		OrderID:     order.ID,
+		Provider:    provider.Name(),
+		ProviderRef: result.ProviderRef,
+		Amount:      order.PaymentAmount,
+		Status:      models.PaymentStatusPending,
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return nil, nil, errors.New("支付单创建失败")
+	}
+
+	return &payment, result, nil
+}
+
+// ConfirmPayment 向渠道确认支付结果，已处理的支付单直接返回
+func (s *PaymentService) ConfirmPayment(tx *gorm.DB, orderID, paymentID uint) (*models.Payment, error) {
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND order_id = ?", paymentID, orderID).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, err
+	}
+	if payment.Status != models.PaymentStatusPending {
+		return &payment, nil
+	}
+
+	provider, err := GetPaymentProvider(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := provider.Confirm(payment.ProviderRef)
+	if err != nil {
+		return nil, fmt.Errorf("确认支付失败: %w", err)
+	}
+
+	if err := s.applyResult(tx, &payment, result.Status, result.FailureReason); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// HandleWebhook 处理渠道支付回调，重复回调不会重复入账
+func (s *PaymentService) HandleWebhook(tx *gorm.DB, providerName string, payload []byte, signature string) (*models.Payment, error) {
+	provider, err := GetPaymentProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := provider.VerifyWebhook(payload, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider = ? AND provider_ref = ?", provider.Name(), event.ProviderRef).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, err
+	}
+	if payment.Status != models.PaymentStatusPending {
+		return &payment, nil
+	}
+
+	if err := s.applyResult(tx, &payment, event.Status, event.FailureReason); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// RefundOrder 为订单已支付的金额创建退款单
+// 仅在当前事务内登记退款，渠道退款在事务提交后由 ProcessRefunds 执行；
+// 没有需要退款的支付单时（如积分全额抵扣）直接标记订单已退款
+func (s *PaymentService) RefundOrder(tx *gorm.DB, order *models.Order) error {
+	var payments []models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", order.ID, models.PaymentStatusSucceeded).
+		Find(&payments).Error; err != nil {
+		return err
+	}
+
+	for i := range payments {
+		if err := s.enqueueRefund(tx, &payments[i]); err != nil {
+			return err
+		}
+	}
+
+	return s.settleOrderRefund(tx, order)
+}
+
+// GetOrderPayments 获取订单的支付记录
+func (s *PaymentService) GetOrderPayments(db *gorm.DB, orderID uint) ([]models.Payment, error) {
+	var payments []models.Payment
+	if err := db.Where("order_id = ?", orderID).Order("created_at ASC, id ASC").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// MarkPaidWithoutPayment 无需支付的订单（如积分全额抵扣）直接标记为已支付
+func (s *PaymentService) MarkPaidWithoutPayment(tx *gorm.DB, order *models.Order) error {
+	now := time.Now()
+	if err := tx.Model(order).Updates(map[string]interface{}{
+		"payment_status": models.OrderPaymentPaid,
+		"paid_at":        now,
+	}).Error; err != nil {
+		return errors.New("订单支付状态更新失败")
+	}
+	order.PaymentStatus = models.OrderPaymentPaid
+	order.PaidAt = &now
+	return nil
+}
+
+// applyResult 根据渠道结果更新支付单，支付成功时同步订单支付状态
+// 订单在支付完成前已取消的，支付成功后登记原路退款
+func (s *PaymentService) applyResult(tx *gorm.DB, payment *models.Payment, status models.PaymentStatus, failureReason string) error {
+	switch status {
+	case models.PaymentStatusSucceeded:
+		now := time.Now()
+		if err := tx.Model(payment).Updates(map[string]interface{}{
+			"status":  models.PaymentStatusSucceeded,
+			"paid_at": now,
+		}).Error; err != nil {
+			return errors.New("支付单状态更新失败")
+		}
+		payment.Status = models.PaymentStatusSucceeded
+		payment.PaidAt = &now
+
+		order, err := s.lockOrder(tx, payment.OrderID)
+		if err != nil {
+			return err
+		}
+		if order.Status == models.OrderStatusCancelled || order.PaymentStatus != models.OrderPaymentUnpaid {
+			return s.enqueueRefund(tx, payment)
+		}
+		if err := tx.Model(order).Updates(map[string]interface{}{
+			"payment_status": models.OrderPaymentPaid,
+			"paid_at":        now,
+		}).Error; err != nil {
+			return errors.New("订单支付状态更新失败")
+		}
+	case models.PaymentStatusFailed:
+		if err := tx.Model(payment).Updates(map[string]interface{}{
+			"status":         models.PaymentStatusFailed,
+			"failure_reason": failureReason,
+		}).Error; err != nil {
+			return errors.New("支付单状态更新失败")
+		}
+		payment.Status = models.PaymentStatusFailed
+		payment.FailureReason = failureReason
+	}
+	return nil
+}
+
+// lockOrder 加锁订单行
+func (s *PaymentService) lockOrder(tx *gorm.DB, orderID uint) (*models.Order, error) {
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// requireOrderPaid 订单支付完成后才能进入制作队列
+func requireOrderPaid(tx *gorm.DB, order *models.Order, to models.OrderStatus) error {
+	if order.PaymentStatus != models.OrderPaymentPaid {
+		return ErrOrderUnpaid
+	}
+	return nil
+}
+
+// refundOrderPayment 订单取消时登记原路退还已支付金额
+func refundOrderPayment(tx *gorm.DB, order *models.Order, from models.OrderStatus) error {
+	return NewPaymentService().RefundOrder(tx, order)
+}
+
+// NewPaymentService 创建支付服务实例
+func NewPaymentService() *PaymentService {
+	return &PaymentService{}
+}
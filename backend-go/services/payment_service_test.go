@@ -0,0 +1,314 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/testutil"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// mockProvider 获取模拟支付渠道，测试结束时清空其状态
+func mockProvider(t *testing.T) *MockPaymentProvider {
+	t.Helper()
+	provider, err := GetPaymentProvider("mock")
+	if err != nil {
+		t.Fatalf("获取模拟支付渠道失败: %v", err)
+	}
+	mock := provider.(*MockPaymentProvider)
+	mock.Reset()
+	t.Cleanup(mock.Reset)
+	return mock
+}
+
+// payOrder 为订单创建支付单并确认
+func payOrder(t *testing.T, db *gorm.DB, orderID uint) *models.Payment {
+	t.Helper()
+	var payment *models.Payment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		created, _, err := NewPaymentService().CreatePayment(tx, orderID)
+		if err != nil {
+			return err
+		}
+		payment, err = NewPaymentService().ConfirmPayment(tx, orderID, created.ID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("支付订单失败: %v", err)
+	}
+	return payment
+}
+
+// createPendingPayment 为订单创建待确认的支付单
+func createPendingPayment(t *testing.T, db *gorm.DB, orderID uint) *models.Payment {
+	t.Helper()
+	var payment *models.Payment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, _, err = NewPaymentService().CreatePayment(tx, orderID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("创建支付单失败: %v", err)
+	}
+	return payment
+}
+
+// webhookPayload 构造模拟渠道回调报文
+func webhookPayload(t *testing.T, providerRef string, status models.PaymentStatus) []byte {
+	t.Helper()
+	payload, err := json.Marshal(mockWebhookPayload{ProviderRef: providerRef, Status: string(status)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestPayCancelRefundThroughMockProvider(t *testing.T) {
+	db := testutil.NewDB(t)
+	mock := mockProvider(t)
+	store := createStore(t, db)
+	order := createOrder(t, db, store, nil, models.OrderStatusPending, 32.5)
+
+	payment := payOrder(t, db, order.ID)
+	if payment.Status != models.PaymentStatusSucceeded {
+		t.Fatalf("支付单状态 = %s，期望 succeeded", payment.Status)
+	}
+	db.First(order, order.ID)
+	if order.PaymentStatus != models.OrderPaymentPaid || order.PaidAt == nil {
+		t.Fatalf("订单支付状态 = %s，期望 paid", order.PaymentStatus)
+	}
+
+	// 取消时只在事务内登记退款，渠道退款在提交后执行
+	updateStatus(t, db, order.ID, models.OrderStatusCancelled)
+	refunds, err := NewPaymentService().GetOrderRefunds(db, order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 1 || refunds[0].Status != models.PaymentRefundPending || refunds[0].Amount != 32.5 {
+		t.Fatalf("取消后退款单 = %+v，期望一笔 32.5 元的待退款单", refunds)
+	}
+	if got := mock.RefundedAmount(payment.ProviderRef); got != 0 {
+		t.Fatalf("事务内已调用渠道退款 %.2f 元，期望提交后再退款", got)
+	}
+
+	NewPaymentService().ProcessOrderRefunds(db, order.ID)
+
+	db.First(&refunds[0], refunds[0].ID)
+	if refunds[0].Status != models.PaymentRefundSucceeded || refunds[0].Attempts != 1 {
+		t.Fatalf("退款单状态 = %s（尝试 %d 次），期望一次成功", refunds[0].Status, refunds[0].Attempts)
+	}
+	db.First(payment, payment.ID)
+	if payment.Status != models.PaymentStatusRefunded {
+		t.Fatalf("支付单状态 = %s，期望 refunded", payment.Status)
+	}
+	db.First(order, order.ID)
+	if order.PaymentStatus != models.OrderPaymentRefunded {
+		t.Fatalf("订单支付状态 = %s，期望 refunded", order.PaymentStatus)
+	}
+	if got := mock.RefundedAmount(payment.ProviderRef); got != 32.5 {
+		t.Fatalf("渠道已退款 %.2f 元，期望 32.50", got)
+	}
+
+	// 提交失败后重试使用相同幂等键，渠道不会重复退款
+	if err := mock.Refund(payment.ProviderRef, 32.5, refundIdempotencyKey(&refunds[0])); err != nil {
+		t.Fatalf("相同幂等键重复退款返回错误: %v", err)
+	}
+	if got := mock.RefundedAmount(payment.ProviderRef); got != 32.5 {
+		t.Fatalf("相同幂等键重复退款后渠道已退款 %.2f 元，期望仍为 32.50", got)
+	}
+}
+
+func TestRefundRetriesWithBackoff(t *testing.T) {
+	db := testutil.NewDB(t)
+	mock := mockProvider(t)
+	store := createStore(t, db)
+	order := createOrder(t, db, store, nil, models.OrderStatusPending, 18)
+	payment := payOrder(t, db, order.ID)
+	updateStatus(t, db, order.ID, models.OrderStatusCancelled)
+
+	var refund models.PaymentRefund
+	db.Where("order_id = ?", order.ID).First(&refund)
+	now := refund.NextAttemptAt.Add(time.Second)
+	service := NewPaymentService()
+
+	// 前两次渠道失败，重试间隔按 1、2 分钟递增
+	steps := []struct {
+		name        string
+		failure     string
+		at          time.Duration
+		wantOK      int
+		wantStatus  models.PaymentRefundStatus
+		wantAttempt int
+		wantNext    time.Duration
+	}{
+		{name: "首次失败", failure: "渠道繁忙", at: 0, wantStatus: models.PaymentRefundPending, wantAttempt: 1, wantNext: time.Minute},
+		{name: "未到重试时间", at: 30 * time.Second, wantStatus: models.PaymentRefundPending, wantAttempt: 1, wantNext: time.Minute},
+		{name: "第二次失败", failure: "渠道超时", at: time.Minute, wantStatus: models.PaymentRefundPending, wantAttempt: 2, wantNext: 3 * time.Minute},
+		{name: "重试成功", at: 3 * time.Minute, wantOK: 1, wantStatus: models.PaymentRefundSucceeded, wantAttempt: 3},
+	}
+	for _, step := range steps {
+		if step.failure != "" {
+			mock.FailNextRefund(step.failure)
+		}
+		ok, err := service.ProcessRefunds(db, now.Add(step.at))
+		if err != nil {
+			t.Fatalf("%s: 处理退款失败: %v", step.name, err)
+		}
+		if ok != step.wantOK {
+			t.Fatalf("%s: 成功退款 %d 笔，期望 %d", step.name, ok, step.wantOK)
+		}
+
+		db.First(&refund, refund.ID)
+		if refund.Status != step.wantStatus || refund.Attempts != step.wantAttempt {
+			t.Fatalf("%s: 退款单状态 = %s（尝试 %d 次），期望 %s（尝试 %d 次）",
+				step.name, refund.Status, refund.Attempts, step.wantStatus, step.wantAttempt)
+		}
+		if step.failure != "" && refund.LastError != step.failure {
+			t.Fatalf("%s: 退款失败原因 = %q，期望 %q", step.name, refund.LastError, step.failure)
+		}
+		if step.wantNext > 0 && !refund.NextAttemptAt.Equal(now.Add(step.wantNext)) {
+			t.Fatalf("%s: 下次重试时间 = %s，期望 %s", step.name, refund.NextAttemptAt, now.Add(step.wantNext))
+		}
+	}
+
+	db.First(order, order.ID)
+	if order.PaymentStatus != models.OrderPaymentRefunded {
+		t.Fatalf("订单支付状态 = %s，期望 refunded", order.PaymentStatus)
+	}
+	if got := mock.RefundedAmount(payment.ProviderRef); got != 18 {
+		t.Fatalf("渠道已退款 %.2f 元，期望 18.00", got)
+	}
+}
+
+func TestRefundMarkedFailedAfterMaxAttempts(t *testing.T) {
+	db := testutil.NewDB(t)
+	mock := mockProvider(t)
+	store := createStore(t, db)
+	order := createOrder(t, db, store, nil, models.OrderStatusPending, 20)
+	payOrder(t, db, order.ID)
+	updateStatus(t, db, order.ID, models.OrderStatusCancelled)
+
+	var refund models.PaymentRefund
+	db.Where("order_id = ?", order.ID).First(&refund)
+	now := refund.NextAttemptAt
+	for i := 0; i < refundMaxAttempts; i++ {
+		mock.FailNextRefund("渠道不可用")
+		now = now.Add(refundMaxBackoff)
+		if _, err := NewPaymentService().ProcessRefunds(db, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db.First(&refund, refund.ID)
+	if refund.Status != models.PaymentRefundFailed || refund.Attempts != refundMaxAttempts {
+		t.Fatalf("退款单状态 = %s（尝试 %d 次），期望重试 %d 次后标记失败", refund.Status, refund.Attempts, refundMaxAttempts)
+	}
+	db.First(order, order.ID)
+	if order.PaymentStatus != models.OrderPaymentPaid {
+		t.Fatalf("退款失败后订单支付状态 = %s，期望保持 paid 等待人工处理", order.PaymentStatus)
+	}
+}
+
+func TestConfirmPaymentFailure(t *testing.T) {
+	db := testutil.NewDB(t)
+	mock := mockProvider(t)
+	store := createStore(t, db)
+	order := createOrder(t, db, store, nil, models.OrderStatusPending, 15)
+
+	mock.FailNextConfirm("余额不足")
+	payment := payOrder(t, db, order.ID)
+	if payment.Status != models.PaymentStatusFailed || payment.FailureReason != "余额不足" {
+		t.Fatalf("支付单状态 = %s（%s），期望因余额不足失败", payment.Status, payment.FailureReason)
+	}
+	db.First(order, order.ID)
+	if order.PaymentStatus != models.OrderPaymentUnpaid {
+		t.Fatalf("支付失败后订单支付状态 = %s，期望 unpaid", order.PaymentStatus)
+	}
+
+	// 未支付订单不能进入制作
+	err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := NewOrderService().UpdateStatus(tx, order.ID, models.OrderStatusPreparing, SystemActor, "")
+		return err
+	})
+	if !errors.Is(err, ErrOrderUnpaid) {
+		t.Fatalf("未支付订单开始制作返回 %v，期望 ErrOrderUnpaid", err)
+	}
+
+	// 失败后可重新发起支付
+	payment = payOrder(t, db, order.ID)
+	if payment.Status != models.PaymentStatusSucceeded {
+		t.Fatalf("重新支付后支付单状态 = %s，期望 succeeded", payment.Status)
+	}
+	updateStatus(t, db, order.ID, models.OrderStatusPreparing)
+}
+
+func TestHandleWebhook(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         models.PaymentStatus
+		badSignature   bool
+		cancelFirst    bool
+		deliverTwice   bool
+		wantErr        error
+		wantPayment    models.PaymentStatus
+		wantOrder      models.OrderPaymentStatus
+		wantRefundRows int64
+	}{
+		{name: "签名无效", status: models.PaymentStatusSucceeded, badSignature: true, wantErr: ErrInvalidWebhookSignature, wantPayment: models.PaymentStatusPending, wantOrder: models.OrderPaymentUnpaid},
+		{name: "支付成功", status: models.PaymentStatusSucceeded, wantPayment: models.PaymentStatusSucceeded, wantOrder: models.OrderPaymentPaid},
+		{name: "支付失败", status: models.PaymentStatusFailed, wantPayment: models.PaymentStatusFailed, wantOrder: models.OrderPaymentUnpaid},
+		{name: "重复回调不重复入账", status: models.PaymentStatusSucceeded, deliverTwice: true, wantPayment: models.PaymentStatusSucceeded, wantOrder: models.OrderPaymentPaid},
+		{name: "订单已取消后支付成功", status: models.PaymentStatusSucceeded, cancelFirst: true, wantPayment: models.PaymentStatusSucceeded, wantOrder: models.OrderPaymentUnpaid, wantRefundRows: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			mock := mockProvider(t)
+			store := createStore(t, db)
+			order := createOrder(t, db, store, nil, models.OrderStatusPending, 25)
+			payment := createPendingPayment(t, db, order.ID)
+			if tt.cancelFirst {
+				updateStatus(t, db, order.ID, models.OrderStatusCancelled)
+			}
+
+			payload := webhookPayload(t, payment.ProviderRef, tt.status)
+			signature := mock.SignWebhook(payload)
+			if tt.badSignature {
+				signature = mock.SignWebhook([]byte("forged"))
+			}
+
+			deliveries := 1
+			if tt.deliverTwice {
+				deliveries = 2
+			}
+			for i := 0; i < deliveries; i++ {
+				err := db.Transaction(func(tx *gorm.DB) error {
+					_, err := NewPaymentService().HandleWebhook(tx, "mock", payload, signature)
+					return err
+				})
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("第 %d 次回调返回 %v，期望 %v", i+1, err, tt.wantErr)
+				}
+			}
+
+			db.First(payment, payment.ID)
+			if payment.Status != tt.wantPayment {
+				t.Fatalf("支付单状态 = %s，期望 %s", payment.Status, tt.wantPayment)
+			}
+			db.First(order, order.ID)
+			if order.PaymentStatus != tt.wantOrder {
+				t.Fatalf("订单支付状态 = %s，期望 %s", order.PaymentStatus, tt.wantOrder)
+			}
+			var refunds int64
+			db.Model(&models.PaymentRefund{}).Where("order_id = ?", order.ID).Count(&refunds)
+			if refunds != tt.wantRefundRows {
+				t.Fatalf("退款单数量 = %d，期望 %d", refunds, tt.wantRefundRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PointsExpiryResult 积分过期处理结果
+type PointsExpiryResult struct {
+	Users  int `json:"users"`
+	Lots   int `json:"lots"`
+	Points int `json:"points"`
+}
+
+// pointsExpiryMonths 积分有效期（月），0 表示永不过期
+func pointsExpiryMonths() int {
+	if config.AppConfig != nil {
+		return config.AppConfig.PointsExpiryMonths
+	}
+	return 12
+}
+
+// pointsExpiryNoticeDays 积分到期提醒提前天数
+func pointsExpiryNoticeDays() int {
+	if config.AppConfig != nil && config.AppConfig.PointsExpiryNoticeDays > 0 {
+		return config.AppConfig.PointsExpiryNoticeDays
+	}
+	return 30
+}
+
+// PointsExpiresAt 计算积分批次过期时间：获得日期加有效期后的次日零点
+// 同一天获得的积分同时过期，便于按日期提醒
+func PointsExpiresAt(earnedAt time.Time) *time.Time {
+	months := pointsExpiryMonths()
+	if months <= 0 {
+		return nil
+	}
+	day := time.Date(earnedAt.Year(), earnedAt.Month(), earnedAt.Day(), 0, 0, 0, 0, earnedAt.Location())
+	expiresAt := day.AddDate(0, months, 1)
+	return &expiresAt
+}
+
+// CreateLot 为一笔积分收入创建批次
+func (s *PointsService) CreateLot(tx *gorm.DB, userID uint, transactionID *uint, source models.TransactionType, points int, earnedAt time.Time) error {
+	if points <= 0 {
+		return nil
+	}
+	lot := models.PointLot{
+		UserID:        userID,
+		TransactionID: transactionID,
+		SourceType:    source,
+		Points:        points,
+		Remaining:     points,
+		EarnedAt:      earnedAt,
+		ExpiresAt:     PointsExpiresAt(earnedAt),
+	}
+	if err := tx.Create(&lot).Error; err != nil {
+		return errors.New("积分批次创建失败")
+	}
+	return nil
+}
+
+// consumeLots 按先到期先扣减的顺序扣减积分批次
+// 启用批次前已有的积分没有批次记录，扣减前先按当前可用积分补齐
+func (s *PointsService) consumeLots(tx *gorm.DB, userPoints *models.UserPoints, points int) error {
+	if points <= 0 {
+		return nil
+	}
+
+	var lots []models.PointLot
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND remaining > 0 AND expired_at IS NULL", userPoints.UserID).
+		Order("expires_at IS NULL, expires_at ASC, id ASC").
+		Find(&lots).Error; err != nil {
+		return errors.New("积分批次查询失败")
+	}
+
+	covered := 0
+	for _, lot := range lots {
+		covered += lot.Remaining
+	}
+	if covered < userPoints.TotalPoints {
+		legacy := models.PointLot{
+			UserID:     userPoints.UserID,
+			SourceType: models.TransactionTypeEarned,
+			Points:     userPoints.TotalPoints - covered,
+			Remaining:  userPoints.TotalPoints - covered,
+			EarnedAt:   time.Now(),
+			ExpiresAt:  PointsExpiresAt(time.Now()),
+		}
+		if err := tx.Create(&legacy).Error; err != nil {
+			return errors.New("积分批次创建失败")
+		}
+		lots = append(lots, legacy)
+	}
+
+	remaining := points
+	for i := range lots {
+		if remaining == 0 {
+			break
+		}
+		take := lots[i].Remaining
+		if take > remaining {
+			take = remaining
+		}
+		if err := tx.Model(&lots[i]).Update("remaining", lots[i].Remaining-take).Error; err != nil {
+			return errors.New("积分批次扣减失败")
+		}
+		remaining -= take
+	}
+	return nil
+}
+
+// ExpirePoints 处理已到期的积分批次：清零批次剩余积分、扣减可用积分并记录过期变动
+// 已处理的批次会标记过期时间，可重复执行
+func (s *PointsService) ExpirePoints(db *gorm.DB, now time.Time) (*PointsExpiryResult, error) {
+	result := &PointsExpiryResult{}
+
+	var userIDs []uint
+	if err := db.Model(&models.PointLot{}).
+		Where("expires_at <= ? AND expired_at IS NULL AND remaining > 0", now).
+		Distinct().Order("user_id ASC").Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+
+	for _, userID := range userIDs {
+		lots, points, err := s.expireUserLots(db, userID, now)
+		if err != nil {
+			return result, err
+		}
+		if lots > 0 {
+			result.Users++
+			result.Lots += lots
+			result.Points += points
+		}
+	}
+	return result, nil
+}
+
+// expireUserLots 在单个事务内处理一位用户的到期批次
+func (s *PointsService) expireUserLots(db *gorm.DB, userID uint, now time.Time) (int, int, error) {
+	lotCount, expired := 0, 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		userPoints, err := s.LockUserPoints(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var lots []models.PointLot
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND expires_at <= ? AND expired_at IS NULL AND remaining > 0", userID, now).
+			Order("expires_at ASC, id ASC").Find(&lots).Error; err != nil {
+			return err
+		}
+		if len(lots) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(lots))
+		for _, lot := range lots {
+			ids = append(ids, lot.ID)
+			expired += lot.Remaining
+		}
+		if err := tx.Model(&models.PointLot{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{"remaining": 0, "expired_at": now}).Error; err != nil {
+			return err
+		}
+		lotCount = len(lots)
+
+		if expired > userPoints.TotalPoints {
+			expired = userPoints.TotalPoints
+		}
+		if expired == 0 {
+			return nil
+		}
+		userPoints.TotalPoints -= expired
+		if err := tx.Model(userPoints).Update("total_points", userPoints.TotalPoints).Error; err != nil {
+			return err
+		}
+
+		transaction := models.PointTransaction{
+			UserID:          userID,
+			TransactionType: models.TransactionTypeExpired,
+			PointsChange:    -expired,
+			PointsBalance:   userPoints.TotalPoints,
+			Description:     fmt.Sprintf("积分过期（%d个批次）", len(lots)),
+		}
+		return tx.Create(&transaction).Error
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return lotCount, expired, nil
+}
+
+// ExpiringSoon 查询提醒期内即将过期的积分，按过期日期汇总
+func (s *PointsService) ExpiringSoon(db *gorm.DB, userID uint, now time.Time) ([]models.ExpiringPoints, error) {
+	until := now.AddDate(0, 0, pointsExpiryNoticeDays())
+
+	var lots []models.PointLot
+	if err := db.Where("user_id = ? AND remaining > 0 AND expired_at IS NULL AND expires_at > ? AND expires_at <= ?", userID, now, until).
+		Order("expires_at ASC").Find(&lots).Error; err != nil {
+		return nil, err
+	}
+
+	expiring := make([]models.ExpiringPoints, 0)
+	for _, lot := range lots {
+		// 过期时间为次日零点，提醒展示最后可用日期
+		lastDay := lot.ExpiresAt.AddDate(0, 0, -1).Format("2006-01-02")
+		if n := len(expiring); n > 0 && expiring[n-1].ExpiresOn == lastDay {
+			expiring[n-1].Points += lot.Remaining
+			continue
+		}
+		expiring = append(expiring, models.ExpiringPoints{
+			Points:    lot.Remaining,
+			ExpiresOn: lastDay,
+			ExpiresAt: *lot.ExpiresAt,
+		})
+	}
+	return expiring, nil
+}
+
+// PointsExpiryJob 定时任务：处理已到期的积分
+func PointsExpiryJob(db *gorm.DB, now time.Time) error {
+	_, err := NewPointsService().ExpirePoints(db, now)
+	return err
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/testutil"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// grantLot 为会员登记一个积分批次并增加可用积分
+func grantLot(t *testing.T, db *gorm.DB, userID uint, points int, earnedAt time.Time) {
+	t.Helper()
+	if err := NewPointsService().CreateLot(db, userID, nil, models.TransactionTypeEarned, points, earnedAt); err != nil {
+		t.Fatalf("创建积分批次失败: %v", err)
+	}
+	if err := db.Model(&models.UserPoints{}).Where("user_id = ?", userID).
+		Update("total_points", gorm.Expr("total_points + ?", points)).Error; err != nil {
+		t.Fatalf("增加可用积分失败: %v", err)
+	}
+}
+
+// lotRemaining 按创建顺序返回会员各批次剩余积分
+func lotRemaining(t *testing.T, db *gorm.DB, userID uint) []int {
+	t.Helper()
+	var lots []models.PointLot
+	if err := db.Where("user_id = ?", userID).Order("id ASC").Find(&lots).Error; err != nil {
+		t.Fatal(err)
+	}
+	remaining := make([]int, 0, len(lots))
+	for _, lot := range lots {
+		remaining = append(remaining, lot.Remaining)
+	}
+	return remaining
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUsePointsConsumesLotsFIFO(t *testing.T) {
+	base := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		lots   []time.Duration // 各批次相对 base 的获得时间，每批 100 分
+		legacy int             // 没有批次记录的历史积分
+		use    int
+		want   []int // 扣减后各批次剩余积分（按创建顺序，历史积分补齐的批次在最后）
+	}{
+		{name: "先扣最早到期的批次", lots: []time.Duration{0, 24 * time.Hour}, use: 150, want: []int{0, 50}},
+		{name: "按到期时间而非创建顺序扣减", lots: []time.Duration{48 * time.Hour, 0}, use: 120, want: []int{80, 0}},
+		{name: "同日获得的批次按创建顺序扣减", lots: []time.Duration{0, time.Hour}, use: 100, want: []int{0, 100}},
+		{name: "历史积分补齐批次后扣减", lots: []time.Duration{0}, legacy: 50, use: 120, want: []int{0, 30}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			user := createMember(t, db, "member")
+			for _, offset := range tt.lots {
+				grantLot(t, db, user.ID, 100, base.Add(offset))
+			}
+			if tt.legacy > 0 {
+				db.Model(&models.UserPoints{}).Where("user_id = ?", user.ID).
+					Update("total_points", gorm.Expr("total_points + ?", tt.legacy))
+			}
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return NewPointsService().UsePoints(tx, user.ID, tt.use, 1, "积分抵扣")
+			})
+			if err != nil {
+				t.Fatalf("使用积分失败: %v", err)
+			}
+
+			if got := lotRemaining(t, db, user.ID); !equalInts(got, tt.want) {
+				t.Fatalf("批次剩余积分 = %v，期望 %v", got, tt.want)
+			}
+			want := len(tt.lots)*100 + tt.legacy - tt.use
+			if got := pointsOf(t, db, user.ID).TotalPoints; got != want {
+				t.Fatalf("可用积分 = %d，期望 %d", got, want)
+			}
+		})
+	}
+}
+
+func TestExpirePoints(t *testing.T) {
+	earned := time.Date(2025, 3, 15, 18, 30, 0, 0, time.UTC)
+	// 有效期 12 个月，过期时间为 2026-03-16 零点
+	expiresAt := time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		used        int
+		adjust      int // 处理前直接扣减的可用积分，模拟账户余额低于批次剩余
+		now         time.Time
+		wantPoints  int
+		wantExpired int
+		wantTotal   int
+	}{
+		{name: "未到期不处理", now: expiresAt.Add(-time.Second), wantTotal: 300},
+		{name: "到期批次清零", now: expiresAt, wantPoints: 100, wantExpired: 1, wantTotal: 200},
+		{name: "已部分使用的批次只过期剩余积分", used: 60, now: expiresAt, wantPoints: 40, wantExpired: 1, wantTotal: 200},
+		{name: "过期积分不超过可用积分", adjust: 250, now: expiresAt.Add(time.Hour), wantPoints: 50, wantExpired: 1, wantTotal: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			user := createMember(t, db, "member")
+			grantLot(t, db, user.ID, 100, earned)
+			grantLot(t, db, user.ID, 200, earned.AddDate(0, 1, 0))
+			if tt.used > 0 {
+				err := db.Transaction(func(tx *gorm.DB) error {
+					return NewPointsService().UsePoints(tx, user.ID, tt.used, 1, "积分抵扣")
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.adjust > 0 {
+				db.Model(&models.UserPoints{}).Where("user_id = ?", user.ID).
+					Update("total_points", gorm.Expr("total_points - ?", tt.adjust))
+			}
+
+			result, err := NewPointsService().ExpirePoints(db, tt.now)
+			if err != nil {
+				t.Fatalf("处理积分过期失败: %v", err)
+			}
+			if result.Points != tt.wantPoints || result.Lots != tt.wantExpired {
+				t.Fatalf("过期结果 = %+v，期望 %d 个批次共 %d 分", result, tt.wantExpired, tt.wantPoints)
+			}
+			if got := pointsOf(t, db, user.ID).TotalPoints; got != tt.wantTotal {
+				t.Fatalf("可用积分 = %d，期望 %d", got, tt.wantTotal)
+			}
+
+			var expiredTx int64
+			db.Model(&models.PointTransaction{}).
+				Where("user_id = ? AND transaction_type = ?", user.ID, models.TransactionTypeExpired).Count(&expiredTx)
+			wantTx := int64(0)
+			if tt.wantPoints > 0 {
+				wantTx = 1
+			}
+			if expiredTx != wantTx {
+				t.Fatalf("过期变动记录 = %d 条，期望 %d 条", expiredTx, wantTx)
+			}
+
+			// 重复执行不会再次扣减
+			again, err := NewPointsService().ExpirePoints(db, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Lots != 0 || again.Points != 0 {
+				t.Fatalf("重复执行过期结果 = %+v，期望不再处理", again)
+			}
+		})
+	}
+}
+
+func TestExpiringSoonGroupsByLastDay(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createMember(t, db, "member")
+	earned := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+	grantLot(t, db, user.ID, 100, earned)
+	grantLot(t, db, user.ID, 50, earned.Add(8*time.Hour))
+	grantLot(t, db, user.ID, 70, earned.AddDate(0, 2, 0))
+
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	expiring, err := NewPointsService().ExpiringSoon(db, user.ID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expiring) != 1 || expiring[0].Points != 150 || expiring[0].ExpiresOn != "2026-03-15" {
+		t.Fatalf("即将过期积分 = %+v，期望 2026-03-15 到期 150 分", expiring)
+	}
+}
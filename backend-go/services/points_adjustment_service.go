@@ -0,0 +1,161 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidPointsAdjustment 积分调整参数不合法
+	ErrInvalidPointsAdjustment = errors.New("积分调整参数不合法")
+	// ErrPointsAdjustmentNotFound 积分调整记录不存在
+	ErrPointsAdjustmentNotFound = errors.New("积分调整记录不存在")
+	// ErrPointsAdjustmentReviewed 积分调整已审批
+	ErrPointsAdjustmentReviewed = errors.New("该积分调整已处理")
+	// ErrPointsAdjustmentSelfReview 发起人不能审批自己的积分调整
+	ErrPointsAdjustmentSelfReview = errors.New("积分调整须由另一位管理员审批")
+)
+
+// PointsAdjustmentService 管理员手动积分调整服务
+type PointsAdjustmentService struct{}
+
+// approvalThreshold 需要审批的调整数量阈值
+func (s *PointsAdjustmentService) approvalThreshold() int {
+	if config.AppConfig != nil && config.AppConfig.PointsAdjustmentApprovalThreshold >= 0 {
+		return config.AppConfig.PointsAdjustmentApprovalThreshold
+	}
+	return 500
+}
+
+// RequiresApproval 调整数量（绝对值）超过阈值时需要第二位管理员审批
+func (s *PointsAdjustmentService) RequiresApproval(points int) bool {
+	if points < 0 {
+		points = -points
+	}
+	return points > s.approvalThreshold()
+}
+
+// Request 发起积分调整：未超过审批阈值时直接到账，否则等待审批
+func (s *PointsAdjustmentService) Request(db *gorm.DB, userID uint, points int, reason string, operatorID uint) (*models.PointsAdjustment, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: 调整原因不能为空", ErrInvalidPointsAdjustment)
+	}
+	if points == 0 {
+		return nil, fmt.Errorf("%w: 调整积分不能为0", ErrInvalidPointsAdjustment)
+	}
+
+	adjustment := &models.PointsAdjustment{
+		UserID:      userID,
+		Points:      points,
+		Reason:      reason,
+		Status:      models.PointsAdjustmentPending,
+		RequestedBy: operatorID,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.UserPoints{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("积分账户不存在")
+		}
+
+		if err := tx.Create(adjustment).Error; err != nil {
+			return err
+		}
+		if s.RequiresApproval(points) {
+			return nil
+		}
+		return s.apply(tx, adjustment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
+
+// Approve 审批通过并执行积分调整，审批人不能是发起人
+func (s *PointsAdjustmentService) Approve(db *gorm.DB, id uint, reviewerID uint, note string) (*models.PointsAdjustment, error) {
+	var adjustment models.PointsAdjustment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := s.lockPending(tx, id, reviewerID, &adjustment); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		adjustment.ReviewedBy = &reviewerID
+		adjustment.ReviewNote = strings.TrimSpace(note)
+		adjustment.ReviewedAt = &now
+		return s.apply(tx, &adjustment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// Reject 驳回积分调整，审批人不能是发起人
+func (s *PointsAdjustmentService) Reject(db *gorm.DB, id uint, reviewerID uint, note string) (*models.PointsAdjustment, error) {
+	var adjustment models.PointsAdjustment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := s.lockPending(tx, id, reviewerID, &adjustment); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		adjustment.Status = models.PointsAdjustmentRejected
+		adjustment.ReviewedBy = &reviewerID
+		adjustment.ReviewNote = strings.TrimSpace(note)
+		adjustment.ReviewedAt = &now
+		return tx.Save(&adjustment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// lockPending 锁定待审批的积分调整，避免重复审批
+func (s *PointsAdjustmentService) lockPending(tx *gorm.DB, id uint, reviewerID uint, adjustment *models.PointsAdjustment) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(adjustment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPointsAdjustmentNotFound
+		}
+		return err
+	}
+	if adjustment.Status != models.PointsAdjustmentPending {
+		return ErrPointsAdjustmentReviewed
+	}
+	if adjustment.RequestedBy == reviewerID {
+		return ErrPointsAdjustmentSelfReview
+	}
+	return nil
+}
+
+// apply 写入积分变动并标记调整已到账
+func (s *PointsAdjustmentService) apply(tx *gorm.DB, adjustment *models.PointsAdjustment) error {
+	transaction, err := NewPointsService().AdjustPoints(tx, adjustment.UserID, adjustment.Points, "手动调整："+adjustment.Reason)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	adjustment.Status = models.PointsAdjustmentApplied
+	adjustment.TransactionID = &transaction.ID
+	adjustment.AppliedAt = &now
+	return tx.Save(adjustment).Error
+}
+
+// NewPointsAdjustmentService 创建积分调整服务实例
+func NewPointsAdjustmentService() *PointsAdjustmentService {
+	return &PointsAdjustmentService{}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PointsDrift 积分余额与积分变动记录不一致的账户
+type PointsDrift struct {
+	UserID       uint `json:"user_id"`
+	TotalPoints  int  `json:"total_points"`  // 账户记录的可用积分
+	LedgerPoints int  `json:"ledger_points"` // 按积分变动记录汇总的可用积分
+	Drift        int  `json:"drift"`         // 账户余额 - 变动记录汇总
+	Repaired     bool `json:"repaired"`
+}
+
+// PointsReconcileResult 积分对账结果
+type PointsReconcileResult struct {
+	Repair   bool          `json:"repair"`
+	Checked  int           `json:"checked"`
+	Drifted  int           `json:"drifted"`
+	Repaired int           `json:"repaired"`
+	Drifts   []PointsDrift `json:"drifts"`
+}
+
+// Reconcile 按积分变动记录重新汇总每个账户的可用积分，与账户余额比对
+// repair 为 true 时以变动记录为准修正余额，并同步积分批次；userID 不为空时只核对该用户
+func (s *PointsService) Reconcile(db *gorm.DB, repair bool, userID *uint) (*PointsReconcileResult, error) {
+	result := &PointsReconcileResult{
+		Repair: repair,
+		Drifts: make([]PointsDrift, 0),
+	}
+
+	query := db.Model(&models.UserPoints{}).Order("id ASC")
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var batch []models.UserPoints
+	err := query.FindInBatches(&batch, 500, func(_ *gorm.DB, _ int) error {
+		userIDs := make([]uint, 0, len(batch))
+		for _, up := range batch {
+			userIDs = append(userIDs, up.UserID)
+		}
+		ledger, err := s.ledgerBalances(db, userIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, up := range batch {
+			result.Checked++
+			if up.TotalPoints == ledger[up.UserID] {
+				continue
+			}
+
+			drift := PointsDrift{
+				UserID:       up.UserID,
+				TotalPoints:  up.TotalPoints,
+				LedgerPoints: ledger[up.UserID],
+				Drift:        up.TotalPoints - ledger[up.UserID],
+			}
+			if repair {
+				repaired, err := s.repairBalance(db, up.UserID)
+				if err != nil {
+					return err
+				}
+				drift.Repaired = repaired
+				if repaired {
+					result.Repaired++
+				}
+			}
+			result.Drifted++
+			result.Drifts = append(result.Drifts, drift)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// ledgerBalances 汇总用户的积分变动记录
+func (s *PointsService) ledgerBalances(db *gorm.DB, userIDs []uint) (map[uint]int, error) {
+	var rows []struct {
+		UserID  uint
+		Balance int
+	}
+	if err := db.Model(&models.PointTransaction{}).
+		Select("user_id, COALESCE(SUM(points_change), 0) AS balance").
+		Where("user_id IN ? AND deleted_at IS NULL", userIDs).
+		Group("user_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		balances[row.UserID] = row.Balance
+	}
+	return balances, nil
+}
+
+// repairBalance 锁定账户后重新汇总并修正余额，同时让积分批次剩余量与余额一致
+func (s *PointsService) repairBalance(db *gorm.DB, userID uint) (bool, error) {
+	repaired := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		userPoints, err := s.LockUserPoints(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		ledger, err := s.ledgerBalances(tx, []uint{userID})
+		if err != nil {
+			return err
+		}
+		target := ledger[userID]
+		if target < 0 {
+			target = 0
+		}
+		if target == userPoints.TotalPoints {
+			return nil
+		}
+
+		var covered int
+		if err := tx.Model(&models.PointLot{}).
+			Select("COALESCE(SUM(remaining), 0)").
+			Where("user_id = ? AND remaining > 0 AND expired_at IS NULL", userID).
+			Scan(&covered).Error; err != nil {
+			return err
+		}
+		userPoints.TotalPoints = target
+		if covered > target {
+			if err := s.consumeLots(tx, userPoints, covered-target); err != nil {
+				return err
+			}
+		} else if covered < target {
+			if err := s.CreateLot(tx, userID, nil, models.TransactionTypeEarned, target-covered, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(userPoints).Update("total_points", target).Error; err != nil {
+			return err
+		}
+		repaired = true
+		return nil
+	})
+	return repaired, err
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PointsService 积分服务
@@ -58,12 +59,22 @@ func (s *PointsService) CalculateEarnedPoints(paymentAmount float64, memberLevel
 	return earnedPoints, nil
 }
 
-// UsePoints 使用积分
-func (s *PointsService) UsePoints(tx *gorm.DB, userID uint, pointsToUse int, orderID uint, description string) error {
+// LockUserPoints 锁定并读取用户积分账户（SELECT ... FOR UPDATE）
+// 积分账户的读改写都须在事务内先加锁，避免并发扣减时余额校验失效
+func (s *PointsService) LockUserPoints(tx *gorm.DB, userID uint) (*models.UserPoints, error) {
 	var userPoints models.UserPoints
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).First(&userPoints).Error; err != nil {
+		return nil, errors.New("积分账户不存在")
+	}
+	return &userPoints, nil
+}
 
-	if err := tx.Where("user_id = ?", userID).First(&userPoints).Error; err != nil {
-		return errors.New("积分账户不存在")
+// UsePoints 使用积分
+func (s *PointsService) UsePoints(tx *gorm.DB, userID uint, pointsToUse int, orderID uint, description string) error {
+	userPoints, err := s.LockUserPoints(tx, userID)
+	if err != nil {
+		return err
 	}
 
 	// 检查可用积分
@@ -71,10 +82,15 @@ func (s *PointsService) UsePoints(tx *gorm.DB, userID uint, pointsToUse int, ord
 		return fmt.Errorf("可用积分不足，当前可用: %d", userPoints.TotalPoints)
 	}
 
+	// 按先到期先扣减的顺序扣减积分批次
+	if err := s.consumeLots(tx, userPoints, pointsToUse); err != nil {
+		return err
+	}
+
 	// 扣减积分
 	userPoints.TotalPoints -= pointsToUse
 
-	if err := tx.Save(&userPoints).Error; err != nil {
+	if err := tx.Save(userPoints).Error; err != nil {
 		return errors.New("积分扣减失败")
 	}
 
@@ -97,10 +113,9 @@ func (s *PointsService) UsePoints(tx *gorm.DB, userID uint, pointsToUse int, ord
 
 // EarnPoints 获得积分
 func (s *PointsService) EarnPoints(tx *gorm.DB, userID uint, pointsToEarn int, orderID *uint, transactionType models.TransactionType, description string) error {
-	var userPoints models.UserPoints
-
-	if err := tx.Where("user_id = ?", userID).First(&userPoints).Error; err != nil {
-		return errors.New("积分账户不存在")
+	userPoints, err := s.LockUserPoints(tx, userID)
+	if err != nil {
+		return err
 	}
 
 	// 增加积分
@@ -108,14 +123,11 @@ func (s *PointsService) EarnPoints(tx *gorm.DB, userID uint, pointsToEarn int, o
 	userPoints.LifetimePoints += pointsToEarn
 
 	// 检查是否需要升级会员等级
-	newLevel := s.CalculateMemberLevel(userPoints.LifetimePoints)
-	if newLevel != userPoints.MemberLevel {
-		userPoints.MemberLevel = newLevel
-		now := time.Now()
-		userPoints.LevelUpgradeDate = &now
+	if err := s.applyMemberLevel(tx, userPoints, "获得积分："+description); err != nil {
+		return err
 	}
 
-	if err := tx.Save(&userPoints).Error; err != nil {
+	if err := tx.Save(userPoints).Error; err != nil {
 		return errors.New("积分增加失败")
 	}
 
@@ -133,19 +145,274 @@ func (s *PointsService) EarnPoints(tx *gorm.DB, userID uint, pointsToEarn int, o
 		return errors.New("积分记录创建失败")
 	}
 
+	return s.CreateLot(tx, userID, &transaction.ID, transactionType, pointsToEarn, transaction.CreatedAt)
+}
+
+// RefundPoints 退还订单使用的积分（不计入累计积分）
+func (s *PointsService) RefundPoints(tx *gorm.DB, userID uint, pointsToRefund int, orderID uint, description string) error {
+	userPoints, err := s.LockUserPoints(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	userPoints.TotalPoints += pointsToRefund
+
+	if err := tx.Save(userPoints).Error; err != nil {
+		return errors.New("积分退还失败")
+	}
+
+	transaction := models.PointTransaction{
+		UserID:          userID,
+		OrderID:         &orderID,
+		TransactionType: models.TransactionTypeRefunded,
+		PointsChange:    pointsToRefund,
+		PointsBalance:   userPoints.TotalPoints,
+		Description:     description,
+	}
+
+	if err := tx.Create(&transaction).Error; err != nil {
+		return errors.New("积分记录创建失败")
+	}
+
+	// 退还的积分作为新批次，有效期自退还时起算
+	return s.CreateLot(tx, userID, &transaction.ID, models.TransactionTypeRefunded, pointsToRefund, transaction.CreatedAt)
+}
+
+// ReverseEarnedPoints 扣回订单已发放的积分，并重新计算会员等级（允许降级）
+// 可用积分不足时只扣至0，累计积分与积分变动均按实际扣回数量记录
+func (s *PointsService) ReverseEarnedPoints(tx *gorm.DB, userID uint, pointsToReverse int, orderID uint, description string) error {
+	return s.reversePoints(tx, userID, pointsToReverse, &orderID, models.TransactionTypeEarned, description)
+}
+
+// ReverseReferralBonus 扣回推荐奖励积分，规则同 ReverseEarnedPoints
+// 被推荐人的奖励关联触发订单，先扣回该批次；推荐人的奖励不关联订单，按先到期先扣减扣回
+func (s *PointsService) ReverseReferralBonus(tx *gorm.DB, userID uint, pointsToReverse int, orderID *uint, description string) error {
+	return s.reversePoints(tx, userID, pointsToReverse, orderID, models.TransactionTypeReferralBonus, description)
+}
+
+// reversePoints 扣回已发放的积分，orderID 不为空时先扣回该订单对应来源的积分批次
+func (s *PointsService) reversePoints(tx *gorm.DB, userID uint, pointsToReverse int, orderID *uint, sourceType models.TransactionType, description string) error {
+	userPoints, err := s.LockUserPoints(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	// 先扣回该订单自身获得的积分批次，已被使用的部分再按先到期先扣减从其他批次扣回
+	fromLot := 0
+	if orderID != nil {
+		fromLot, err = s.reverseOrderLot(tx, userID, *orderID, sourceType, pointsToReverse)
+		if err != nil {
+			return err
+		}
+	}
+	userPoints.TotalPoints -= fromLot
+
+	rest := pointsToReverse - fromLot
+	if rest > userPoints.TotalPoints {
+		rest = userPoints.TotalPoints
+	}
+	if err := s.consumeLots(tx, userPoints, rest); err != nil {
+		return err
+	}
+	userPoints.TotalPoints -= rest
+
+	// 累计积分与积分记录按实际扣回的数量调整，保证账目一致
+	deducted := fromLot + rest
+	userPoints.LifetimePoints -= deducted
+	if userPoints.LifetimePoints < 0 {
+		userPoints.LifetimePoints = 0
+	}
+
+	if err := s.applyMemberLevel(tx, userPoints, "扣回积分："+description); err != nil {
+		return err
+	}
+
+	if err := tx.Save(userPoints).Error; err != nil {
+		return errors.New("积分扣回失败")
+	}
+
+	if deducted < pointsToReverse {
+		description = fmt.Sprintf("%s（可用积分不足，%d 积分未扣回）", description, pointsToReverse-deducted)
+	}
+
+	transaction := models.PointTransaction{
+		UserID:          userID,
+		OrderID:         orderID,
+		TransactionType: models.TransactionTypeReversed,
+		PointsChange:    -deducted,
+		PointsBalance:   userPoints.TotalPoints,
+		Description:     description,
+	}
+
+	if err := tx.Create(&transaction).Error; err != nil {
+		return errors.New("积分记录创建失败")
+	}
+
 	return nil
 }
 
-// CalculateMemberLevel 根据累计积分计算会员等级
-func (s *PointsService) CalculateMemberLevel(lifetimePoints int) models.MemberLevel {
-	if lifetimePoints >= 20000 {
-		return models.MemberLevelPlatinum
-	} else if lifetimePoints >= 5000 {
-		return models.MemberLevelGold
-	} else if lifetimePoints >= 1000 {
-		return models.MemberLevelSilver
+// reverseOrderLot 扣减订单对应来源（消费奖励或推荐奖励）的积分批次，返回实际扣减数量
+func (s *PointsService) reverseOrderLot(tx *gorm.DB, userID, orderID uint, sourceType models.TransactionType, points int) (int, error) {
+	var lot models.PointLot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Joins("JOIN point_transactions ON point_transactions.id = point_lots.transaction_id").
+		Where("point_lots.user_id = ? AND point_transactions.order_id = ? AND point_transactions.transaction_type = ?",
+			userID, orderID, sourceType).
+		Where("point_lots.remaining > 0 AND point_lots.expired_at IS NULL").
+		First(&lot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.New("积分批次查询失败")
+	}
+
+	take := lot.Remaining
+	if take > points {
+		take = points
+	}
+	if err := tx.Model(&lot).Update("remaining", lot.Remaining-take).Error; err != nil {
+		return 0, errors.New("积分批次扣减失败")
+	}
+	return take, nil
+}
+
+// AdjustPoints 管理员手动调整积分，points 为正数时增加、负数时扣减
+// 手动调整不计入累计积分，不影响会员等级；扣减不能超过当前可用积分
+func (s *PointsService) AdjustPoints(tx *gorm.DB, userID uint, points int, description string) (*models.PointTransaction, error) {
+	if points == 0 {
+		return nil, errors.New("调整积分不能为0")
+	}
+
+	userPoints, err := s.LockUserPoints(tx, userID)
+	if err != nil {
+		return nil, err
 	}
-	return models.MemberLevelBronze
+
+	if points < 0 {
+		if userPoints.TotalPoints < -points {
+			return nil, fmt.Errorf("可用积分不足，当前可用: %d", userPoints.TotalPoints)
+		}
+		if err := s.consumeLots(tx, userPoints, -points); err != nil {
+			return nil, err
+		}
+	}
+
+	userPoints.TotalPoints += points
+	if err := tx.Save(userPoints).Error; err != nil {
+		return nil, errors.New("积分调整失败")
+	}
+
+	transaction := models.PointTransaction{
+		UserID:          userID,
+		TransactionType: models.TransactionTypeAdjustment,
+		PointsChange:    points,
+		PointsBalance:   userPoints.TotalPoints,
+		Description:     description,
+	}
+	if err := tx.Create(&transaction).Error; err != nil {
+		return nil, errors.New("积分记录创建失败")
+	}
+
+	if points > 0 {
+		if err := s.CreateLot(tx, userID, &transaction.ID, models.TransactionTypeAdjustment, points, transaction.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return &transaction, nil
+}
+
+// applyMemberLevel 根据累计积分更新会员等级（升级或降级），等级变动记入会员等级记录
+func (s *PointsService) applyMemberLevel(tx *gorm.DB, userPoints *models.UserPoints, reason string) error {
+	newLevel, err := s.CalculateMemberLevel(tx, userPoints.LifetimePoints)
+	if err != nil {
+		return err
+	}
+	if newLevel == userPoints.MemberLevel {
+		return nil
+	}
+
+	fromLevel := userPoints.MemberLevel
+	history := models.MemberLevelHistory{
+		UserID:          userPoints.UserID,
+		ToLevel:         newLevel,
+		PointsAtUpgrade: userPoints.LifetimePoints,
+		UpgradeReason:   reason,
+	}
+	if fromLevel != "" {
+		history.FromLevel = &fromLevel
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return errors.New("会员等级记录创建失败")
+	}
+
+	userPoints.MemberLevel = newLevel
+	now := time.Now()
+	userPoints.LevelUpgradeDate = &now
+	return nil
+}
+
+// CalculateMemberLevel 根据累计积分和启用的会员等级配置计算会员等级
+// 累计积分低于所有门槛时取门槛最低的等级
+func (s *PointsService) CalculateMemberLevel(db *gorm.DB, lifetimePoints int) (models.MemberLevel, error) {
+	var levels []models.MemberLevelConfig
+	if err := db.Where("is_active = ?", true).Order("min_points DESC").Find(&levels).Error; err != nil {
+		return "", errors.New("会员等级配置查询失败")
+	}
+	if len(levels) == 0 {
+		return models.MemberLevelBronze, nil
+	}
+
+	for _, level := range levels {
+		if lifetimePoints >= level.MinPoints {
+			return level.LevelName, nil
+		}
+	}
+	return levels[len(levels)-1].LevelName, nil
+}
+
+// RecalculateMemberLevels 按当前等级配置重新计算全部会员等级，返回等级变动的会员数
+func (s *PointsService) RecalculateMemberLevels(db *gorm.DB, reason string) (int, error) {
+	changed := 0
+	var batch []models.UserPoints
+	err := db.Order("id ASC").FindInBatches(&batch, 200, func(_ *gorm.DB, _ int) error {
+		for i := range batch {
+			ok, err := s.recalculateMemberLevel(db, batch[i].UserID, reason)
+			if err != nil {
+				return err
+			}
+			if ok {
+				changed++
+			}
+		}
+		return nil
+	}).Error
+	return changed, err
+}
+
+// recalculateMemberLevel 在单个事务内重新计算一位会员的等级
+func (s *PointsService) recalculateMemberLevel(db *gorm.DB, userID uint, reason string) (bool, error) {
+	changed := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		userPoints, err := s.LockUserPoints(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		before := userPoints.MemberLevel
+		if err := s.applyMemberLevel(tx, userPoints, reason); err != nil {
+			return err
+		}
+		if userPoints.MemberLevel == before {
+			return nil
+		}
+		changed = true
+		return tx.Model(userPoints).Updates(map[string]interface{}{
+			"member_level":       userPoints.MemberLevel,
+			"level_upgrade_date": userPoints.LevelUpgradeDate,
+		}).Error
+	})
+	return changed, err
 }
 
 // GetMaxUsablePoints 获取订单最大可用积分
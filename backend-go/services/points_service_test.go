@@ -0,0 +1,97 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/testutil"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TestConcurrentUsePointsNeverOverspends 并发抵扣同一账户时，余额校验与扣减须在同一把锁内完成
+// SQLite 不支持 FOR UPDATE，测试库只有一个连接，并发事务在连接上排队执行，
+// 等价于 MySQL 下 LockUserPoints 行锁的串行效果
+func TestConcurrentUsePointsNeverOverspends(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createMember(t, db, "member")
+	grantLot(t, db, user.ID, 100, time.Now().UTC())
+
+	const workers, each = 10, 30
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(orderID uint) {
+			defer wg.Done()
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return NewPointsService().UsePoints(tx, user.ID, each, orderID, "积分抵扣")
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(uint(i + 1))
+	}
+	wg.Wait()
+
+	if succeeded != 100/each {
+		t.Fatalf("成功抵扣 %d 次，期望 %d 次", succeeded, 100/each)
+	}
+	if got := pointsOf(t, db, user.ID).TotalPoints; got != 100-succeeded*each {
+		t.Fatalf("可用积分 = %d，期望 %d", got, 100-succeeded*each)
+	}
+
+	var lotTotal int64
+	db.Model(&models.PointLot{}).Where("user_id = ?", user.ID).Select("COALESCE(SUM(remaining), 0)").Scan(&lotTotal)
+	if lotTotal != int64(100-succeeded*each) {
+		t.Fatalf("批次剩余积分合计 = %d，期望与可用积分一致", lotTotal)
+	}
+
+	var used int64
+	db.Model(&models.PointTransaction{}).
+		Where("user_id = ? AND transaction_type = ?", user.ID, models.TransactionTypeUsed).Count(&used)
+	if used != int64(succeeded) {
+		t.Fatalf("使用记录 = %d 条，期望 %d 条", used, succeeded)
+	}
+}
+
+func TestUsePointsRejectsOverspend(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance int
+		use     int
+		wantErr bool
+	}{
+		{name: "余额充足", balance: 100, use: 100},
+		{name: "余额不足", balance: 100, use: 101, wantErr: true},
+		{name: "无积分", balance: 0, use: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			user := createMember(t, db, "member")
+			grantLot(t, db, user.ID, tt.balance, time.Now().UTC())
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return NewPointsService().UsePoints(tx, user.ID, tt.use, 1, "积分抵扣")
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("使用积分返回 %v，期望出错 = %v", err, tt.wantErr)
+			}
+			want := tt.balance
+			if !tt.wantErr {
+				want -= tt.use
+			}
+			if got := pointsOf(t, db, user.ID).TotalPoints; got != want {
+				t.Fatalf("可用积分 = %d，期望 %d", got, want)
+			}
+		})
+	}
+}
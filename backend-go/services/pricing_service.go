@@ -0,0 +1,189 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMenuItemNotFound 菜品不存在
+	ErrMenuItemNotFound = errors.New("菜品不存在")
+	// ErrMenuItemUnavailable 菜品已下架
+	ErrMenuItemUnavailable = errors.New("菜品已下架")
+	// ErrInvalidQuantity 商品数量无效
+	ErrInvalidQuantity = errors.New("商品数量必须大于0")
+	// ErrInvalidOption 定制选项无效
+	ErrInvalidOption = errors.New("定制选项无效")
+	// ErrPriceMismatch 客户端金额与服务端计算不一致
+	ErrPriceMismatch = errors.New("订单金额已变动，请刷新后重新下单")
+)
+
+// priceTolerance 金额比较容差（半分）
+const priceTolerance = 0.005
+
+// PricingLine 计价请求行
+type PricingLine struct {
+	MenuID    uint
+	Quantity  int
+	OptionIDs []uint
+}
+
+// PricedOption 计价后的定制选项
+type PricedOption struct {
+	GroupName string
+	Option    models.MenuOption
+}
+
+// PricedLine 服务端计价后的订单行
+type PricedLine struct {
+	MenuItem  models.MenuItem
+	Quantity  int
+	Options   []PricedOption
+	UnitPrice float64 // 基础价格 + 选项加价
+	Subtotal  float64
+}
+
+// PricedOrder 服务端计价结果
+type PricedOrder struct {
+	Lines []PricedLine
+	Total float64
+}
+
+// PricingService 计价服务，所有金额均以菜单价格为准
+type PricingService struct{}
+
+// PriceLines 根据门店菜单计算每行单价、小计及订单总价
+// 门店设置了价格或下架的菜品以门店设置为准
+func (s *PricingService) PriceLines(db *gorm.DB, storeID uint, lines []PricingLine) (*PricedOrder, error) {
+	priced := &PricedOrder{Lines: make([]PricedLine, 0, len(lines))}
+
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+
+		var menuItem models.MenuItem
+		if err := db.Preload("OptionGroups.Options").First(&menuItem, line.MenuID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: ID %d", ErrMenuItemNotFound, line.MenuID)
+			}
+			return nil, err
+		}
+
+		var overrides []models.StoreMenuItem
+		if err := db.Where("store_id = ? AND menu_item_id = ?", storeID, menuItem.ID).Limit(1).Find(&overrides).Error; err != nil {
+			return nil, err
+		}
+		if len(overrides) > 0 {
+			overrides[0].ApplyTo(&menuItem)
+		}
+
+		if !menuItem.IsAvailable {
+			return nil, fmt.Errorf("%w: %s", ErrMenuItemUnavailable, menuItem.Name)
+		}
+
+		options, err := s.resolveOptions(&menuItem, line.OptionIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		unitPrice := menuItem.Price
+		for _, opt := range options {
+			unitPrice += opt.Option.PriceDelta
+		}
+		unitPrice = RoundMoney(unitPrice)
+		subtotal := RoundMoney(unitPrice * float64(line.Quantity))
+
+		priced.Lines = append(priced.Lines, PricedLine{
+			MenuItem:  menuItem,
+			Quantity:  line.Quantity,
+			Options:   options,
+			UnitPrice: unitPrice,
+			Subtotal:  subtotal,
+		})
+		priced.Total += subtotal
+	}
+
+	priced.Total = RoundMoney(priced.Total)
+	return priced, nil
+}
+
+// resolveOptions 校验并解析所选定制选项
+// 必选组未选择时使用默认选项；单选组最多一项；多选组不超过上限
+func (s *PricingService) resolveOptions(menuItem *models.MenuItem, optionIDs []uint) ([]PricedOption, error) {
+	selected := make(map[uint]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		if selected[id] {
+			return nil, fmt.Errorf("%w: 重复选择", ErrInvalidOption)
+		}
+		selected[id] = true
+	}
+
+	result := make([]PricedOption, 0, len(optionIDs))
+	matched := 0
+
+	for _, group := range menuItem.OptionGroups {
+		chosen := make([]models.MenuOption, 0)
+		for _, opt := range group.Options {
+			if !selected[opt.ID] {
+				continue
+			}
+			if !opt.IsAvailable {
+				return nil, fmt.Errorf("%w: %s 暂不可选", ErrInvalidOption, opt.Name)
+			}
+			chosen = append(chosen, opt)
+		}
+		matched += len(chosen)
+
+		if len(chosen) == 0 && group.IsRequired {
+			for _, opt := range group.Options {
+				if opt.IsDefault && opt.IsAvailable {
+					chosen = append(chosen, opt)
+					break
+				}
+			}
+			if len(chosen) == 0 {
+				return nil, fmt.Errorf("%w: 请选择%s", ErrInvalidOption, group.Name)
+			}
+		}
+
+		if group.SelectionType == models.OptionSelectionSingle && len(chosen) > 1 {
+			return nil, fmt.Errorf("%w: %s 只能选择一项", ErrInvalidOption, group.Name)
+		}
+		if group.SelectionType == models.OptionSelectionMulti && group.MaxSelect > 0 && len(chosen) > group.MaxSelect {
+			return nil, fmt.Errorf("%w: %s 最多选择%d项", ErrInvalidOption, group.Name, group.MaxSelect)
+		}
+
+		for _, opt := range chosen {
+			result = append(result, PricedOption{GroupName: group.Name, Option: opt})
+		}
+	}
+
+	if matched != len(selected) {
+		return nil, fmt.Errorf("%w: 选项不属于%s", ErrInvalidOption, menuItem.Name)
+	}
+
+	return result, nil
+}
+
+// VerifyTotal 校验客户端提交的总价与服务端计算结果一致
+func (s *PricingService) VerifyTotal(priced *PricedOrder, clientTotal float64) error {
+	if math.Abs(priced.Total-clientTotal) > priceTolerance {
+		return fmt.Errorf("%w（应付 ¥%.2f，提交 ¥%.2f）", ErrPriceMismatch, priced.Total, clientTotal)
+	}
+	return nil
+}
+
+// RoundMoney 金额保留两位小数
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// NewPricingService 创建计价服务实例
+func NewPricingService() *PricingService {
+	return &PricingService{}
+}
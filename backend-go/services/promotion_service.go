@@ -0,0 +1,312 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrCouponNotFound 优惠券不存在
+	ErrCouponNotFound = errors.New("优惠券不存在")
+	// ErrCouponNotApplicable 优惠券不满足使用条件
+	ErrCouponNotApplicable = errors.New("优惠券不满足使用条件")
+	// ErrPromotionUsageExceeded 优惠已达使用次数上限
+	ErrPromotionUsageExceeded = errors.New("优惠已达使用次数上限")
+	// ErrInvalidPromotion 优惠规则配置无效
+	ErrInvalidPromotion = errors.New("优惠规则配置无效")
+)
+
+// PromotionContext 优惠计算条件
+type PromotionContext struct {
+	UserID      *uint
+	MemberLevel models.MemberLevel
+	CouponCode  string
+	Now         time.Time
+}
+
+// AppliedDiscount 生效的优惠
+type AppliedDiscount struct {
+	Promotion models.Promotion `json:"-"`
+	ID        uint             `json:"promotion_id"`
+	Code      string           `json:"code,omitempty"`
+	Name      string           `json:"name"`
+	Type      string           `json:"type"`
+	Amount    float64          `json:"amount"`
+}
+
+// DiscountResult 优惠计算结果
+type DiscountResult struct {
+	Discounts []AppliedDiscount `json:"discounts"`
+	Total     float64           `json:"total"`
+}
+
+// PromotionService 优惠活动服务
+// 每笔订单最多使用一张优惠券，并叠加减免金额最高的一个自动促销，优惠总额不超过订单金额
+type PromotionService struct{}
+
+// NormalizeCode 统一券码格式
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate 校验优惠规则配置
+func (s *PromotionService) Validate(p *models.Promotion) error {
+	if !p.Type.IsValid() {
+		return fmt.Errorf("%w: 未知的优惠类型 %s", ErrInvalidPromotion, p.Type)
+	}
+	switch p.Type {
+	case models.PromotionPercentOff:
+		if p.DiscountValue <= 0 || p.DiscountValue > 100 {
+			return fmt.Errorf("%w: 折扣百分比需在 0-100 之间", ErrInvalidPromotion)
+		}
+	case models.PromotionFixedOff:
+		if p.DiscountValue <= 0 {
+			return fmt.Errorf("%w: 立减金额必须大于0", ErrInvalidPromotion)
+		}
+	case models.PromotionBuyXGetY:
+		if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+			return fmt.Errorf("%w: 买X送Y的数量必须大于0", ErrInvalidPromotion)
+		}
+	}
+	if p.MinSpend < 0 || p.MaxDiscountAmount < 0 || p.UsageLimit < 0 || p.PerUserLimit < 0 {
+		return fmt.Errorf("%w: 金额与次数不能为负数", ErrInvalidPromotion)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("%w: 结束时间必须晚于开始时间", ErrInvalidPromotion)
+	}
+	for _, level := range strings.Split(p.MemberLevels, ",") {
+		level = strings.TrimSpace(level)
+		if level != "" && !models.MemberLevel(level).IsValid() {
+			return fmt.Errorf("%w: 无效的会员等级 %s", ErrInvalidPromotion, level)
+		}
+	}
+	return nil
+}
+
+// Evaluate 计算订单可享受的优惠
+// lock 为 true 时锁定所用优惠行，应在下单事务内使用，保证使用次数判断准确
+func (s *PromotionService) Evaluate(db *gorm.DB, priced *PricedOrder, ctx PromotionContext, lock bool) (*DiscountResult, error) {
+	result := &DiscountResult{Discounts: make([]AppliedDiscount, 0)}
+	remaining := priced.Total
+
+	if code := NormalizeCode(ctx.CouponCode); code != "" {
+		query := db
+		if lock {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		var coupon models.Promotion
+		if err := query.Where("code = ?", code).First(&coupon).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrCouponNotFound
+			}
+			return nil, err
+		}
+
+		amount, err := s.check(db, &coupon, priced, ctx)
+		if err != nil {
+			return nil, err
+		}
+		amount = minMoney(amount, remaining)
+		if amount > 0 {
+			result.add(coupon, amount)
+			remaining = RoundMoney(remaining - amount)
+		}
+	}
+
+	var automatic []models.Promotion
+	if err := db.Where("is_active = ? AND (code IS NULL OR code = '')", true).Find(&automatic).Error; err != nil {
+		return nil, err
+	}
+
+	var best *models.Promotion
+	bestAmount := 0.0
+	for i := range automatic {
+		amount, err := s.check(db, &automatic[i], priced, ctx)
+		if err != nil {
+			if isPromotionRejection(err) {
+				continue
+			}
+			return nil, err
+		}
+		if amount > bestAmount {
+			best, bestAmount = &automatic[i], amount
+		}
+	}
+
+	if best != nil && remaining > 0 {
+		if lock {
+			// 重新加锁读取，保证总使用次数判断准确
+			if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).First(best, best.ID).Error; err != nil {
+				return nil, err
+			}
+			if _, err := s.check(db, best, priced, ctx); err != nil {
+				if !isPromotionRejection(err) {
+					return nil, err
+				}
+				best = nil
+			}
+		}
+		if best != nil {
+			amount := minMoney(bestAmount, remaining)
+			result.add(*best, amount)
+		}
+	}
+
+	return result, nil
+}
+
+// Record 记录订单使用的优惠，应在下单事务内调用
+func (s *PromotionService) Record(tx *gorm.DB, order *models.Order, result *DiscountResult) error {
+	for _, d := range result.Discounts {
+		record := models.OrderDiscount{
+			OrderID:     order.ID,
+			PromotionID: d.ID,
+			UserID:      order.UserID,
+			Code:        d.Code,
+			Name:        d.Name,
+			Type:        d.Promotion.Type,
+			Amount:      d.Amount,
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		order.Discounts = append(order.Discounts, record)
+	}
+	return nil
+}
+
+// UsageCount 统计优惠的有效使用次数（不含已取消订单），userID 不为空时只统计该会员
+func (s *PromotionService) UsageCount(db *gorm.DB, promotionID uint, userID *uint) (int64, error) {
+	query := db.Model(&models.OrderDiscount{}).
+		Joins("INNER JOIN orders ON orders.id = order_discounts.order_id").
+		Where("order_discounts.promotion_id = ? AND orders.status <> ?", promotionID, models.OrderStatusCancelled)
+	if userID != nil {
+		query = query.Where("order_discounts.user_id = ?", *userID)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// check 校验优惠使用条件并计算减免金额
+func (s *PromotionService) check(db *gorm.DB, p *models.Promotion, priced *PricedOrder, ctx PromotionContext) (float64, error) {
+	if !p.ActiveAt(ctx.Now) {
+		return 0, fmt.Errorf("%w: 不在有效期内", ErrCouponNotApplicable)
+	}
+	if !p.AllowsLevel(ctx.MemberLevel) || (p.MemberLevels != "" && ctx.UserID == nil) {
+		return 0, fmt.Errorf("%w: 仅限指定会员等级使用", ErrCouponNotApplicable)
+	}
+	if priced.Total < p.MinSpend {
+		return 0, fmt.Errorf("%w: 订单满 ¥%.2f 可用", ErrCouponNotApplicable, p.MinSpend)
+	}
+
+	if p.UsageLimit > 0 {
+		used, err := s.UsageCount(db, p.ID, nil)
+		if err != nil {
+			return 0, err
+		}
+		if used >= int64(p.UsageLimit) {
+			return 0, ErrPromotionUsageExceeded
+		}
+	}
+	if p.PerUserLimit > 0 {
+		if ctx.UserID == nil {
+			return 0, fmt.Errorf("%w: 请登录后使用", ErrCouponNotApplicable)
+		}
+		used, err := s.UsageCount(db, p.ID, ctx.UserID)
+		if err != nil {
+			return 0, err
+		}
+		if used >= int64(p.PerUserLimit) {
+			return 0, ErrPromotionUsageExceeded
+		}
+	}
+
+	amount := s.discountAmount(p, priced)
+	if amount <= 0 {
+		return 0, fmt.Errorf("%w: 订单中没有适用商品", ErrCouponNotApplicable)
+	}
+	return amount, nil
+}
+
+// discountAmount 按优惠规则计算适用商品的减免金额
+func (s *PromotionService) discountAmount(p *models.Promotion, priced *PricedOrder) float64 {
+	eligible := 0.0
+	units := make([]float64, 0)
+	for i := range priced.Lines {
+		line := &priced.Lines[i]
+		if !p.AppliesTo(&line.MenuItem) {
+			continue
+		}
+		eligible += line.Subtotal
+		for q := 0; q < line.Quantity; q++ {
+			units = append(units, line.UnitPrice)
+		}
+	}
+	if eligible <= 0 {
+		return 0
+	}
+
+	amount := 0.0
+	switch p.Type {
+	case models.PromotionPercentOff:
+		amount = eligible * p.DiscountValue / 100
+		if p.MaxDiscountAmount > 0 && amount > p.MaxDiscountAmount {
+			amount = p.MaxDiscountAmount
+		}
+	case models.PromotionFixedOff:
+		amount = minMoney(p.DiscountValue, eligible)
+	case models.PromotionBuyXGetY:
+		// 每 X+Y 件中赠送价格最低的 Y 件
+		group := p.BuyQuantity + p.GetQuantity
+		free := len(units) / group * p.GetQuantity
+		sort.Float64s(units)
+		for i := 0; i < free; i++ {
+			amount += units[i]
+		}
+	}
+	return RoundMoney(amount)
+}
+
+// add 追加生效的优惠
+func (r *DiscountResult) add(p models.Promotion, amount float64) {
+	code := ""
+	if p.Code != nil {
+		code = *p.Code
+	}
+	r.Discounts = append(r.Discounts, AppliedDiscount{
+		Promotion: p,
+		ID:        p.ID,
+		Code:      code,
+		Name:      p.Name,
+		Type:      string(p.Type),
+		Amount:    amount,
+	})
+	r.Total = RoundMoney(r.Total + amount)
+}
+
+// isPromotionRejection 是否为不满足使用条件（而非系统错误）
+func isPromotionRejection(err error) bool {
+	return errors.Is(err, ErrCouponNotApplicable) || errors.Is(err, ErrPromotionUsageExceeded)
+}
+
+// minMoney 取较小金额
+func minMoney(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// NewPromotionService 创建优惠活动服务实例
+func NewPromotionService() *PromotionService {
+	return &PromotionService{}
+}
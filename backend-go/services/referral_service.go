@@ -0,0 +1,286 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/models"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidReferralCode 推荐码无效
+	ErrInvalidReferralCode = errors.New("推荐码无效")
+)
+
+// referralCodeAlphabet 推荐码字符集（去除易混淆的 0/O/1/I/L）
+const referralCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// referralCodeLength 推荐码长度
+const referralCodeLength = 8
+
+// publicEmailDomains 公共邮箱域名，同域名不视为同一机构批量注册
+var publicEmailDomains = map[string]bool{
+	"qq.com": true, "foxmail.com": true, "163.com": true, "126.com": true, "yeah.net": true,
+	"sina.com": true, "sohu.com": true, "aliyun.com": true, "139.com": true,
+	"gmail.com": true, "outlook.com": true, "hotmail.com": true, "live.com": true,
+	"icloud.com": true, "yahoo.com": true,
+}
+
+func init() {
+	RegisterOrderStatusHook(models.OrderStatusCompleted, rewardReferral)
+	RegisterOrderStatusHook(models.OrderStatusCancelled, revokeReferralReward)
+}
+
+// ReferralService 推荐服务
+type ReferralService struct{}
+
+// EnsureCode 获取用户推荐码，尚未生成时生成并保存
+func (s *ReferralService) EnsureCode(db *gorm.DB, user *models.User) (string, error) {
+	if user.ReferralCode != nil && *user.ReferralCode != "" {
+		return *user.ReferralCode, nil
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := generateReferralCode()
+		if err != nil {
+			return "", err
+		}
+		var taken int64
+		if err := db.Model(&models.User{}).Where("referral_code = ?", code).Count(&taken).Error; err != nil {
+			return "", err
+		}
+		if taken > 0 {
+			continue
+		}
+
+		// 仅在尚未生成时写入，并发生成时以先写入的为准
+		result := db.Model(&models.User{}).
+			Where("id = ? AND (referral_code IS NULL OR referral_code = '')", user.ID).
+			Update("referral_code", code)
+		if result.Error != nil {
+			return "", result.Error
+		}
+		if result.RowsAffected == 0 {
+			if err := db.Select("id", "referral_code").First(user, user.ID).Error; err != nil {
+				return "", err
+			}
+			if user.ReferralCode != nil {
+				return *user.ReferralCode, nil
+			}
+			continue
+		}
+		user.ReferralCode = &code
+		return code, nil
+	}
+	return "", errors.New("推荐码生成失败")
+}
+
+// Bind 注册时绑定推荐关系，应在注册事务内调用
+// 推荐码无效时返回错误；未通过防刷校验的推荐关系仍会记录，但状态为已拒绝，不发放奖励
+func (s *ReferralService) Bind(tx *gorm.DB, referee *models.User, code string) (*models.Referral, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	var referrer models.User
+	if err := tx.Where("referral_code = ? AND is_active = ?", code, true).First(&referrer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidReferralCode
+		}
+		return nil, err
+	}
+
+	referral := models.Referral{
+		ReferrerID: referrer.ID,
+		RefereeID:  referee.ID,
+		Code:       code,
+		Status:     models.ReferralStatusPending,
+		DeviceID:   referee.RegisterDeviceID,
+	}
+
+	reason, err := s.abuseReason(tx, &referrer, referee)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		referral.Status = models.ReferralStatusRejected
+		referral.RejectReason = reason
+	}
+
+	if err := tx.Create(&referral).Error; err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// abuseReason 推荐防刷校验，返回拒绝原因，通过时返回空字符串
+func (s *ReferralService) abuseReason(tx *gorm.DB, referrer, referee *models.User) (string, error) {
+	if referrer.ID == referee.ID {
+		return "不能推荐自己", nil
+	}
+	if referee.Phone != "" && referee.Phone == referrer.Phone {
+		return "与推荐人手机号相同", nil
+	}
+
+	domain := emailDomain(referee.Email)
+	if domain != "" && !publicEmailDomains[domain] && domain == emailDomain(referrer.Email) {
+		return "与推荐人使用同一企业邮箱域名", nil
+	}
+
+	if device := referee.RegisterDeviceID; device != "" {
+		if device == referrer.RegisterDeviceID {
+			return "与推荐人使用同一设备注册", nil
+		}
+		var count int64
+		if err := tx.Model(&models.Referral{}).
+			Where("referrer_id = ? AND device_id = ?", referrer.ID, device).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return "该设备已通过此推荐人注册过账户", nil
+		}
+	}
+	return "", nil
+}
+
+// rewardReferral 被推荐人首笔付费订单完成时，为推荐双方发放推荐奖励
+func rewardReferral(tx *gorm.DB, order *models.Order, from models.OrderStatus) error {
+	if order.UserID == nil || order.PaymentStatus != models.OrderPaymentPaid || order.PaymentAmount <= 0 {
+		return nil
+	}
+
+	var referrals []models.Referral
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("referee_id = ? AND status = ?", *order.UserID, models.ReferralStatusPending).
+		Limit(1).Find(&referrals).Error; err != nil {
+		return err
+	}
+	if len(referrals) == 0 {
+		return nil
+	}
+	referral := &referrals[0]
+
+	referrerPoints, refereePoints, maxRewards := 200, 100, 0
+	if config.AppConfig != nil {
+		referrerPoints = config.AppConfig.ReferralReferrerPoints
+		refereePoints = config.AppConfig.ReferralRefereePoints
+		maxRewards = config.AppConfig.ReferralMaxPerReferrer
+	}
+
+	if maxRewards > 0 {
+		// 锁定推荐人账户，避免并发发放超出上限
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, referral.ReferrerID).Error; err != nil {
+			return err
+		}
+		var rewarded int64
+		if err := tx.Model(&models.Referral{}).
+			Where("referrer_id = ? AND status = ?", referral.ReferrerID, models.ReferralStatusRewarded).
+			Count(&rewarded).Error; err != nil {
+			return err
+		}
+		if rewarded >= int64(maxRewards) {
+			return tx.Model(referral).Updates(map[string]interface{}{
+				"status":        models.ReferralStatusRejected,
+				"reject_reason": fmt.Sprintf("推荐人已达奖励上限（%d人）", maxRewards),
+			}).Error
+		}
+	}
+
+	pointsService := NewPointsService()
+	if referrerPoints > 0 {
+		description := fmt.Sprintf("推荐奖励 - 好友完成首单: %s", order.OrderNumber)
+		if err := pointsService.EarnPoints(tx, referral.ReferrerID, referrerPoints, nil, models.TransactionTypeReferralBonus, description); err != nil {
+			return err
+		}
+	}
+	if refereePoints > 0 {
+		description := fmt.Sprintf("受邀首单奖励 - 订单号: %s", order.OrderNumber)
+		if err := pointsService.EarnPoints(tx, *order.UserID, refereePoints, &order.ID, models.TransactionTypeReferralBonus, description); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	return tx.Model(referral).Updates(map[string]interface{}{
+		"status":          models.ReferralStatusRewarded,
+		"referrer_points": referrerPoints,
+		"referee_points":  refereePoints,
+		"order_id":        order.ID,
+		"rewarded_at":     now,
+	}).Error
+}
+
+// revokeReferralReward 触发推荐奖励的订单完成后又被取消时，扣回推荐双方的奖励
+// 推荐关系恢复为待奖励，被推荐人之后完成的付费订单可重新触发奖励
+func revokeReferralReward(tx *gorm.DB, order *models.Order, from models.OrderStatus) error {
+	if order.UserID == nil || from != models.OrderStatusCompleted {
+		return nil
+	}
+
+	var referrals []models.Referral
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("referee_id = ? AND order_id = ? AND status = ?", *order.UserID, order.ID, models.ReferralStatusRewarded).
+		Limit(1).Find(&referrals).Error; err != nil {
+		return err
+	}
+	if len(referrals) == 0 {
+		return nil
+	}
+	referral := &referrals[0]
+
+	pointsService := NewPointsService()
+	if referral.ReferrerPoints > 0 {
+		description := fmt.Sprintf("好友首单取消，扣回推荐奖励: %s", order.OrderNumber)
+		if err := pointsService.ReverseReferralBonus(tx, referral.ReferrerID, referral.ReferrerPoints, nil, description); err != nil {
+			return err
+		}
+	}
+	if referral.RefereePoints > 0 {
+		description := fmt.Sprintf("首单取消，扣回受邀奖励 - 订单号: %s", order.OrderNumber)
+		if err := pointsService.ReverseReferralBonus(tx, referral.RefereeID, referral.RefereePoints, &order.ID, description); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(referral).Updates(map[string]interface{}{
+		"status":          models.ReferralStatusPending,
+		"referrer_points": 0,
+		"referee_points":  0,
+		"order_id":        nil,
+		"rewarded_at":     nil,
+	}).Error
+}
+
+// generateReferralCode 生成随机推荐码
+func generateReferralCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(referralCodeAlphabet)))
+	for i := 0; i < referralCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(referralCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// emailDomain 提取邮箱域名（小写）
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
+
+// NewReferralService 创建推荐服务实例
+func NewReferralService() *ReferralService {
+	return &ReferralService{}
+}
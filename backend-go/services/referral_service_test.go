@@ -0,0 +1,62 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/testutil"
+	"testing"
+)
+
+func TestCancelledFirstOrderRevokesReferralReward(t *testing.T) {
+	db := testutil.NewDB(t)
+	seedMemberLevels(t, db)
+	store := createStore(t, db)
+	referrer := createMember(t, db, "referrer")
+	referee := createMember(t, db, "referee")
+
+	referral := models.Referral{ReferrerID: referrer.ID, RefereeID: referee.ID, Code: "ABCD2345", Status: models.ReferralStatusPending}
+	if err := db.Create(&referral).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	order := createOrder(t, db, store, &referee.ID, models.OrderStatusReady, 30)
+	db.Model(order).Update("payment_status", models.OrderPaymentPaid)
+
+	updateStatus(t, db, order.ID, models.OrderStatusCompleted)
+	db.First(&referral, referral.ID)
+	if referral.Status != models.ReferralStatusRewarded {
+		t.Fatalf("首单完成后推荐状态 = %s，期望 rewarded", referral.Status)
+	}
+	if got := pointsOf(t, db, referrer.ID).TotalPoints; got != 200 {
+		t.Fatalf("推荐人积分 = %d，期望 200", got)
+	}
+	if got := pointsOf(t, db, referee.ID).TotalPoints; got != 100 {
+		t.Fatalf("被推荐人积分 = %d，期望 100", got)
+	}
+
+	updateStatus(t, db, order.ID, models.OrderStatusCancelled)
+	db.First(&referral, referral.ID)
+	if referral.Status != models.ReferralStatusPending || referral.OrderID != nil {
+		t.Fatalf("首单取消后推荐状态 = %s（订单 %v），期望恢复为 pending", referral.Status, referral.OrderID)
+	}
+	for _, user := range []*models.User{referrer, referee} {
+		points := pointsOf(t, db, user.ID)
+		if points.TotalPoints != 0 || points.LifetimePoints != 0 {
+			t.Fatalf("%s 积分 = %d/%d，期望奖励全部扣回", user.Username, points.TotalPoints, points.LifetimePoints)
+		}
+	}
+
+	var remaining int64
+	db.Model(&models.PointLot{}).Where("remaining > 0").Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("仍有 %d 个积分批次有剩余，期望奖励批次全部扣回", remaining)
+	}
+
+	// 之后的付费订单完成时可重新触发奖励
+	next := createOrder(t, db, store, &referee.ID, models.OrderStatusReady, 25)
+	db.Model(next).Update("payment_status", models.OrderPaymentPaid)
+	updateStatus(t, db, next.ID, models.OrderStatusCompleted)
+	db.First(&referral, referral.ID)
+	if referral.Status != models.ReferralStatusRewarded || referral.OrderID == nil || *referral.OrderID != next.ID {
+		t.Fatalf("新订单完成后推荐状态 = %s，期望由新订单触发奖励", referral.Status)
+	}
+}
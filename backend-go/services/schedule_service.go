@@ -0,0 +1,215 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPickupTime 无效的预约取餐时间
+	ErrInvalidPickupTime = errors.New("无效的预约取餐时间")
+	// ErrPickupSlotFull 预约时段已约满
+	ErrPickupSlotFull = errors.New("该取餐时段已约满，请选择其他时段")
+)
+
+func init() {
+	RegisterOrderStatusHook(models.OrderStatusPreparing, releaseHeldOrder)
+}
+
+// PickupSlot 预约取餐时段
+type PickupSlot struct {
+	StartAt   time.Time `json:"start_at"`
+	EndAt     time.Time `json:"end_at"`
+	Booked    int64     `json:"booked"`
+	Capacity  int       `json:"capacity"` // 0 表示不限
+	Available bool      `json:"available"`
+}
+
+// ScheduleService 预约取餐服务
+type ScheduleService struct{}
+
+// slotDuration 预约时段长度
+func slotDuration() time.Duration {
+	minutes := 15
+	if config.AppConfig != nil && config.AppConfig.ScheduleSlotMinutes > 0 {
+		minutes = config.AppConfig.ScheduleSlotMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// slotCapacity 每个预约时段的订单上限，0 表示不限
+func slotCapacity() int {
+	if config.AppConfig != nil && config.AppConfig.ScheduleSlotCapacity > 0 {
+		return config.AppConfig.ScheduleSlotCapacity
+	}
+	return 0
+}
+
+// releaseLeadTime 预约订单提前进入出品队列的时长
+func releaseLeadTime() time.Duration {
+	minutes := 15
+	if config.AppConfig != nil && config.AppConfig.ScheduleLeadMinutes >= 0 {
+		minutes = config.AppConfig.ScheduleLeadMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// ValidatePickupTime 校验预约取餐时间：按时段对齐、晚于出品准备时间、在可预约天数与营业时间内且时段未约满
+// 应在锁定门店行的事务内调用，保证并发下单时时段容量判断准确
+func (s *ScheduleService) ValidatePickupTime(db *gorm.DB, store *models.Store, pickupAt, now time.Time) error {
+	loc := store.Location()
+	pickupAt = pickupAt.In(loc)
+	now = now.In(loc)
+
+	slot := slotDuration()
+	midnight := time.Date(pickupAt.Year(), pickupAt.Month(), pickupAt.Day(), 0, 0, 0, 0, loc)
+	if pickupAt.Sub(midnight)%slot != 0 {
+		return fmt.Errorf("%w: 取餐时间需按 %d 分钟时段选择", ErrInvalidPickupTime, int(slot.Minutes()))
+	}
+
+	lead := releaseLeadTime()
+	if pickupAt.Before(now.Add(lead)) {
+		return fmt.Errorf("%w: 预约取餐时间需至少晚于当前 %d 分钟", ErrInvalidPickupTime, int(lead.Minutes()))
+	}
+
+	maxDays := 0
+	if config.AppConfig != nil {
+		maxDays = config.AppConfig.ScheduleMaxDaysAhead
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if midnight.After(today.AddDate(0, 0, maxDays)) {
+		return fmt.Errorf("%w: 最多可提前 %d 天预约", ErrInvalidPickupTime, maxDays)
+	}
+
+	window, err := NewStoreService().BusinessWindowOn(db, store, pickupAt)
+	if err != nil {
+		return err
+	}
+	if window.Closed {
+		return fmt.Errorf("%w: 门店当天不营业", ErrInvalidPickupTime)
+	}
+	if pickupAt.Before(window.Open) || !pickupAt.Before(window.LastOrder) {
+		return fmt.Errorf("%w: 可预约时间为 %s - %s", ErrInvalidPickupTime,
+			window.Open.Format("15:04"), window.LastOrder.Format("15:04"))
+	}
+
+	capacity := slotCapacity()
+	if capacity > 0 {
+		booked, err := s.bookedCount(db, store.ID, pickupAt, pickupAt.Add(slot))
+		if err != nil {
+			return err
+		}
+		if booked >= int64(capacity) {
+			return ErrPickupSlotFull
+		}
+	}
+	return nil
+}
+
+// PickupSlots 获取门店指定日期的预约时段及剩余容量
+func (s *ScheduleService) PickupSlots(db *gorm.DB, store *models.Store, day, now time.Time) ([]PickupSlot, error) {
+	window, err := NewStoreService().BusinessWindowOn(db, store, day)
+	if err != nil {
+		return nil, err
+	}
+	if window.Closed {
+		return []PickupSlot{}, nil
+	}
+
+	slot := slotDuration()
+	capacity := slotCapacity()
+	earliest := now.Add(releaseLeadTime())
+
+	var scheduled []time.Time
+	if err := db.Model(&models.Order{}).
+		Where("store_id = ? AND status <> ? AND scheduled_pickup_at >= ? AND scheduled_pickup_at < ?",
+			store.ID, models.OrderStatusCancelled, window.Open, window.LastOrder).
+		Pluck("scheduled_pickup_at", &scheduled).Error; err != nil {
+		return nil, err
+	}
+
+	slots := make([]PickupSlot, 0)
+	for start := window.Open; start.Before(window.LastOrder); start = start.Add(slot) {
+		end := start.Add(slot)
+		var booked int64
+		for _, at := range scheduled {
+			if !at.Before(start) && at.Before(end) {
+				booked++
+			}
+		}
+		slots = append(slots, PickupSlot{
+			StartAt:   start,
+			EndAt:     end,
+			Booked:    booked,
+			Capacity:  capacity,
+			Available: !start.Before(earliest) && (capacity == 0 || booked < int64(capacity)),
+		})
+	}
+	return slots, nil
+}
+
+// ReleaseDue 将到达出品准备时间的预约订单放入出品队列，返回本次放行的订单
+func (s *ScheduleService) ReleaseDue(db *gorm.DB, now time.Time) ([]models.Order, error) {
+	var due []models.Order
+	if err := db.Where("scheduled_pickup_at IS NOT NULL AND released_at IS NULL AND status IN ? AND scheduled_pickup_at <= ?",
+		models.ActiveOrderStatuses, now.Add(releaseLeadTime())).
+		Order("scheduled_pickup_at ASC, id ASC").Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	released := make([]models.Order, 0, len(due))
+	for i := range due {
+		// 条件更新，多实例同时运行时只有一个实例放行成功
+		result := db.Model(&models.Order{}).Where("id = ? AND released_at IS NULL", due[i].ID).Update("released_at", now)
+		if result.Error != nil {
+			return released, result.Error
+		}
+		if result.RowsAffected == 1 {
+			due[i].ReleasedAt = &now
+			released = append(released, due[i])
+		}
+	}
+	return released, nil
+}
+
+// ReleaseScheduledOrdersJob 定时任务：放行到期的预约订单并推送事件
+func ReleaseScheduledOrdersJob(db *gorm.DB, now time.Time) error {
+	released, err := NewScheduleService().ReleaseDue(db, now)
+	for i := range released {
+		PublishOrderEvent(OrderEventReleased, &released[i])
+	}
+	return err
+}
+
+// bookedCount 统计时段内的有效预约订单数
+func (s *ScheduleService) bookedCount(db *gorm.DB, storeID uint, start, end time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&models.Order{}).
+		Where("store_id = ? AND status <> ? AND scheduled_pickup_at >= ? AND scheduled_pickup_at < ?",
+			storeID, models.OrderStatusCancelled, start, end).
+		Count(&count).Error
+	return count, err
+}
+
+// releaseHeldOrder 预约订单提前开始制作时视为已进入出品队列
+func releaseHeldOrder(tx *gorm.DB, order *models.Order, from models.OrderStatus) error {
+	if !order.IsHeld() {
+		return nil
+	}
+	now := time.Now()
+	if err := tx.Model(order).Update("released_at", now).Error; err != nil {
+		return err
+	}
+	order.ReleasedAt = &now
+	return nil
+}
+
+// NewScheduleService 创建预约取餐服务实例
+func NewScheduleService() *ScheduleService {
+	return &ScheduleService{}
+}
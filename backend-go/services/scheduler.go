@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobFunc 后台定时任务
+type JobFunc func(db *gorm.DB, now time.Time) error
+
+// scheduledJob 已注册的定时任务
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	run      JobFunc
+	lastRun  time.Time
+}
+
+// Scheduler 进程内定时任务调度器
+// 按固定节拍检查任务，距上次执行超过任务间隔时执行；任务需自行保证多实例下的幂等
+type Scheduler struct {
+	db   *gorm.DB
+	tick time.Duration
+	mu   sync.Mutex
+	jobs []*scheduledJob
+}
+
+// Register 注册定时任务，interval 为 0 时每个节拍都执行
+func (s *Scheduler) Register(name string, interval time.Duration, run JobFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, &scheduledJob{name: name, interval: interval, run: run})
+}
+
+// Start 在后台启动调度，ctx 取消后停止
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.tick)
+		defer ticker.Stop()
+
+		s.RunDue(time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.RunDue(now)
+			}
+		}
+	}()
+}
+
+// RunDue 执行所有到期的任务
+func (s *Scheduler) RunDue(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if !job.lastRun.IsZero() && now.Sub(job.lastRun) < job.interval {
+			continue
+		}
+		job.lastRun = now
+		s.runJob(job, now)
+	}
+}
+
+// runJob 执行单个任务，任务异常不影响其他任务
+func (s *Scheduler) runJob(job *scheduledJob, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("定时任务 %s 异常: %v", job.name, r)
+		}
+	}()
+
+	if err := job.run(s.db, now); err != nil {
+		log.Printf("定时任务 %s 执行失败: %v", job.name, err)
+	}
+}
+
+// NewScheduler 创建定时任务调度器
+func NewScheduler(db *gorm.DB, tick time.Duration) *Scheduler {
+	if tick <= 0 {
+		tick = 30 * time.Second
+	}
+	return &Scheduler{db: db, tick: tick}
+}
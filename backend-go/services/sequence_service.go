@@ -0,0 +1,92 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPickupCodeExhausted 取餐码已全部被占用
+var ErrPickupCodeExhausted = errors.New("当前取餐码已满，请稍后再试")
+
+// maxPickupCodeAttempts 生成取餐码的最大尝试次数
+const maxPickupCodeAttempts = 50
+
+// SequenceService 订单号与取餐码生成服务
+type SequenceService struct{}
+
+// OrderCodes 订单号与取餐码
+type OrderCodes struct {
+	OrderNumber string
+	PickupCode  string
+}
+
+// PickupCodeDayScope 门店某天占用取餐码的订单：进行中且当天下单，或预约当天取餐
+// 预约单可能在前一天下单，日序列重置后其取餐码仍需保留到取餐当天
+func PickupCodeDayScope(storeID uint, pickupCode string, day time.Time) func(db *gorm.DB) *gorm.DB {
+	startOfDay := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	endOfDay := startOfDay.AddDate(0, 0, 1)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("store_id = ? AND pickup_code = ? AND status IN ?", storeID, pickupCode, models.ActiveOrderStatuses).
+			Where("created_at >= ? OR (scheduled_pickup_at >= ? AND scheduled_pickup_at < ?)", startOfDay, startOfDay, endOfDay)
+	}
+}
+
+// NextOrderCodes 在事务内生成门店当日唯一订单号和取餐码
+// 日期按门店时区计算；订单号由门店当日序列递增生成，不会重复；取餐码按序列循环分配，
+// 跳过本店当天被占用的取餐码（见 PickupCodeDayScope），最多尝试 maxPickupCodeAttempts 次
+func (s *SequenceService) NextOrderCodes(tx *gorm.DB, store *models.Store, now time.Time) (*OrderCodes, error) {
+	now = now.In(store.Location())
+	seqDate := now.Format("20060102")
+
+	// 确保当日序列存在，并发时由主键去重
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.OrderSequence{StoreID: store.ID, SeqDate: seqDate}).Error; err != nil {
+		return nil, err
+	}
+
+	var seq models.OrderSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("store_id = ? AND seq_date = ?", store.ID, seqDate).First(&seq).Error; err != nil {
+		return nil, err
+	}
+
+	seq.OrderSeq++
+	codes := &OrderCodes{OrderNumber: models.FormatOrderNumber(now, store.ID, seq.OrderSeq)}
+
+	for attempt := 0; attempt < maxPickupCodeAttempts; attempt++ {
+		candidate := models.FormatPickupCode(seq.PickupSeq)
+		seq.PickupSeq = (seq.PickupSeq + 1) % models.PickupCodeCapacity
+
+		var count int64
+		if err := tx.Model(&models.Order{}).Scopes(PickupCodeDayScope(store.ID, candidate, now)).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			codes.PickupCode = candidate
+			break
+		}
+	}
+
+	if codes.PickupCode == "" {
+		return nil, ErrPickupCodeExhausted
+	}
+
+	if err := tx.Model(&seq).Updates(map[string]interface{}{
+		"order_seq":  seq.OrderSeq,
+		"pickup_seq": seq.PickupSeq,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// NewSequenceService 创建序列服务实例
+func NewSequenceService() *SequenceService {
+	return &SequenceService{}
+}
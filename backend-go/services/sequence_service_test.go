@@ -0,0 +1,57 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"coffee-ordering-backend/testutil"
+	"testing"
+	"time"
+)
+
+func TestNextOrderCodesSkipsScheduledPickupCodes(t *testing.T) {
+	db := testutil.NewDB(t)
+	store := models.Store{Code: "S1", Name: "测试门店", Timezone: "UTC", OpenTime: "08:00", CloseTime: "22:00", IsActive: true}
+	if err := db.Create(&store).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	pickupAt := now.Add(3 * time.Hour)
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		scheduled *time.Time
+		status    models.OrderStatus
+		want      string
+	}{
+		{"昨天下单今天预约取餐的订单占用取餐码", now.AddDate(0, 0, -1), &pickupAt, models.OrderStatusPending, models.FormatPickupCode(1)},
+		{"昨天下单的普通订单不占用今天的取餐码", now.AddDate(0, 0, -1), nil, models.OrderStatusPending, models.FormatPickupCode(0)},
+		{"已取消的预约订单不占用取餐码", now.AddDate(0, 0, -1), &pickupAt, models.OrderStatusCancelled, models.FormatPickupCode(0)},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.Where("1 = 1").Delete(&models.Order{})
+			db.Where("1 = 1").Delete(&models.OrderSequence{})
+
+			order := models.Order{
+				StoreID:           store.ID,
+				OrderNumber:       models.FormatOrderNumber(tt.createdAt, store.ID, i+1),
+				PickupCode:        models.FormatPickupCode(0),
+				Status:            tt.status,
+				ScheduledPickupAt: tt.scheduled,
+				CreatedAt:         tt.createdAt,
+			}
+			if err := db.Create(&order).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			codes, err := NewSequenceService().NextOrderCodes(db, &store, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if codes.PickupCode != tt.want {
+				t.Fatalf("取餐码 = %s，期望 %s", codes.PickupCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"coffee-ordering-backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrStoreNotAccepting 门店当前不接单
+	ErrStoreNotAccepting = errors.New("门店当前不接单")
+	// ErrInvalidBusinessHours 营业时间配置无效
+	ErrInvalidBusinessHours = errors.New("营业时间配置无效")
+)
+
+// 门店不接单原因
+const (
+	StoreClosedReasonClosed    = "closed"     // 非营业时间
+	StoreClosedReasonHoliday   = "holiday"    // 停业日
+	StoreClosedReasonLastOrder = "last_order" // 已过最后点单时间
+	StoreClosedReasonPaused    = "paused"     // 暂停接单
+	StoreClosedReasonCapacity  = "capacity"   // 出品队列已满
+)
+
+// maxNextOpenLookahead 计算下次营业时间时向后查找的天数
+const maxNextOpenLookahead = 14
+
+// StoreStatus 门店营业状态
+type StoreStatus struct {
+	StoreID         uint       `json:"store_id"`
+	StoreName       string     `json:"store_name"`
+	IsOpen          bool       `json:"is_open"`          // 是否在营业时间内
+	AcceptingOrders bool       `json:"accepting_orders"` // 是否可以下单
+	Reason          string     `json:"reason,omitempty"`
+	Message         string     `json:"message,omitempty"`
+	OpensAt         *time.Time `json:"opens_at,omitempty"`      // 今日开门时间
+	ClosesAt        *time.Time `json:"closes_at,omitempty"`     // 今日打烊时间
+	LastOrderAt     *time.Time `json:"last_order_at,omitempty"` // 今日最后点单时间
+	NextOpenAt      *time.Time `json:"next_open_at,omitempty"`  // 不接单时下次开始接单时间
+	ActiveOrders    int64      `json:"active_orders"`
+	MaxActiveOrders int        `json:"max_active_orders"`
+}
+
+// BusinessWindow 某天的营业时间段（门店时区）
+type BusinessWindow struct {
+	Open        time.Time
+	Close       time.Time
+	LastOrder   time.Time
+	Closed      bool
+	ClosureNote string // 停业日原因，非停业日为空
+}
+
+// ParseClock 解析 HH:MM 格式时间，返回距零点的分钟数
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidBusinessHours, value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// BusinessWindowOn 获取门店指定日期的营业时间段
+// 优先级：停业日 > 每周营业时间 > 门店默认营业时间
+func (s *StoreService) BusinessWindowOn(db *gorm.DB, store *models.Store, day time.Time) (*BusinessWindow, error) {
+	loc := store.Location()
+	day = day.In(loc)
+	date := day.Format("2006-01-02")
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+
+	var closures []models.StoreClosure
+	if err := db.Where("store_id = ? AND start_date <= ? AND end_date >= ?", store.ID, date, date).
+		Limit(1).Find(&closures).Error; err != nil {
+		return nil, err
+	}
+	if len(closures) > 0 {
+		note := closures[0].Reason
+		if note == "" {
+			note = "门店停业"
+		}
+		return &BusinessWindow{Closed: true, ClosureNote: note}, nil
+	}
+
+	openClock, closeClock := store.OpenTime, store.CloseTime
+	var hours []models.StoreOpeningHour
+	if err := db.Where("store_id = ? AND weekday = ?", store.ID, int(day.Weekday())).
+		Limit(1).Find(&hours).Error; err != nil {
+		return nil, err
+	}
+	if len(hours) > 0 {
+		if hours[0].IsClosed {
+			return &BusinessWindow{Closed: true}, nil
+		}
+		openClock, closeClock = hours[0].OpenTime, hours[0].CloseTime
+	}
+
+	openMin, err := ParseClock(openClock)
+	if err != nil {
+		return nil, err
+	}
+	closeMin, err := ParseClock(closeClock)
+	if err != nil {
+		return nil, err
+	}
+	if closeMin <= openMin {
+		return nil, fmt.Errorf("%w: 打烊时间必须晚于开门时间", ErrInvalidBusinessHours)
+	}
+
+	window := &BusinessWindow{
+		Open:  midnight.Add(time.Duration(openMin) * time.Minute),
+		Close: midnight.Add(time.Duration(closeMin) * time.Minute),
+	}
+	window.LastOrder = window.Close.Add(-time.Duration(store.LastOrderMinutes) * time.Minute)
+	return window, nil
+}
+
+// Status 计算门店当前营业与接单状态
+func (s *StoreService) Status(db *gorm.DB, store *models.Store, now time.Time) (*StoreStatus, error) {
+	now = now.In(store.Location())
+	status := &StoreStatus{
+		StoreID:         store.ID,
+		StoreName:       store.Name,
+		MaxActiveOrders: store.MaxActiveOrders,
+	}
+
+	window, err := s.BusinessWindowOn(db, store, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if !window.Closed {
+		status.OpensAt = &window.Open
+		status.ClosesAt = &window.Close
+		status.LastOrderAt = &window.LastOrder
+		status.IsOpen = !now.Before(window.Open) && now.Before(window.Close)
+	}
+
+	// 未支付订单与未放行的预约订单不占用出品队列
+	if err := db.Model(&models.Order{}).
+		Where("store_id = ? AND status IN ? AND payment_status = ?",
+			store.ID, []models.OrderStatus{models.OrderStatusPending, models.OrderStatusPreparing}, models.OrderPaymentPaid).
+		Where("scheduled_pickup_at IS NULL OR released_at IS NOT NULL").
+		Count(&status.ActiveOrders).Error; err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !store.IsActive:
+		status.Reason, status.Message = StoreClosedReasonClosed, "门店已停止营业"
+	case window.Closed && window.ClosureNote != "":
+		status.Reason, status.Message = StoreClosedReasonHoliday, window.ClosureNote
+	case window.Closed || !status.IsOpen:
+		status.Reason, status.Message = StoreClosedReasonClosed, "门店不在营业时间内"
+	case !now.Before(window.LastOrder):
+		status.Reason, status.Message = StoreClosedReasonLastOrder, fmt.Sprintf("今日已停止点单（最后点单时间 %s）", window.LastOrder.Format("15:04"))
+	case store.IsPaused(now):
+		status.Reason, status.Message = StoreClosedReasonPaused, "门店暂停接单"
+		if store.PauseReason != "" {
+			status.Message += "：" + store.PauseReason
+		}
+	case store.MaxActiveOrders > 0 && status.ActiveOrders >= int64(store.MaxActiveOrders):
+		status.Reason, status.Message = StoreClosedReasonCapacity, "当前订单较多，请稍后再试"
+	default:
+		status.AcceptingOrders = true
+	}
+
+	if !status.AcceptingOrders && store.IsActive && status.Reason != StoreClosedReasonCapacity {
+		next, err := s.nextOpenAt(db, store, now)
+		if err != nil {
+			return nil, err
+		}
+		status.NextOpenAt = next
+	}
+
+	return status, nil
+}
+
+// CheckAcceptingOrders 在下单事务内校验门店是否接单
+// 门店行加锁，保证并发下单时出品队列与预约时段容量判断准确
+// 预约订单不要求当前营业，只校验暂停接单与预约时间
+func (s *StoreService) CheckAcceptingOrders(tx *gorm.DB, storeID uint, now time.Time, scheduledPickupAt *time.Time) (*StoreStatus, error) {
+	var store models.Store
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&store, storeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStoreNotFound
+		}
+		return nil, err
+	}
+
+	status, err := s.Status(tx, &store, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if scheduledPickupAt != nil {
+		if !store.IsActive {
+			return status, fmt.Errorf("%w: 门店已停止营业", ErrStoreNotAccepting)
+		}
+		if store.IsPaused(now) {
+			message := "门店暂停接单"
+			if store.PauseReason != "" {
+				message += "：" + store.PauseReason
+			}
+			return status, fmt.Errorf("%w: %s", ErrStoreNotAccepting, message)
+		}
+		return status, NewScheduleService().ValidatePickupTime(tx, &store, *scheduledPickupAt, now)
+	}
+
+	if !status.AcceptingOrders {
+		return status, fmt.Errorf("%w: %s", ErrStoreNotAccepting, status.Message)
+	}
+	return status, nil
+}
+
+// nextOpenAt 计算下次可以下单的时间（不考虑出品队列容量）
+func (s *StoreService) nextOpenAt(db *gorm.DB, store *models.Store, now time.Time) (*time.Time, error) {
+	var pausedUntil time.Time
+	if store.IsPaused(now) {
+		if store.PausedUntil == nil {
+			return nil, nil
+		}
+		pausedUntil = *store.PausedUntil
+	}
+
+	for i := 0; i <= maxNextOpenLookahead; i++ {
+		window, err := s.BusinessWindowOn(db, store, now.AddDate(0, 0, i))
+		if err != nil {
+			return nil, err
+		}
+		if window.Closed {
+			continue
+		}
+
+		candidate := window.Open
+		if candidate.Before(now) {
+			candidate = now
+		}
+		if candidate.Before(pausedUntil) {
+			candidate = pausedUntil
+		}
+		if candidate.Before(window.LastOrder) {
+			return &candidate, nil
+		}
+	}
+	return nil, nil
+}
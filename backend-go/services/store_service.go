@@ -0,0 +1,90 @@
+package services
+
+import (
+	"coffee-ordering-backend/config"
+	"coffee-ordering-backend/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrStoreNotFound 门店不存在
+	ErrStoreNotFound = errors.New("门店不存在")
+	// ErrStoreInactive 门店已停用
+	ErrStoreInactive = errors.New("门店已停用")
+)
+
+// StoreService 门店服务
+type StoreService struct{}
+
+// DefaultStoreID 未指定门店时使用的默认门店ID
+func DefaultStoreID() uint {
+	if config.AppConfig != nil && config.AppConfig.DefaultStoreID != 0 {
+		return config.AppConfig.DefaultStoreID
+	}
+	return 1
+}
+
+// ResolveStore 获取可下单的门店，storeID 为 0 时使用默认门店
+func (s *StoreService) ResolveStore(db *gorm.DB, storeID uint) (*models.Store, error) {
+	if storeID == 0 {
+		storeID = DefaultStoreID()
+	}
+
+	var store models.Store
+	if err := db.First(&store, storeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStoreNotFound
+		}
+		return nil, err
+	}
+	if !store.IsActive {
+		return nil, ErrStoreInactive
+	}
+	return &store, nil
+}
+
+// ApplyMenuOverrides 将门店的价格与上下架设置应用到菜品列表
+func (s *StoreService) ApplyMenuOverrides(db *gorm.DB, storeID uint, items []models.MenuItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	var overrides []models.StoreMenuItem
+	if err := db.Where("store_id = ? AND menu_item_id IN ?", storeID, ids).Find(&overrides).Error; err != nil {
+		return err
+	}
+
+	byMenu := make(map[uint]*models.StoreMenuItem, len(overrides))
+	for i := range overrides {
+		byMenu[overrides[i].MenuItemID] = &overrides[i]
+	}
+	for i := range items {
+		if override, ok := byMenu[items[i].ID]; ok {
+			override.ApplyTo(&items[i])
+		}
+	}
+	return nil
+}
+
+// UnavailableMenuIDs 获取门店下架的菜品ID
+func (s *StoreService) UnavailableMenuIDs(db *gorm.DB, storeID uint) ([]uint, error) {
+	var ids []uint
+	if err := db.Model(&models.StoreMenuItem{}).
+		Where("store_id = ? AND is_available = ?", storeID, false).
+		Pluck("menu_item_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// NewStoreService 创建门店服务实例
+func NewStoreService() *StoreService {
+	return &StoreService{}
+}
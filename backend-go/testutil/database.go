@@ -0,0 +1,102 @@
+// Package testutil 提供测试用的内存数据库
+package testutil
+
+import (
+	"coffee-ordering-backend/database"
+	"coffee-ordering-backend/models"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+var dbSeq int64
+
+// sqliteDialector 测试用 SQLite 方言，将模型中的 MySQL enum 类型映射为 text
+type sqliteDialector struct {
+	*sqlite.Dialector
+}
+
+// DataTypeOf 字段类型
+func (d sqliteDialector) DataTypeOf(field *schema.Field) string {
+	if strings.HasPrefix(strings.ToLower(string(field.DataType)), "enum") {
+		return "text"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+// Migrator 使用本方言的迁移器
+func (d sqliteDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return sqlite.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
+
+// NewDB 创建独立的内存数据库并迁移全部表，测试期间替换 database.DB
+// 只开放一个连接，并发事务依次执行；SQLite 不支持行锁，FOR UPDATE 会被忽略
+func NewDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:testdb%d?mode=memory&cache=shared", atomic.AddInt64(&dbSeq, 1))
+	db, err := gorm.Open(sqliteDialector{sqlite.Open(dsn).(*sqlite.Dialector)}, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("获取数据库连接失败: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.UserPoints{},
+		&models.PointTransaction{},
+		&models.Store{},
+		&models.StoreMenuItem{},
+		&models.StoreOpeningHour{},
+		&models.StoreClosure{},
+		&models.MenuItem{},
+		&models.MenuOptionGroup{},
+		&models.MenuOption{},
+		&models.Order{},
+		&models.Promotion{},
+		&models.OrderDiscount{},
+		&models.OrderItem{},
+		&models.OrderItemOption{},
+		&models.OrderStatusHistory{},
+		&models.Payment{},
+		&models.PaymentRefund{},
+		&models.OrderSequence{},
+		&models.IdempotencyKey{},
+		&models.Ingredient{},
+		&models.RecipeItem{},
+		&models.StockMovement{},
+		&models.MemberLevelConfig{},
+		&models.MemberLevelHistory{},
+		&models.BirthdayBonusGrant{},
+		&models.Referral{},
+		&models.PointLot{},
+		&models.PointsAdjustment{},
+	); err != nil {
+		t.Fatalf("迁移测试数据库失败: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+	return db
+}
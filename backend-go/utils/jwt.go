@@ -1,32 +1,43 @@
 package utils
 
 import (
+	"coffee-ordering-backend/config"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("coffee-ordering-secret-key-change-in-production") // 生产环境应从环境变量读取
+// jwtSecret 签名密钥，从配置 JWT_SECRET 读取，未加载配置时使用开发默认密钥
+func jwtSecret() []byte {
+	if config.AppConfig != nil && config.AppConfig.JWTSecret != "" {
+		return []byte(config.AppConfig.JWTSecret)
+	}
+	return []byte(config.DefaultJWTSecret)
+}
 
 // Claims JWT声明
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	StoreID      *uint  `json:"store_id,omitempty"` // 管理员所属门店，为空表示全部门店
+	TokenVersion int    `json:"token_version"`      // 与用户当前令牌版本不一致时令牌失效
 	jwt.RegisteredClaims
 }
 
 // GenerateToken 生成JWT令牌
-func GenerateToken(userID uint, username, email, role string) (string, error) {
+func GenerateToken(userID uint, username, email, role string, storeID *uint, tokenVersion int) (string, error) {
 	expirationTime := time.Now().Add(2 * time.Hour) // 2小时过期
 	
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Role:     role,
+		UserID:       userID,
+		Username:     username,
+		Email:        email,
+		Role:         role,
+		StoreID:      storeID,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -35,13 +46,13 @@ func GenerateToken(userID uint, username, email, role string) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return token.SignedString(jwtSecret())
 }
 
 // ParseToken 解析JWT令牌
 func ParseToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+		return jwtSecret(), nil
 	})
 
 	if err != nil {
@@ -67,5 +78,5 @@ func RefreshToken(tokenString string) (string, error) {
 		return "", errors.New("token not close to expiration")
 	}
 
-	return GenerateToken(claims.UserID, claims.Username, claims.Email, claims.Role)
+	return GenerateToken(claims.UserID, claims.Username, claims.Email, claims.Role, claims.StoreID, claims.TokenVersion)
 }
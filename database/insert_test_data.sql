@@ -103,6 +103,13 @@ INSERT INTO point_transactions (user_id, order_id, transaction_type, points_chan
 -- 6. 更新订单状态（模拟订单完成）
 -- 注意：实际场景中这些应该通过业务逻辑自动触发
 UPDATE orders SET status = 'completed' WHERE id IN (1, 2, 3, 5, 7);
+-- 已有 earned 记录的订单标记为积分已发放，避免重复发放
+UPDATE orders SET points_awarded_at = created_at WHERE id IN (1, 2, 3, 5);
+-- 历史订单视为线下已支付，待处理订单保持未支付
+UPDATE orders SET payment_status = 'paid', paid_at = created_at WHERE id IN (1, 2, 3, 5, 6, 7);
+-- 已出品订单的单品标记为完成，制作中订单的单品标记为制作中
+UPDATE order_items SET status = 'done', done_at = created_at WHERE order_id IN (1, 2, 3, 5, 7);
+UPDATE order_items SET status = 'making', started_at = created_at WHERE order_id = 6;
 
 SELECT '测试数据插入完成！' AS message;
 SELECT '用户数量:' AS info, COUNT(*) AS count FROM users;
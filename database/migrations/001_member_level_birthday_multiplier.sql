@@ -0,0 +1,24 @@
+-- ============================================
+-- 升级脚本：会员等级生日积分倍数
+-- 适用于在 birthday_multiplier 字段加入前部署的数据库（新部署直接执行 setup.sql 即可）
+-- 执行方式: mysql -u root -p coffee_ordering < database/migrations/001_member_level_birthday_multiplier.sql
+-- 可重复执行
+-- ============================================
+
+-- 1. 添加 birthday_multiplier 字段（已存在时跳过）
+SET @column_exists = (
+    SELECT COUNT(*) FROM information_schema.COLUMNS
+    WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'member_levels' AND COLUMN_NAME = 'birthday_multiplier'
+);
+SET @ddl = IF(@column_exists = 0,
+    'ALTER TABLE member_levels ADD COLUMN birthday_multiplier DECIMAL(5,2) NOT NULL DEFAULT 1.00 COMMENT ''生日积分倍数'' AFTER max_discount_percentage',
+    'SELECT 1');
+PREPARE stmt FROM @ddl;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- 2. 按各等级 benefits 中的生日权益设置倍数（银牌双倍、金牌三倍、白金五倍）
+-- 只更新仍为默认值 1.00 的等级，不覆盖管理员已调整的配置
+UPDATE member_levels SET birthday_multiplier = 2.00 WHERE level_name = 'silver' AND birthday_multiplier = 1.00;
+UPDATE member_levels SET birthday_multiplier = 3.00 WHERE level_name = 'gold' AND birthday_multiplier = 1.00;
+UPDATE member_levels SET birthday_multiplier = 5.00 WHERE level_name = 'platinum' AND birthday_multiplier = 1.00;
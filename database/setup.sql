@@ -17,7 +17,8 @@ CREATE TABLE users (
     email VARCHAR(100) NOT NULL UNIQUE,
     password VARCHAR(255) NOT NULL,
     phone VARCHAR(20),
-    role VARCHAR(20) DEFAULT 'user',
+    role VARCHAR(20) DEFAULT 'user' COMMENT 'user, owner, admin, manager, barista, cashier',
+    store_id INT NULL COMMENT '管理员所属门店，为空表示可管理全部门店',
     first_name VARCHAR(50),
     last_name VARCHAR(50),
     avatar_url VARCHAR(255),
@@ -25,15 +26,78 @@ CREATE TABLE users (
     birth_date DATE,
     is_verified BOOLEAN DEFAULT FALSE,
     is_active BOOLEAN DEFAULT TRUE,
+    reset_password_token VARCHAR(255) COMMENT '密码重置令牌摘要',
+    reset_password_expires_at TIMESTAMP NULL,
+    token_version INT NOT NULL DEFAULT 0 COMMENT '令牌版本，禁用、改角色、重置密码时递增使已签发令牌失效',
+    referral_code VARCHAR(16) NULL UNIQUE COMMENT '推荐码，首次使用时生成',
+    register_device_id VARCHAR(64) COMMENT '注册设备标识（推荐防刷）',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP NULL,
     INDEX idx_email (email),
+    INDEX idx_register_device_id (register_device_id),
     INDEX idx_username (username),
     INDEX idx_role (role),
+    INDEX idx_is_active (is_active),
+    INDEX idx_reset_password_token (reset_password_token),
+    INDEX idx_store_id (store_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 1.1 门店表
+-- ============================================
+CREATE TABLE stores (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    address VARCHAR(255),
+    phone VARCHAR(20),
+    timezone VARCHAR(50) NOT NULL DEFAULT 'Asia/Shanghai',
+    open_time CHAR(5) NOT NULL DEFAULT '08:00' COMMENT '营业开始时间 HH:MM（门店时区）',
+    close_time CHAR(5) NOT NULL DEFAULT '22:00' COMMENT '营业结束时间 HH:MM（门店时区）',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_order_minutes INT NOT NULL DEFAULT 0 COMMENT '打烊前停止接单的分钟数',
+    max_active_orders INT NOT NULL DEFAULT 0 COMMENT '出品队列中进行中订单上限，0 表示不限',
+    ordering_paused BOOLEAN NOT NULL DEFAULT FALSE COMMENT '是否暂停接单',
+    pause_reason VARCHAR(255) COMMENT '暂停接单原因',
+    paused_until TIMESTAMP NULL COMMENT '暂停接单截止时间，为空表示需手动恢复',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_is_active (is_active)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- ============================================
+-- 1.2 门店每周营业时间表（未配置的星期使用门店默认营业时间）
+-- ============================================
+CREATE TABLE store_opening_hours (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    store_id INT NOT NULL,
+    weekday TINYINT NOT NULL COMMENT '0=周日 ... 6=周六',
+    open_time CHAR(5) NOT NULL COMMENT '营业开始时间 HH:MM（门店时区）',
+    close_time CHAR(5) NOT NULL COMMENT '营业结束时间 HH:MM（门店时区）',
+    is_closed BOOLEAN NOT NULL DEFAULT FALSE COMMENT '当天休息',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (store_id) REFERENCES stores(id) ON DELETE CASCADE,
+    UNIQUE KEY idx_store_weekday (store_id, weekday)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 1.3 门店停业日期表（节假日、装修等）
+-- ============================================
+CREATE TABLE store_closures (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    store_id INT NOT NULL,
+    start_date CHAR(10) NOT NULL COMMENT '开始日期 2006-01-02（门店时区）',
+    end_date CHAR(10) NOT NULL COMMENT '结束日期 2006-01-02（含当天）',
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (store_id) REFERENCES stores(id) ON DELETE CASCADE,
+    INDEX idx_store_id (store_id),
+    INDEX idx_start_date (start_date),
+    INDEX idx_end_date (end_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- ============================================
 -- 2. 菜单表
 -- ============================================
@@ -45,6 +109,7 @@ CREATE TABLE menu_items (
     category VARCHAR(50) NOT NULL DEFAULT 'coffee',
     image_url VARCHAR(255),
     is_available BOOLEAN DEFAULT TRUE,
+    sold_out BOOLEAN NOT NULL DEFAULT FALSE COMMENT '因原料不足自动下架，补货后自动恢复',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_category (category),
@@ -52,12 +117,113 @@ CREATE TABLE menu_items (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 
+-- ============================================
+-- 2.1 菜单定制选项组表（杯型、奶类、甜度、温度、加浓缩等）
+-- ============================================
+CREATE TABLE menu_option_groups (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    menu_item_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    selection_type ENUM('single', 'multi') NOT NULL DEFAULT 'single' COMMENT '单选/多选',
+    is_required BOOLEAN NOT NULL DEFAULT FALSE,
+    max_select INT NOT NULL DEFAULT 0 COMMENT '多选上限，0为不限',
+    sort_order INT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (menu_item_id) REFERENCES menu_items(id) ON DELETE CASCADE,
+    INDEX idx_menu_item_id (menu_item_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 2.2 菜单定制选项表
+-- ============================================
+CREATE TABLE menu_options (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    group_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    price_delta DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '相对基础价格的加价',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    is_available BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES menu_option_groups(id) ON DELETE CASCADE,
+    INDEX idx_group_id (group_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 2.3 原料表
+-- ============================================
+CREATE TABLE ingredients (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    unit VARCHAR(20) NOT NULL COMMENT '单位: g, ml, 个',
+    stock DECIMAL(12,3) NOT NULL DEFAULT 0 COMMENT '当前库存',
+    low_stock_threshold DECIMAL(12,3) NOT NULL DEFAULT 0 COMMENT '低库存阈值',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 2.4 菜品配方表（每份菜品消耗的原料数量）
+-- ============================================
+CREATE TABLE menu_recipes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    menu_item_id INT NOT NULL,
+    ingredient_id INT NOT NULL,
+    quantity DECIMAL(12,3) NOT NULL COMMENT '每份消耗数量',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (menu_item_id) REFERENCES menu_items(id) ON DELETE CASCADE,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE RESTRICT,
+    UNIQUE KEY idx_menu_ingredient (menu_item_id, ingredient_id),
+    INDEX idx_ingredient_id (ingredient_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 2.5 库存变动记录表
+-- ============================================
+CREATE TABLE stock_movements (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    ingredient_id INT NOT NULL,
+    movement_type ENUM('order_consume', 'order_restore', 'stock_in', 'stocktake') NOT NULL,
+    quantity_change DECIMAL(12,3) NOT NULL COMMENT '变动数量（正负数）',
+    stock_after DECIMAL(12,3) NOT NULL COMMENT '变动后库存',
+    order_id INT NULL,
+    operator_id INT NULL,
+    note VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE,
+    INDEX idx_ingredient_id (ingredient_id),
+    INDEX idx_movement_type (movement_type),
+    INDEX idx_order_id (order_id),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 2.6 门店菜品设置表（覆盖菜单价格与上下架）
+-- ============================================
+CREATE TABLE store_menu_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    store_id INT NOT NULL,
+    menu_item_id INT NOT NULL,
+    price DECIMAL(10,2) NULL COMMENT '门店价格，为空时使用菜单价格',
+    is_available BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (store_id) REFERENCES stores(id) ON DELETE CASCADE,
+    FOREIGN KEY (menu_item_id) REFERENCES menu_items(id) ON DELETE CASCADE,
+    UNIQUE KEY idx_store_menu (store_id, menu_item_id),
+    INDEX idx_menu_item_id (menu_item_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- ============================================
 -- 3. 订单表
 -- ============================================
 CREATE TABLE orders (
     id INT AUTO_INCREMENT PRIMARY KEY,
     user_id INT NULL,
+    store_id INT NOT NULL DEFAULT 1,
     order_number VARCHAR(50) NOT NULL UNIQUE,
     pickup_code VARCHAR(10) NOT NULL,
     status ENUM('pending', 'preparing', 'ready', 'completed', 'cancelled') DEFAULT 'pending',
@@ -68,13 +234,144 @@ CREATE TABLE orders (
     points_deduction_amount DECIMAL(10,2) DEFAULT 0.00 COMMENT '积分抵扣金额',
     points_earned INT DEFAULT 0 COMMENT '订单完成后获得的积分',
     member_level_at_time ENUM('bronze','silver','gold','platinum') COMMENT '下单时会员等级',
+    points_awarded_at TIMESTAMP NULL COMMENT '积分实际发放时间（防止重复发放）',
+    points_refunded_at TIMESTAMP NULL COMMENT '取消订单退还抵扣积分时间',
+    points_reversed_at TIMESTAMP NULL COMMENT '取消订单扣回已发放积分时间',
+    discount_amount DECIMAL(10,2) DEFAULT 0.00 COMMENT '优惠券与促销活动减免金额',
+    payment_amount DECIMAL(10,2) DEFAULT 0.00 COMMENT '应付金额（下单时计算）',
+    payment_status ENUM('unpaid', 'paid', 'refunded') DEFAULT 'unpaid' COMMENT '支付状态',
+    paid_at TIMESTAMP NULL COMMENT '支付完成时间',
+    scheduled_pickup_at TIMESTAMP NULL COMMENT '预约取餐时间，为空表示尽快制作',
+    released_at TIMESTAMP NULL COMMENT '预约订单进入出品队列时间',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_user_id (user_id),
     INDEX idx_status (status),
     INDEX idx_pickup_code (pickup_code),
     INDEX idx_order_number (order_number),
-    INDEX idx_created_at (created_at)
+    INDEX idx_created_at (created_at),
+    INDEX idx_payment_status (payment_status),
+    INDEX idx_scheduled_pickup_at (scheduled_pickup_at),
+    INDEX idx_store_pickup (store_id, pickup_code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 3.1 门店每日订单序列表（生成订单号与取餐码）
+-- ============================================
+CREATE TABLE order_sequences (
+    store_id INT NOT NULL,
+    seq_date CHAR(8) NOT NULL COMMENT '日期: 20060102（门店时区）',
+    order_seq INT NOT NULL DEFAULT 0 COMMENT '门店当日订单序号',
+    pickup_seq INT NOT NULL DEFAULT 0 COMMENT '下一个取餐码序号（A000-Z999循环）',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (store_id, seq_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 3.2 下单幂等键表
+-- ============================================
+CREATE TABLE idempotency_keys (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    scope VARCHAR(50) NOT NULL COMMENT '作用域: user:<id> 或 guest',
+    `key` VARCHAR(100) NOT NULL COMMENT 'Idempotency-Key 请求头',
+    request_hash CHAR(64) NOT NULL COMMENT '请求内容SHA-256摘要',
+    order_id INT NULL,
+    status_code INT NOT NULL,
+    response_body TEXT NOT NULL COMMENT '首次请求的响应',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_idempotency_scope_key (scope, `key`),
+    INDEX idx_order_id (order_id),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 3.3 支付单表
+-- ============================================
+CREATE TABLE payments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    provider VARCHAR(30) NOT NULL COMMENT '支付渠道: mock 等',
+    provider_ref VARCHAR(100) NOT NULL COMMENT '渠道支付单号',
+    amount DECIMAL(10,2) NOT NULL,
+    status ENUM('pending', 'succeeded', 'failed', 'refunded') NOT NULL DEFAULT 'pending',
+    failure_reason VARCHAR(255),
+    paid_at TIMESTAMP NULL,
+    refunded_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT,
+    UNIQUE KEY idx_provider_ref (provider, provider_ref),
+    INDEX idx_order_id (order_id),
+    INDEX idx_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 3.4 退款单表（取消订单时登记，事务提交后调用支付渠道退款，失败按退避重试）
+-- ============================================
+CREATE TABLE payment_refunds (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    payment_id INT NOT NULL COMMENT '每个支付单只有一条退款单',
+    order_id INT NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    status ENUM('pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending' COMMENT 'failed 表示重试次数用尽，需人工处理',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(255),
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    refunded_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE RESTRICT,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT,
+    UNIQUE KEY idx_payment_id (payment_id),
+    INDEX idx_order_id (order_id),
+    INDEX idx_status_next_attempt (status, next_attempt_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 3.5 优惠活动表（code 为空为自动促销，否则为优惠券）
+-- ============================================
+CREATE TABLE promotions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    code VARCHAR(50) NULL UNIQUE COMMENT '优惠券码（大写）',
+    type ENUM('percent_off', 'fixed_off', 'buy_x_get_y') NOT NULL COMMENT '折扣/立减/买X送Y',
+    category VARCHAR(50) COMMENT '适用菜品分类，为空表示不限',
+    menu_item_id INT NULL COMMENT '适用菜品，为空表示不限',
+    discount_value DECIMAL(10,2) DEFAULT 0.00 COMMENT '折扣百分比或立减金额',
+    max_discount_amount DECIMAL(10,2) DEFAULT 0.00 COMMENT '折扣封顶金额，0 表示不封顶',
+    buy_quantity INT DEFAULT 0 COMMENT '买X',
+    get_quantity INT DEFAULT 0 COMMENT '送Y',
+    min_spend DECIMAL(10,2) DEFAULT 0.00 COMMENT '订单最低消费',
+    member_levels VARCHAR(100) COMMENT '限定会员等级，逗号分隔，为空表示不限',
+    starts_at TIMESTAMP NULL,
+    ends_at TIMESTAMP NULL,
+    usage_limit INT DEFAULT 0 COMMENT '总使用次数上限，0 表示不限',
+    per_user_limit INT DEFAULT 0 COMMENT '每位会员使用次数上限，0 表示不限',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_is_active (is_active)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 3.6 订单优惠明细表（下单时快照）
+-- ============================================
+CREATE TABLE order_discounts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    promotion_id INT NOT NULL,
+    user_id INT NULL COMMENT '使用人，用于统计每位会员的使用次数',
+    code VARCHAR(50),
+    name VARCHAR(100) NOT NULL,
+    type ENUM('percent_off', 'fixed_off', 'buy_x_get_y') NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    INDEX idx_order_id (order_id),
+    INDEX idx_promotion_id (promotion_id),
+    INDEX idx_user_id (user_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- ============================================
@@ -85,7 +382,11 @@ CREATE TABLE order_items (
     order_id INT NOT NULL,
     menu_item_id INT NOT NULL,
     quantity INT NOT NULL,
-    unit_price DECIMAL(10,2) NOT NULL COMMENT '下单时商品单价（历史快照）',
+    unit_price DECIMAL(10,2) NOT NULL COMMENT '下单时商品单价（含定制选项加价，历史快照）',
+    status ENUM('queued', 'making', 'done', 'voided') NOT NULL DEFAULT 'queued' COMMENT '出品状态',
+    started_at TIMESTAMP NULL COMMENT '开始制作时间',
+    done_at TIMESTAMP NULL COMMENT '出品完成时间',
+    voided_at TIMESTAMP NULL COMMENT '作废时间',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
     FOREIGN KEY (menu_item_id) REFERENCES menu_items(id) ON DELETE RESTRICT,
@@ -93,6 +394,40 @@ CREATE TABLE order_items (
     INDEX idx_menu_item_id (menu_item_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- ============================================
+-- 4.1 订单项定制选项表（下单时快照）
+-- ============================================
+CREATE TABLE order_item_options (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_item_id INT NOT NULL,
+    option_id INT NOT NULL COMMENT '下单时选项ID（选项删除后仍保留快照）',
+    group_name VARCHAR(50) NOT NULL,
+    option_name VARCHAR(50) NOT NULL,
+    price_delta DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_item_id) REFERENCES order_items(id) ON DELETE CASCADE,
+    INDEX idx_order_item_id (order_item_id),
+    INDEX idx_option_id (option_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 4.2 订单状态变更记录表
+-- ============================================
+CREATE TABLE order_status_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    from_status ENUM('pending', 'preparing', 'ready', 'completed', 'cancelled') NULL COMMENT '变更前状态（下单时为空）',
+    to_status ENUM('pending', 'preparing', 'ready', 'completed', 'cancelled') NOT NULL COMMENT '变更后状态',
+    actor_id INT NULL COMMENT '操作人ID（游客或系统为空）',
+    actor_role VARCHAR(20) NOT NULL COMMENT '操作人角色: admin, user, guest, system',
+    reason VARCHAR(255) COMMENT '变更原因',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    INDEX idx_order_id (order_id),
+    INDEX idx_actor_id (actor_id),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- ============================================
 -- 5. 会员积分表
 -- ============================================
@@ -117,7 +452,7 @@ CREATE TABLE point_transactions (
     id INT AUTO_INCREMENT PRIMARY KEY,
     user_id INT NOT NULL,
     order_id INT NULL,
-    transaction_type ENUM('earned', 'used', 'expired', 'refunded', 'signup_bonus', 'birthday_bonus', 'referral_bonus') NOT NULL,
+    transaction_type ENUM('earned', 'used', 'expired', 'refunded', 'reversed', 'signup_bonus', 'birthday_bonus', 'referral_bonus', 'adjustment') NOT NULL,
     points_change INT NOT NULL COMMENT '积分变动（正负数）',
     points_balance INT NOT NULL COMMENT '变动后余额',
     description VARCHAR(255) NOT NULL COMMENT '变动描述',
@@ -131,6 +466,74 @@ CREATE TABLE point_transactions (
     INDEX idx_created_at (created_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- ============================================
+-- 6.1 推荐关系表（每位被推荐人一条）
+-- ============================================
+CREATE TABLE referrals (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    referrer_id INT NOT NULL COMMENT '推荐人',
+    referee_id INT NOT NULL UNIQUE COMMENT '被推荐人',
+    code VARCHAR(16) NOT NULL COMMENT '注册时使用的推荐码',
+    status ENUM('pending', 'rewarded', 'rejected') NOT NULL DEFAULT 'pending',
+    reject_reason VARCHAR(255),
+    device_id VARCHAR(64) COMMENT '被推荐人注册设备',
+    referrer_points INT DEFAULT 0 COMMENT '推荐人获得积分',
+    referee_points INT DEFAULT 0 COMMENT '被推荐人获得积分',
+    order_id INT NULL COMMENT '触发奖励的订单',
+    rewarded_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (referrer_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (referee_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_referrer_id (referrer_id),
+    INDEX idx_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 6.2 积分批次表（按先到期先扣减，到期后由定时任务清零）
+-- ============================================
+CREATE TABLE point_lots (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    transaction_id INT NULL COMMENT '来源积分变动记录',
+    source_type VARCHAR(30) NOT NULL COMMENT '来源类型',
+    points INT NOT NULL COMMENT '批次原始积分',
+    remaining INT NOT NULL COMMENT '剩余可用积分',
+    earned_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NULL COMMENT '过期时间，为空表示永不过期',
+    expired_at TIMESTAMP NULL COMMENT '实际过期处理时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_remaining (user_id, remaining),
+    INDEX idx_transaction_id (transaction_id),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 6.3 手动积分调整表（超过阈值需第二位管理员审批）
+-- ============================================
+CREATE TABLE points_adjustments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    points INT NOT NULL COMMENT '调整积分（正数补偿，负数扣减）',
+    reason VARCHAR(255) NOT NULL COMMENT '调整原因',
+    status ENUM('pending', 'applied', 'rejected') NOT NULL DEFAULT 'pending',
+    requested_by INT NOT NULL COMMENT '发起人',
+    reviewed_by INT NULL COMMENT '审批人',
+    review_note VARCHAR(255),
+    reviewed_at TIMESTAMP NULL,
+    transaction_id INT NULL COMMENT '到账后对应的积分变动记录',
+    applied_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_id (user_id),
+    INDEX idx_status (status),
+    INDEX idx_requested_by (requested_by),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- ============================================
 -- 7. 会员等级配置表
 -- ============================================
@@ -142,6 +545,7 @@ CREATE TABLE member_levels (
     points_earning_rate DECIMAL(5,4) NOT NULL DEFAULT 1.0000,
     points_discount_rate DECIMAL(5,4) NOT NULL DEFAULT 0.0000,
     max_discount_percentage DECIMAL(5,2) NOT NULL DEFAULT 20.00,
+    birthday_multiplier DECIMAL(5,2) NOT NULL DEFAULT 1.00 COMMENT '生日积分倍数',
     benefits JSON,
     sort_order INT NOT NULL DEFAULT 0,
     is_active BOOLEAN DEFAULT TRUE,
@@ -150,14 +554,58 @@ CREATE TABLE member_levels (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 
+-- ============================================
+-- 7.1 会员等级变动记录表（升级与降级）
+-- ============================================
+CREATE TABLE member_level_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    from_level ENUM('bronze', 'silver', 'gold', 'platinum') NULL,
+    to_level ENUM('bronze', 'silver', 'gold', 'platinum') NOT NULL,
+    points_at_upgrade INT NOT NULL COMMENT '变动时累计积分',
+    upgrade_reason VARCHAR(255) COMMENT '变动原因',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_id (user_id),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================
+-- 7.2 生日积分发放记录表（每位会员每年一条）
+-- ============================================
+CREATE TABLE birthday_bonus_grants (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    year INT NOT NULL,
+    member_level ENUM('bronze', 'silver', 'gold', 'platinum') NOT NULL COMMENT '发放时会员等级',
+    multiplier DECIMAL(5,2) NOT NULL COMMENT '发放时生日积分倍数',
+    points INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY idx_user_year (user_id, year)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- ============================================
 -- 8. 插入默认会员等级配置
 -- ============================================
-INSERT INTO member_levels (level_name, level_display_name, min_points, points_earning_rate, points_discount_rate, max_discount_percentage, benefits, sort_order) VALUES
-('bronze', '铜牌会员', 0, 1.0000, 0.0000, 20.00, '{"description": "基础会员权益", "features": ["积分累积", "生日礼品"]}', 1),
-('silver', '银牌会员', 1000, 1.2000, 0.0500, 30.00, '{"description": "银牌会员专享权益", "features": ["积分累积", "生日双倍积分", "专属优惠券"]}', 2),
-('gold', '金牌会员', 5000, 1.5000, 0.1000, 40.00, '{"description": "金牌尊贵权益", "features": ["积分累积", "生日三倍积分", "专属优惠券", "新品优先体验"]}', 3),
-('platinum', '白金会员', 20000, 2.0000, 0.1500, 50.00, '{"description": "白金顶级权益", "features": ["积分累积", "生日五倍积分", "专属客服", "免费配送"]}', 4);
+INSERT INTO member_levels (level_name, level_display_name, min_points, points_earning_rate, points_discount_rate, max_discount_percentage, birthday_multiplier, benefits, sort_order) VALUES
+('bronze', '铜牌会员', 0, 1.0000, 0.0000, 20.00, 1.00, '{"description": "基础会员权益", "features": ["积分累积", "生日礼品"]}', 1),
+('silver', '银牌会员', 1000, 1.2000, 0.0500, 30.00, 2.00, '{"description": "银牌会员专享权益", "features": ["积分累积", "生日双倍积分", "专属优惠券"]}', 2),
+('gold', '金牌会员', 5000, 1.5000, 0.1000, 40.00, 3.00, '{"description": "金牌尊贵权益", "features": ["积分累积", "生日三倍积分", "专属优惠券", "新品优先体验"]}', 3),
+('platinum', '白金会员', 20000, 2.0000, 0.1500, 50.00, 5.00, '{"description": "白金顶级权益", "features": ["积分累积", "生日五倍积分", "专属客服", "免费配送"]}', 4);
+
+-- ============================================
+-- 8.1 插入默认门店
+-- ============================================
+INSERT INTO stores (id, code, name, address, timezone, open_time, close_time) VALUES
+(1, 'HQ', '总店', '', 'Asia/Shanghai', '08:00', '22:00');
+
+-- ============================================
+-- 8.2 插入会员专属优惠券
+-- ============================================
+INSERT INTO promotions (name, description, code, type, discount_value, max_discount_amount, min_spend, member_levels, per_user_limit) VALUES
+('银牌会员专属券', '银牌及以上会员满30元享9折', 'SILVER10', 'percent_off', 10.00, 10.00, 30.00, 'silver,gold,platinum', 3),
+('金牌会员专属券', '金牌及以上会员满30元立减8元', 'GOLD8', 'fixed_off', 8.00, 0.00, 30.00, 'gold,platinum', 5);
 
 -- ============================================
 -- 9. 插入管理员账户 (密码: admin123)
@@ -188,6 +636,30 @@ INSERT INTO menu_items (name, description, price, category, image_url, is_availa
 ('橙汁', '鲜榨橙汁，维C丰富', 12.00, '其他', 'https://images.unsplash.com/photo-1621506289937-a8e4df240d0b?w=400', TRUE),
 ('矿泉水', '天然矿泉水，纯净清爽', 5.00, '其他', 'https://images.unsplash.com/photo-1548839140-29a749e1cf4d?w=400', TRUE);
 
+-- ============================================
+-- 10.1 插入示例定制选项（拿铁咖啡）
+-- ============================================
+INSERT INTO menu_option_groups (id, menu_item_id, name, selection_type, is_required, max_select, sort_order) VALUES
+(1, 2, '杯型', 'single', TRUE, 0, 1),
+(2, 2, '奶类', 'single', TRUE, 0, 2),
+(3, 2, '温度', 'single', TRUE, 0, 3),
+(4, 2, '甜度', 'single', TRUE, 0, 4),
+(5, 2, '加浓缩', 'single', FALSE, 0, 5);
+
+INSERT INTO menu_options (group_id, name, price_delta, is_default, sort_order) VALUES
+(1, '中杯', 0.00, TRUE, 1),
+(1, '大杯', 3.00, FALSE, 2),
+(1, '超大杯', 5.00, FALSE, 3),
+(2, '全脂牛奶', 0.00, TRUE, 1),
+(2, '燕麦奶', 4.00, FALSE, 2),
+(3, '热', 0.00, TRUE, 1),
+(3, '冰', 0.00, FALSE, 2),
+(4, '标准糖', 0.00, TRUE, 1),
+(4, '半糖', 0.00, FALSE, 2),
+(4, '无糖', 0.00, FALSE, 3),
+(5, '加一份浓缩', 4.00, FALSE, 1),
+(5, '加两份浓缩', 7.00, FALSE, 2);
+
 -- ============================================
 -- 11. 创建触发器（用户注册后自动创建积分账户）
 -- ============================================
@@ -22,7 +22,7 @@ export const useAuthStore = defineStore('auth', () => {
 
   // 计算属性
   const isAuthenticated = computed(() => !!token.value && !!user.value)
-  const isAdmin = computed(() => user.value?.role === 'admin')
+  const isAdmin = computed(() => ['owner', 'admin', 'manager', 'barista', 'cashier'].includes(user.value?.role))
   const userInfo = computed(() => user.value)
 
   // 方法
@@ -121,11 +121,6 @@ export const useAuthStore = defineStore('auth', () => {
         user.value = JSON.parse(storedUser)
       }
 
-      // 如果是假token（管理员登录），直接返回true，不验证
-      if (storedToken.startsWith('fake-admin-token-')) {
-        return true
-      }
-
       // 如果已经有用户信息，直接返回true，不需要验证
       // 这样可以避免在每次路由切换时都调用API
       if (user.value && token.value) {
@@ -286,15 +281,6 @@ export const useAuthStore = defineStore('auth', () => {
     }
   }
 
-  // 管理员假登录（兼容旧版）
-  const setAdminAuth = (adminUser) => {
-    const fakeToken = 'fake-admin-token-' + Date.now()
-    user.value = adminUser
-    token.value = fakeToken
-    localStorage.setItem('token', fakeToken)
-    localStorage.setItem('user', JSON.stringify(adminUser))
-  }
-
   return {
     // 状态
     user,
@@ -320,7 +306,6 @@ export const useAuthStore = defineStore('auth', () => {
     setRedirectPath,
     getRedirectPath,
     clearRedirectPath,
-    adminLogin,
-    setAdminAuth
+    adminLogin
   }
 })
\ No newline at end of file